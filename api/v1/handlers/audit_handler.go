@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler 查询和导出审计日志
+type AuditHandler struct {
+	service *service.AuditService
+}
+
+func NewAuditHandler(svc *service.AuditService) *AuditHandler {
+	return &AuditHandler{service: svc}
+}
+
+// ListAuditEvents godoc
+// @Summary 查询审计日志
+// @Description 按用户、动作、资源、命名空间、状态码和时间范围 (RFC3339) 分页查询审计事件，按时间倒序
+// @Tags Audit
+// @Produce json
+// @Security BearerAuth
+// @Param username query string false "操作人"
+// @Param verb query string false "动作，如 create、delete"
+// @Param resource query string false "资源，如 deployments"
+// @Param namespace query string false "命名空间"
+// @Param from query string false "开始时间 (含)"
+// @Param to query string false "结束时间 (不含)"
+// @Param failed query bool false "只看失败的请求"
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页大小" default(20)
+// @Success 200 {object} models.AuditEventListResponse
+// @Failure 400 {object} handlers.ErrorResponse
+// @Router /api/v1/audit/events [get]
+func (h *AuditHandler) ListAuditEvents(c *gin.Context) {
+	var q models.AuditQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		respondError(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	resp, err := h.service.Query(&q)
+	if err != nil {
+		respondAuditError(c, "查询审计日志失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, resp)
+}
+
+// ExportAuditEvents godoc
+// @Summary 导出审计日志
+// @Description 导出符合条件的全部审计事件，支持 csv 和 json 格式，过滤参数与查询接口相同
+// @Tags Audit
+// @Produce text/csv,application/json
+// @Security BearerAuth
+// @Param format query string false "导出格式 csv 或 json" default(csv)
+// @Success 200 {file} file
+// @Failure 400 {object} handlers.ErrorResponse
+// @Router /api/v1/audit/events/export [get]
+func (h *AuditHandler) ExportAuditEvents(c *gin.Context) {
+	var q models.AuditQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		respondError(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	format := c.DefaultQuery("format", service.AuditExportCSV)
+	if format != service.AuditExportCSV && format != service.AuditExportJSON {
+		respondError(c, http.StatusBadRequest, "不支持的导出格式: "+format)
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == service.AuditExportJSON {
+		contentType = "application/json; charset=utf-8"
+	}
+	filename := fmt.Sprintf("audit-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+	// 响应头已经发出，导出中途失败只能记录错误
+	if err := h.service.Export(&q, format, c.Writer); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+func respondAuditError(c *gin.Context, message string, err error) {
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondError(c, http.StatusInternalServerError, message+": "+err.Error())
+}
@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/configs"
 	"github.com/ciliverse/cilikube/internal/service"
 	"github.com/ciliverse/cilikube/pkg/auth"
 	"github.com/gin-gonic/gin"
@@ -14,15 +19,15 @@ type AuthHandler struct {
 	authService *service.AuthService
 }
 
-func NewAuthHandler() *AuthHandler {
+func NewAuthHandler(authService *service.AuthService) *AuthHandler {
 	return &AuthHandler{
-		authService: &service.AuthService{},
+		authService: authService,
 	}
 }
 
 // Login 用户登录
 // @Summary 用户登录
-// @Description 用户通过用户名和密码登录系统
+// @Description 用户通过用户名和密码登录系统；启用了两步验证时返回 two_factor_required 和 mfa_token，需要再调用 /auth/login/2fa
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -30,6 +35,7 @@ func NewAuthHandler() *AuthHandler {
 // @Success 200 {object} models.LoginResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
 // @Router /api/v1/auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
@@ -41,8 +47,11 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.Login(&req)
+	response, err := h.authService.Login(&req, clientInfo(c))
 	if err != nil {
+		if respondLocked(c, err) {
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"code":    401,
 			"message": err.Error(),
@@ -178,7 +187,7 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 
 // ChangePassword 修改密码
 // @Summary 修改密码
-// @Description 修改当前登录用户的密码
+// @Description 修改当前登录用户的密码，新密码需满足密码策略；修改后所有会话需要重新登录
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -218,13 +227,54 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
-		"message": "密码修改成功",
+		"message": "密码修改成功，请重新登录",
+	})
+}
+
+// RefreshToken 刷新 access token
+// @Summary 刷新 access token
+// @Description 使用刷新令牌换取新的 access token，旧的刷新令牌随即失效
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param refresh body models.RefreshTokenRequest true "刷新令牌"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/auth/refresh [post]
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	response, err := h.authService.RefreshToken(req.RefreshToken, clientInfo(c))
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrTwoFactorRequired) {
+			code = http.StatusUnauthorized
+		}
+		c.JSON(code, gin.H{
+			"code":    code,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "刷新成功",
+		"data":    response,
 	})
 }
 
 // Logout 用户登出
 // @Summary 用户登出
-// @Description 用户登出系统（前端清除token即可）
+// @Description 吊销当前会话的 access token 和刷新令牌
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -232,12 +282,397 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 // @Success 200 {object} map[string]interface{}
 // @Router /api/v1/auth/logout [post]
 func (h *AuthHandler) Logout(c *gin.Context) {
+	userID, _, _, ok := auth.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "用户信息不存在",
+		})
+		return
+	}
+
+	if err := h.authService.Logout(userID, c.GetUint(auth.ContextKeySessionID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "登出失败: " + err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "登出成功",
 	})
 }
 
+// LogoutAll 登出所有会话
+// @Summary 登出所有会话
+// @Description 吊销当前用户在所有设备上的会话
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, _, _, ok := auth.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "用户信息不存在",
+		})
+		return
+	}
+
+	if err := h.authService.LogoutAll(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "登出所有会话失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "已登出所有会话",
+	})
+}
+
+// ListSessions 获取当前用户的有效会话
+// @Summary 获取会话列表
+// @Description 列出当前用户所有未过期、未吊销的会话及其设备和 IP
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.SessionResponse
+// @Router /api/v1/auth/sessions [get]
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, _, _, ok := auth.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "用户信息不存在",
+		})
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(userID, c.GetUint(auth.ContextKeySessionID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取会话列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    sessions,
+	})
+}
+
+// RevokeSession 吊销当前用户的指定会话
+// @Summary 吊销会话
+// @Description 吊销当前用户在某个设备上的会话
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "会话ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, _, _, ok := auth.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "用户信息不存在",
+		})
+		return
+	}
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的会话ID",
+		})
+		return
+	}
+
+	if err := h.authService.RevokeSession(userID, uint(sessionID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "会话已吊销",
+	})
+}
+
+// ListAPITokens 获取当前用户的 API token
+// @Summary 获取 API token 列表
+// @Description 列出当前用户未吊销、未过期的个人访问令牌，不包含令牌明文
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.APITokenResponse
+// @Router /api/v1/auth/tokens [get]
+func (h *AuthHandler) ListAPITokens(c *gin.Context) {
+	userID, _, _, ok := auth.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "用户信息不存在",
+		})
+		return
+	}
+
+	tokens, err := h.authService.ListAPITokens(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取 API token 列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    tokens,
+	})
+}
+
+// CreateAPIToken 创建 API token
+// @Summary 创建 API token
+// @Description 创建带权限范围和有效期的个人访问令牌，令牌明文只在响应中返回一次
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateAPITokenRequest true "令牌名称、权限范围、限定角色和有效天数"
+// @Success 201 {object} models.APITokenResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/auth/tokens [post]
+func (h *AuthHandler) CreateAPIToken(c *gin.Context) {
+	userID, _, _, ok := auth.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "用户信息不存在",
+		})
+		return
+	}
+	// 泄露的 API token 不能用来签发新的令牌
+	if _, isAPIToken := c.Get(auth.ContextKeyAPITokenID); isAPIToken {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "请使用账号登录后创建 API token",
+		})
+		return
+	}
+
+	var req models.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	token, err := h.authService.CreateAPIToken(userID, &req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		var validationErr *service.ValidationError
+		if errors.As(err, &validationErr) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"code":    status,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"code":    201,
+		"message": "创建成功，请妥善保存令牌，之后将无法再次查看",
+		"data":    token,
+	})
+}
+
+// RevokeAPIToken 吊销 API token
+// @Summary 吊销 API token
+// @Description 吊销当前用户的指定个人访问令牌，立即生效
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API token ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/auth/tokens/{id} [delete]
+func (h *AuthHandler) RevokeAPIToken(c *gin.Context) {
+	userID, _, _, ok := auth.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "用户信息不存在",
+		})
+		return
+	}
+	tokenID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的 API token ID",
+		})
+		return
+	}
+
+	if err := h.authService.RevokeAPIToken(userID, uint(tokenID)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrAPITokenNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"code":    status,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "API token 已吊销",
+	})
+}
+
+// OIDCLogin 跳转到身份提供方登录
+// @Summary OIDC 单点登录
+// @Description 生成 state 和 PKCE 参数后重定向到身份提供方的授权页面，state 同时写入 HttpOnly cookie，回调时校验；?redirect=false 时以 JSON 返回授权地址
+// @Tags Auth
+// @Produce json
+// @Success 302
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/auth/oidc/login [get]
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	if !h.authService.OIDCEnabled() {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": service.ErrOIDCDisabled.Error(),
+		})
+		return
+	}
+
+	authURL, state, err := h.authService.StartOIDCLogin(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"code":    502,
+			"message": "连接身份提供方失败: " + err.Error(),
+		})
+		return
+	}
+	setOIDCStateCookie(c, state, int(service.OIDCStateTTL.Seconds()))
+
+	if c.Query("redirect") == "false" {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    200,
+			"message": "获取成功",
+			"data":    gin.H{"auth_url": authURL},
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback 身份提供方回调
+// @Summary OIDC 登录回调
+// @Description 校验授权码并签发令牌，启用了两步验证的用户只返回 mfa_token；配置了 frontend_url 时重定向到前端并在 URL fragment 中携带令牌，否则返回 JSON
+// @Tags Auth
+// @Produce json
+// @Param code query string true "授权码"
+// @Param state query string true "state"
+// @Success 200 {object} models.LoginResponse
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/auth/oidc/callback [get]
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	if !h.authService.OIDCEnabled() {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": service.ErrOIDCDisabled.Error(),
+		})
+		return
+	}
+	if idpErr := c.Query("error"); idpErr != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "身份提供方拒绝登录: " + idpErr + " " + c.Query("error_description"),
+		})
+		return
+	}
+
+	browserState, _ := c.Cookie(oidcStateCookie)
+	setOIDCStateCookie(c, "", -1)
+	response, err := h.authService.CompleteOIDCLogin(c.Request.Context(), c.Query("state"), browserState, c.Query("code"), clientInfo(c))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if frontendURL := configs.GlobalConfig.OIDC.FrontendURL; frontendURL != "" {
+		// 令牌放在 fragment 中，不会出现在服务端访问日志和 Referer 里
+		fragment := url.Values{}
+		switch {
+		case response.TwoFactorRequired:
+			// 需要两步验证时只携带 mfa_token，前端继续调用 /auth/login/2fa
+			fragment.Set("mfa_token", response.MFAToken)
+			fragment.Set("two_factor_required", "true")
+		case response.TwoFactorSetupRequired:
+			fragment.Set("mfa_token", response.MFAToken)
+			fragment.Set("two_factor_setup_required", "true")
+		default:
+			fragment.Set("token", response.Token)
+			fragment.Set("expires_at", response.ExpiresAt.Format(time.RFC3339))
+			fragment.Set("refresh_token", response.RefreshToken)
+			fragment.Set("refresh_expires_at", response.RefreshExpiresAt.Format(time.RFC3339))
+		}
+		c.Redirect(http.StatusFound, frontendURL+"#"+fragment.Encode())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "登录成功",
+		"data":    response,
+	})
+}
+
+// oidcStateCookie 发起 OIDC 登录的浏览器保存 state 的 cookie，回调时校验，防止登录 CSRF
+const oidcStateCookie = "cilikube_oidc_state"
+
+// setOIDCStateCookie 设置或清除 (maxAge < 0) state cookie。IdP 回调是跨站的顶层跳转，
+// 使用 SameSite=Lax 才能带上 cookie；回调地址为 https 时只通过 https 发送
+func setOIDCStateCookie(c *gin.Context, state string, maxAge int) {
+	secure := c.Request.TLS != nil || strings.HasPrefix(configs.GlobalConfig.OIDC.RedirectURL, "https://")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, maxAge, "/api/v1/auth/oidc", "", secure, true)
+}
+
+// clientInfo 读取请求的设备和 IP 信息，记录在会话中
+func clientInfo(c *gin.Context) models.ClientInfo {
+	return models.ClientInfo{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
+}
+
 // GetUserList 获取用户列表（管理员）
 // @Summary 获取用户列表
 // @Description 管理员获取系统中所有用户列表
@@ -333,6 +768,40 @@ func (h *AuthHandler) UpdateUserStatus(c *gin.Context) {
 	})
 }
 
+// UnlockUser 解除用户登录锁定（管理员）
+// @Summary 解除登录锁定
+// @Description 清除用户名的连续登录失败记录，立即允许该用户登录
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/auth/users/{id}/unlock [post]
+func (h *AuthHandler) UnlockUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	if err := h.authService.UnlockUser(uint(userID), c.GetString(auth.ContextKeyUsername), c.ClientIP()); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "已解除登录锁定",
+	})
+}
+
 // DeleteUser 删除用户（管理员）
 // @Summary 删除用户
 // @Description 管理员删除用户账号
@@ -380,3 +849,17 @@ func (h *AuthHandler) DeleteUser(c *gin.Context) {
 		"message": "用户删除成功",
 	})
 }
+
+// respondLocked 登录被锁定时返回 429 和 Retry-After，返回值表示是否已写入响应
+func respondLocked(c *gin.Context, err error) bool {
+	var locked *service.AccountLockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(int(locked.RetryAfter().Seconds())+1))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"code":    429,
+		"message": err.Error(),
+	})
+	return true
+}
@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/internal/service"
+	"github.com/ciliverse/cilikube/pkg/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyTwoFactorLogin 登录第二步
+// @Summary 两步验证登录
+// @Description 提交登录返回的 mfa_token 和身份验证器中的验证码 (或恢复码)，验证通过后签发令牌
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorLoginRequest true "mfa_token 和验证码"
+// @Success 200 {object} models.LoginResponse
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/auth/login/2fa [post]
+func (h *AuthHandler) VerifyTwoFactorLogin(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	response, err := h.authService.VerifyTwoFactorLogin(&req, clientInfo(c))
+	if err != nil {
+		if respondLocked(c, err) {
+			return
+		}
+		respondTwoFactorError(c, err, http.StatusUnauthorized)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "登录成功",
+		"data":    response,
+	})
+}
+
+// BeginTwoFactorEnrollment 登录时按安全策略绑定两步验证
+// @Summary 登录时获取两步验证绑定信息
+// @Description 登录返回 two_factor_setup_required 时，使用 mfa_token 获取 TOTP 密钥和二维码地址
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorSetupLoginRequest true "mfa_token"
+// @Success 200 {object} models.TOTPSetupResponse
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/auth/login/2fa/setup [post]
+func (h *AuthHandler) BeginTwoFactorEnrollment(c *gin.Context) {
+	var req models.TwoFactorSetupLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	setup, err := h.authService.BeginTwoFactorEnrollment(req.MFAToken)
+	if err != nil {
+		respondTwoFactorError(c, err, http.StatusUnauthorized)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    setup,
+	})
+}
+
+// CompleteTwoFactorEnrollment 登录时完成两步验证绑定
+// @Summary 登录时启用两步验证
+// @Description 提交身份验证器中的验证码完成绑定，返回恢复码和登录令牌
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorLoginRequest true "mfa_token 和验证码"
+// @Success 200 {object} models.TwoFactorEnrollResponse
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/auth/login/2fa/enable [post]
+func (h *AuthHandler) CompleteTwoFactorEnrollment(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	response, err := h.authService.CompleteTwoFactorEnrollment(&req, clientInfo(c))
+	if err != nil {
+		respondTwoFactorError(c, err, http.StatusUnauthorized)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "两步验证已启用，请妥善保存恢复码",
+		"data":    response,
+	})
+}
+
+// BeginTOTPSetup 获取两步验证绑定信息
+// @Summary 获取两步验证绑定信息
+// @Description 生成新的 TOTP 密钥和 otpauth:// 地址，提交验证码启用前不会生效
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.TOTPSetupResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/auth/2fa/setup [post]
+func (h *AuthHandler) BeginTOTPSetup(c *gin.Context) {
+	userID, _, _, ok := auth.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "用户信息不存在",
+		})
+		return
+	}
+
+	setup, err := h.authService.BeginTOTPSetup(userID)
+	if err != nil {
+		respondTwoFactorError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    setup,
+	})
+}
+
+// EnableTOTP 启用两步验证
+// @Summary 启用两步验证
+// @Description 提交身份验证器中的验证码启用两步验证，返回一次性恢复码
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TOTPCodeRequest true "验证码"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/auth/2fa/enable [post]
+func (h *AuthHandler) EnableTOTP(c *gin.Context) {
+	userID, req, ok := bindTOTPCode(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.authService.EnableTOTP(userID, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "两步验证已启用，请妥善保存恢复码",
+		"data":    models.RecoveryCodesResponse{RecoveryCodes: codes},
+	})
+}
+
+// DisableTOTP 关闭两步验证
+// @Summary 关闭两步验证
+// @Description 提交验证码或恢复码关闭两步验证，安全策略要求两步验证的用户不能关闭
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TOTPCodeRequest true "验证码或恢复码"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/auth/2fa/disable [post]
+func (h *AuthHandler) DisableTOTP(c *gin.Context) {
+	userID, req, ok := bindTOTPCode(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.DisableTOTP(userID, req.Code); err != nil {
+		respondTwoFactorError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "两步验证已关闭",
+	})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+// @Summary 重新生成恢复码
+// @Description 提交验证码重新生成恢复码，旧的恢复码全部失效
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TOTPCodeRequest true "验证码"
+// @Success 200 {object} models.RecoveryCodesResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/auth/2fa/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, req, ok := bindTOTPCode(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "恢复码已重新生成",
+		"data":    models.RecoveryCodesResponse{RecoveryCodes: codes},
+	})
+}
+
+// ResetTwoFactor 重置用户的两步验证（管理员）
+// @Summary 重置用户两步验证
+// @Description 用户丢失身份验证器时由管理员关闭其两步验证，用户下次登录时可重新绑定
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/auth/users/{id}/2fa [delete]
+func (h *AuthHandler) ResetTwoFactor(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	if err := h.authService.ResetTwoFactor(uint(userID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "两步验证已重置",
+	})
+}
+
+// GetSecurityPolicy 获取安全策略（管理员）
+// @Summary 获取安全策略
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SecurityPolicy
+// @Router /api/v1/auth/security-policy [get]
+func (h *AuthHandler) GetSecurityPolicy(c *gin.Context) {
+	policy, err := h.authService.GetSecurityPolicy()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "获取安全策略失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    policy,
+	})
+}
+
+// UpdateSecurityPolicy 更新安全策略（管理员）
+// @Summary 更新安全策略
+// @Description 开启 require_two_factor_for_writers 后，拥有写权限的用户必须启用两步验证才能登录
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.UpdateSecurityPolicyRequest true "安全策略"
+// @Success 200 {object} models.SecurityPolicy
+// @Router /api/v1/auth/security-policy [put]
+func (h *AuthHandler) UpdateSecurityPolicy(c *gin.Context) {
+	var req models.UpdateSecurityPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	policy, err := h.authService.UpdateSecurityPolicy(&req)
+	if err != nil {
+		respondTwoFactorError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "安全策略已更新",
+		"data":    policy,
+	})
+}
+
+func bindTOTPCode(c *gin.Context) (uint, *models.TOTPCodeRequest, bool) {
+	userID, _, _, ok := auth.GetCurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "用户信息不存在",
+		})
+		return 0, nil, false
+	}
+	var req models.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return 0, nil, false
+	}
+	return userID, &req, true
+}
+
+// respondTwoFactorError 参数和验证码错误返回 400，登录第二步失效返回 401，其他错误使用 fallback
+func respondTwoFactorError(c *gin.Context, err error, fallback int) {
+	status := fallback
+	var validationErr *service.ValidationError
+	switch {
+	case errors.Is(err, service.ErrInvalidMFAToken):
+		status = http.StatusUnauthorized
+	case errors.Is(err, service.ErrInvalidTOTPCode), errors.As(err, &validationErr):
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": err.Error(),
+	})
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/internal/service"
+	"github.com/ciliverse/cilikube/pkg/utils"
+	"github.com/gin-gonic/gin"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// CertificateHandler 证书过期清单
+type CertificateHandler struct {
+	service *service.CertificateService
+}
+
+func NewCertificateHandler(svc *service.CertificateService) *CertificateHandler {
+	return &CertificateHandler{service: svc}
+}
+
+// ListCertificates godoc
+// @Summary 证书过期清单 (所有命名空间)
+// @Description 扫描 kubernetes.io/tls 类型的 Secret 和被 Ingress tls 引用的 Secret，返回证书主题、SAN、签发者、过期时间和剩余天数，并列出悬空的 Ingress tls 引用
+// @Tags Certificates
+// @Produce json
+// @Security BearerAuth
+// @Param threshold_days query int false "只返回剩余天数不超过该值的证书"
+// @Param sort_by query string false "排序字段: daysRemaining 或 name" default(daysRemaining)
+// @Param order query string false "排序方向: asc 或 desc" default(asc)
+// @Success 200 {object} models.CertificateInventoryResponse
+// @Failure 400 {object} handlers.ErrorResponse
+// @Failure 500 {object} handlers.ErrorResponse
+// @Router /api/v1/certificates [get]
+func (h *CertificateHandler) ListCertificates(c *gin.Context) {
+	h.listCertificates(c, "")
+}
+
+// ListNamespaceCertificates godoc
+// @Summary 证书过期清单 (单个命名空间)
+// @Description 与 /api/v1/certificates 相同，只扫描指定命名空间
+// @Tags Certificates
+// @Produce json
+// @Security BearerAuth
+// @Param namespace path string true "Namespace"
+// @Param threshold_days query int false "只返回剩余天数不超过该值的证书"
+// @Param sort_by query string false "排序字段: daysRemaining 或 name" default(daysRemaining)
+// @Param order query string false "排序方向: asc 或 desc" default(asc)
+// @Success 200 {object} models.CertificateInventoryResponse
+// @Failure 400 {object} handlers.ErrorResponse
+// @Failure 500 {object} handlers.ErrorResponse
+// @Router /api/v1/namespaces/{namespace}/certificates [get]
+func (h *CertificateHandler) ListNamespaceCertificates(c *gin.Context) {
+	namespace := strings.TrimSpace(c.Param("namespace"))
+	if !utils.ValidateNamespace(namespace) {
+		respondError(c, http.StatusBadRequest, "无效的命名空间格式")
+		return
+	}
+	h.listCertificates(c, namespace)
+}
+
+func (h *CertificateHandler) listCertificates(c *gin.Context, namespace string) {
+	var query models.CertificateInventoryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondError(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	inventory, err := forUser(c, h.service).Inventory(namespace, &query)
+	if err != nil {
+		var validationErr *service.ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			respondError(c, http.StatusBadRequest, err.Error())
+		case k8serrors.IsForbidden(err):
+			respondError(c, http.StatusForbidden, "没有权限读取 Secret 或 Ingress: "+err.Error())
+		default:
+			respondError(c, http.StatusInternalServerError, "获取证书清单失败: "+err.Error())
+		}
+		return
+	}
+	respondSuccess(c, http.StatusOK, inventory)
+}
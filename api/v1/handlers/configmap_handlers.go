@@ -43,7 +43,7 @@ func (h *ConfigMapHandler) ListConfigMaps(c *gin.Context) {
 	labelSelector := c.Query("labelSelector")
 	limit := utils.ParseInt(c.DefaultQuery("limit", "100"), 100)
 
-	cmList, err := h.service.List(namespace, labelSelector, int64(limit))
+	cmList, err := forUser(c, h.service).List(namespace, labelSelector, int64(limit))
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取ConfigMap列表失败: "+err.Error())
 		return
@@ -81,7 +81,7 @@ func (h *ConfigMapHandler) GetConfigMap(c *gin.Context) {
 		return
 	}
 
-	cm, err := h.service.Get(namespace, name)
+	cm, err := forUser(c, h.service).Get(namespace, name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "ConfigMap不存在")
@@ -128,7 +128,7 @@ func (h *ConfigMapHandler) CreateConfigMap(c *gin.Context) {
 		cm.APIVersion = "v1"
 	}
 
-	createdCM, err := h.service.Create(namespace, &cm)
+	createdCM, err := forUser(c, h.service).Create(namespace, &cm)
 	if err != nil {
 		if errors.IsAlreadyExists(err) {
 			respondError(c, http.StatusConflict, "ConfigMap已存在")
@@ -184,7 +184,7 @@ func (h *ConfigMapHandler) UpdateConfigMap(c *gin.Context) {
 		cm.APIVersion = "v1"
 	}
 
-	updatedCM, err := h.service.Update(namespace, &cm)
+	updatedCM, err := forUser(c, h.service).Update(namespace, &cm)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "ConfigMap不存在")
@@ -225,7 +225,7 @@ func (h *ConfigMapHandler) DeleteConfigMap(c *gin.Context) {
 		return
 	}
 
-	err := h.service.Delete(namespace, name)
+	err := forUser(c, h.service).Delete(namespace, name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			c.Status(http.StatusNoContent)
@@ -34,7 +34,7 @@ func (h *DaemonSetHandler) ListDaemonSets(c *gin.Context) {
 	}
 
 	// 2. 调用服务层获取DaemonSet列表
-	daemonsets, err := h.service.List(namespace, c.Query("selector"))
+	daemonsets, err := forUser(c, h.service).List(namespace, c.Query("selector"))
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取DaemonSet列表失败: "+err.Error())
 		return
@@ -71,7 +71,7 @@ func (h *DaemonSetHandler) CreateDaemonSet(c *gin.Context) {
 		Spec: req.Spec,
 	}
 
-	createdDaemonset, err := h.service.Create(namespace, daemonset)
+	createdDaemonset, err := forUser(c, h.service).Create(namespace, daemonset)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "创建DaemonSet失败: "+err.Error())
 		return
@@ -97,7 +97,7 @@ func (h *DaemonSetHandler) GetDaemonSet(c *gin.Context) {
 	}
 
 	// 2. 调用服务层获取DaemonSet详情
-	daemonset, err := h.service.Get(namespace, name)
+	daemonset, err := forUser(c, h.service).Get(namespace, name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "DaemonSet不存在")
@@ -144,7 +144,7 @@ func (h *DaemonSetHandler) UpdateDaemonSet(c *gin.Context) {
 		Spec: req.Spec,
 	}
 
-	updatedDaemonset, err := h.service.Update(namespace, daemonset)
+	updatedDaemonset, err := forUser(c, h.service).Update(namespace, daemonset)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "更新DaemonSet失败: "+err.Error())
 		return
@@ -171,7 +171,7 @@ func (h *DaemonSetHandler) DeleteDaemonSet(c *gin.Context) {
 	}
 
 	// 2. 调用服务层删除DaemonSet
-	if err := h.service.Delete(namespace, name); err != nil {
+	if err := forUser(c, h.service).Delete(namespace, name); err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "DaemonSet不存在")
 			return
@@ -194,7 +194,7 @@ func (h *DaemonSetHandler) WatchDaemonSets(c *gin.Context) {
 	}
 
 	// 2. 调用服务层Watch DaemonSets
-	watcher, err := h.service.Watch(namespace, c.Query("selector"))
+	watcher, err := forUser(c, h.service).Watch(namespace, c.Query("selector"))
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "Watch DaemonSets失败: "+err.Error())
 		return
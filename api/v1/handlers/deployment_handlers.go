@@ -36,7 +36,7 @@ func (h *DeploymentHandler) ListDeployments(c *gin.Context) {
 	}
 
 	// 2. 调用服务层获取Deployment列表
-	deployments, err := h.service.List(namespace)
+	deployments, err := forUser(c, h.service).List(namespace)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取Deployment列表失败: "+err.Error())
 		return
@@ -88,7 +88,7 @@ func (h *DeploymentHandler) CreateDeployment(c *gin.Context) {
 	}
 
 	// 调用服务层创建Deployment
-	createdDeployment, err := h.service.Create(namespace, deployment)
+	createdDeployment, err := forUser(c, h.service).Create(namespace, deployment)
 	if err != nil {
 		if errors.IsAlreadyExists(err) {
 			respondError(c, http.StatusConflict, "Deployment已存在")
@@ -117,7 +117,7 @@ func (h *DeploymentHandler) GetDeployment(c *gin.Context) {
 	}
 
 	// 2. 调用服务层获取Deployment详情
-	deployment, err := h.service.Get(namespace, name)
+	deployment, err := forUser(c, h.service).Get(namespace, name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "Deployment不存在")
@@ -174,7 +174,7 @@ func (h *DeploymentHandler) UpdateDeployment(c *gin.Context) {
 	}
 
 	// 调用服务层更新Deployment
-	resultDeployment, err := h.service.Update(namespace, name, updateDeployment)
+	resultDeployment, err := forUser(c, h.service).Update(namespace, name, updateDeployment)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "Deployment不存在 (可能在更新期间被删除)")
@@ -206,7 +206,7 @@ func (h *DeploymentHandler) DeleteDeployment(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.Delete(namespace, name); err != nil {
+	if err := forUser(c, h.service).Delete(namespace, name); err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "Deployment不存在")
 			return
@@ -230,7 +230,7 @@ func (h *DeploymentHandler) WatchDeployments(c *gin.Context) {
 	labelSelector := c.Query("labelSelector")
 
 	// 创建 Deployment Watcher
-	watcher, err := h.service.Watch(namespace, labelSelector)
+	watcher, err := forUser(c, h.service).Watch(namespace, labelSelector)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "开始监听Deployment失败: "+err.Error())
 		return
@@ -292,7 +292,7 @@ func (h *DeploymentHandler) ScaleDeployment(c *gin.Context) {
 	}
 
 	// 2. 调用服务层修改Deployment的副本数
-	deployment, err := h.service.Scale(namespace, name, req.Replicas)
+	deployment, err := forUser(c, h.service).Scale(namespace, name, req.Replicas)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "Deployment不存在")
@@ -328,7 +328,7 @@ func (h *DeploymentHandler) GetDeploymentPods(c *gin.Context) {
 		limit = 500 // Fallback
 	}
 
-	pods, err := h.service.PodList(namespace, name, limit)
+	pods, err := forUser(c, h.service).PodList(namespace, name, limit)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取Pod列表失败: "+err.Error())
 		return
@@ -24,7 +24,7 @@ func (h *EventsHandler) ListEventsHandler(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "无效的命名空间")
 		return
 	}
-	events := h.service.List(namespace)
+	events := forUser(c, h.service).List(namespace)
 	respondSuccess(c, http.StatusOK, events)
 }
 
@@ -39,6 +39,6 @@ func (h *EventsHandler) GetEventsHandler(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "事件名称不能为空")
 		return
 	}
-	event := h.service.Get(namespace, name)
+	event := forUser(c, h.service).Get(namespace, name)
 	respondSuccess(c, http.StatusOK, event)
 }
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"github.com/ciliverse/cilikube/pkg/auth"
+	"github.com/gin-gonic/gin"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// clientScoped 可以替换 Kubernetes 客户端的服务
+type clientScoped[T any] interface {
+	WithClient(client kubernetes.Interface, config *rest.Config) T
+}
+
+// forUser 开启用户模拟时返回以当前用户身份访问集群的服务，否则原样返回
+func forUser[T clientScoped[T]](c *gin.Context, svc T) T {
+	client, ok := auth.ImpersonatedClient(c)
+	if !ok {
+		return svc
+	}
+	return svc.WithClient(client.Clientset, client.Config)
+}
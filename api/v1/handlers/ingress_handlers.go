@@ -33,7 +33,7 @@ func (h *IngressHandler) ListIngresses(c *gin.Context) {
 	}
 
 	// 2. 调用服务层获取Ingress列表
-	ingresses, err := h.service.List(namespace, c.Query("selector"), 0)
+	ingresses, err := forUser(c, h.service).List(namespace, c.Query("selector"), 0)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取Ingress列表失败: "+err.Error())
 		return
@@ -75,7 +75,7 @@ func (h *IngressHandler) CreateIngress(c *gin.Context) {
 		Spec: req.Spec,
 	}
 
-	createdIngress, err := h.service.Create(namespace, ingress)
+	createdIngress, err := forUser(c, h.service).Create(namespace, ingress)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "创建Ingress失败: "+err.Error())
 		return
@@ -101,7 +101,7 @@ func (h *IngressHandler) GetIngress(c *gin.Context) {
 	}
 
 	// 2. 调用服务层获取Ingress详情
-	ingress, err := h.service.Get(namespace, name)
+	ingress, err := forUser(c, h.service).Get(namespace, name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "Ingress不存在")
@@ -148,7 +148,7 @@ func (h *IngressHandler) UpdateIngress(c *gin.Context) {
 		Spec: req.Spec,
 	}
 
-	updatedIngress, err := h.service.Update(namespace, ingress)
+	updatedIngress, err := forUser(c, h.service).Update(namespace, ingress)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "更新Ingress失败: "+err.Error())
 		return
@@ -175,7 +175,7 @@ func (h *IngressHandler) DeleteIngress(c *gin.Context) {
 	}
 
 	// 2. 调用服务层删除Ingress
-	if err := h.service.Delete(namespace, name); err != nil {
+	if err := forUser(c, h.service).Delete(namespace, name); err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "Ingress不存在")
 			return
@@ -198,7 +198,7 @@ func (h *IngressHandler) WatchIngresses(c *gin.Context) {
 	}
 
 	// 2. 调用服务层Watch Ingresses
-	watcher, err := h.service.Watch(namespace, c.Query("selector"))
+	watcher, err := forUser(c, h.service).Watch(namespace, c.Query("selector"))
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "Watch Ingresses失败: "+err.Error())
 		return
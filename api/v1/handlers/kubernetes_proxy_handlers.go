@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"k8s.io/apimachinery/pkg/util/proxy"
@@ -20,7 +21,7 @@ func NewProxyHandler(service *service.ProxyService) *ProxyHandler {
 }
 
 func (p *ProxyHandler) Proxy(c *gin.Context) {
-	config := p.service.GetConfig()
+	config := forUser(c, p.service).GetConfig()
 	transport, err := rest.TransportFor(config)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "服务器内部错误: "+err.Error())
@@ -31,6 +32,13 @@ func (p *ProxyHandler) Proxy(c *gin.Context) {
 		respondError(c, http.StatusInternalServerError, "服务器内部错误: "+err.Error())
 		return
 	}
+	// 客户端携带的是 CiliKube 的令牌；Impersonate-* 头会让 client-go 跳过用户模拟，都不能转发给 API Server
+	c.Request.Header.Del("Authorization")
+	for key := range c.Request.Header {
+		if strings.HasPrefix(key, "Impersonate-") {
+			c.Request.Header.Del(key)
+		}
+	}
 	httpProxy := proxy.NewUpgradeAwareHandler(target, transport, false, false, nil)
 	httpProxy.UpgradeTransport = proxy.NewUpgradeRequestRoundTripper(transport, transport)
 	httpProxy.ServeHTTP(c.Writer, c.Request)
@@ -26,7 +26,7 @@ func NewNamespaceHandler(svc *service.NamespaceService) *NamespaceHandler {
 // ListNamespaces ...
 func (h *NamespaceHandler) ListNamespaces(c *gin.Context) {
 	// 1. 调用服务层获取Namespace列表
-	namespaces, err := h.service.List(c.Query("selector"), 0)
+	namespaces, err := forUser(c, h.service).List(c.Query("selector"), 0)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取Namespace列表失败: "+err.Error())
 		return
@@ -55,7 +55,7 @@ func (h *NamespaceHandler) CreateNamespace(c *gin.Context) {
 		},
 	}
 
-	createdNamespace, err := h.service.Create(namespace)
+	createdNamespace, err := forUser(c, h.service).Create(namespace)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "创建Namespace失败: "+err.Error())
 		return
@@ -75,7 +75,7 @@ func (h *NamespaceHandler) GetNamespace(c *gin.Context) {
 	}
 
 	// 2. 调用服务层获取Namespace详情
-	namespace, err := h.service.Get(name)
+	namespace, err := forUser(c, h.service).Get(name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "Namespace不存在")
@@ -114,7 +114,7 @@ func (h *NamespaceHandler) UpdateNamespace(c *gin.Context) {
 		},
 	}
 
-	updatedNamespace, err := h.service.Update(namespace)
+	updatedNamespace, err := forUser(c, h.service).Update(namespace)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "更新Namespace失败: "+err.Error())
 		return
@@ -135,7 +135,7 @@ func (h *NamespaceHandler) DeleteNamespace(c *gin.Context) {
 	}
 
 	// 2. 调用服务层删除Namespace
-	if err := h.service.Delete(name); err != nil {
+	if err := forUser(c, h.service).Delete(name); err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "Namespace不存在")
 			return
@@ -151,7 +151,7 @@ func (h *NamespaceHandler) DeleteNamespace(c *gin.Context) {
 // WatchNamespaces ...
 func (h *NamespaceHandler) WatchNamespaces(c *gin.Context) {
 	// 1. 调用服务层Watch Namespaces
-	watcher, err := h.service.Watch(c.Query("selector"))
+	watcher, err := forUser(c, h.service).Watch(c.Query("selector"))
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "Watch Namespaces失败: "+err.Error())
 		return
@@ -34,7 +34,7 @@ func (h *NetworkPolicyHandler) ListNetworkPolicies(c *gin.Context) {
 	}
 
 	// 2. 调用服务层获取NetworkPolicy列表
-	networkPolicies, err := h.service.List(namespace, c.Query("selector"), 0)
+	networkPolicies, err := forUser(c, h.service).List(namespace, c.Query("selector"), 0)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取NetworkPolicy列表失败: "+err.Error())
 		return
@@ -71,7 +71,7 @@ func (h *NetworkPolicyHandler) CreateNetworkPolicy(c *gin.Context) {
 		Spec: req.Spec,
 	}
 
-	createdNetworkPolicy, err := h.service.Create(namespace, networkPolicy)
+	createdNetworkPolicy, err := forUser(c, h.service).Create(namespace, networkPolicy)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "创建NetworkPolicy失败: "+err.Error())
 		return
@@ -97,7 +97,7 @@ func (h *NetworkPolicyHandler) GetNetworkPolicy(c *gin.Context) {
 	}
 
 	// 2. 调用服务层获取NetworkPolicy详情
-	networkPolicy, err := h.service.Get(namespace, name)
+	networkPolicy, err := forUser(c, h.service).Get(namespace, name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "NetworkPolicy不存在")
@@ -144,7 +144,7 @@ func (h *NetworkPolicyHandler) UpdateNetworkPolicy(c *gin.Context) {
 		Spec: req.Spec,
 	}
 
-	updatedNetworkPolicy, err := h.service.Update(namespace, networkPolicy)
+	updatedNetworkPolicy, err := forUser(c, h.service).Update(namespace, networkPolicy)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "更新NetworkPolicy失败: "+err.Error())
 		return
@@ -171,7 +171,7 @@ func (h *NetworkPolicyHandler) DeleteNetworkPolicy(c *gin.Context) {
 	}
 
 	// 2. 调用服务层删除NetworkPolicy
-	if err := h.service.Delete(namespace, name); err != nil {
+	if err := forUser(c, h.service).Delete(namespace, name); err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "NetworkPolicy不存在")
 			return
@@ -194,7 +194,7 @@ func (h *NetworkPolicyHandler) WatchNetworkPolicies(c *gin.Context) {
 	}
 
 	// 2. 调用服务层Watch NetworkPolicies
-	watcher, err := h.service.Watch(namespace, c.Query("selector"))
+	watcher, err := forUser(c, h.service).Watch(namespace, c.Query("selector"))
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "Watch NetworkPolicies失败: "+err.Error())
 		return
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"context"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/ciliverse/cilikube/api/v1/models"
 	"github.com/ciliverse/cilikube/internal/service"
@@ -26,7 +28,7 @@ func NewNodeHandler(svc *service.NodeService) *NodeHandler {
 // ListNodes ...
 func (h *NodeHandler) ListNodes(c *gin.Context) {
 	// 1. 调用服务层获取Node列表
-	nodes, err := h.service.List(c.Query("selector"), 0)
+	nodes, err := forUser(c, h.service).List(c.Query("selector"), 0)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取Node列表失败: "+err.Error())
 		return
@@ -56,7 +58,7 @@ func (h *NodeHandler) CreateNode(c *gin.Context) {
 		Spec: req.Spec,
 	}
 
-	createdNode, err := h.service.Create(node)
+	createdNode, err := forUser(c, h.service).Create(node)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "创建Node失败: "+err.Error())
 		return
@@ -76,7 +78,7 @@ func (h *NodeHandler) GetNode(c *gin.Context) {
 	}
 
 	// 2. 调用服务层获取Node详情
-	node, err := h.service.Get(name)
+	node, err := forUser(c, h.service).Get(name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "Node不存在")
@@ -90,6 +92,30 @@ func (h *NodeHandler) GetNode(c *gin.Context) {
 	respondSuccess(c, http.StatusOK, models.ToNodeResponse(node))
 }
 
+// GetNodeDetail 获取Node详情 (资源分配、Pod 分布、状况与污点)
+func (h *NodeHandler) GetNodeDetail(c *gin.Context) {
+	name := c.Param("name")
+	// 1. 参数校验
+	if !utils.ValidateResourceName(name) {
+		respondError(c, http.StatusBadRequest, "无效的Node名称格式")
+		return
+	}
+
+	// 2. 调用服务层汇总Node详情
+	detail, err := forUser(c, h.service).GetDetail(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			respondError(c, http.StatusNotFound, "Node不存在")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, "获取Node详情失败: "+err.Error())
+		return
+	}
+
+	// 3. 返回结果
+	respondSuccess(c, http.StatusOK, detail)
+}
+
 // UpdateNode ...
 func (h *NodeHandler) UpdateNode(c *gin.Context) {
 	name := c.Param("name")
@@ -116,7 +142,7 @@ func (h *NodeHandler) UpdateNode(c *gin.Context) {
 		Spec: req.Spec,
 	}
 
-	updatedNode, err := h.service.Update(node)
+	updatedNode, err := forUser(c, h.service).Update(node)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "更新Node失败: "+err.Error())
 		return
@@ -137,7 +163,7 @@ func (h *NodeHandler) DeleteNode(c *gin.Context) {
 	}
 
 	// 2. 调用服务层删除Node
-	if err := h.service.Delete(name); err != nil {
+	if err := forUser(c, h.service).Delete(name); err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "Node不存在")
 			return
@@ -153,7 +179,7 @@ func (h *NodeHandler) DeleteNode(c *gin.Context) {
 // WatchNodes ...
 func (h *NodeHandler) WatchNodes(c *gin.Context) {
 	// 1. 调用服务层Watch Nodes
-	watcher, err := h.service.Watch(c.Query("selector"))
+	watcher, err := forUser(c, h.service).Watch(c.Query("selector"))
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "Watch Nodes失败: "+err.Error())
 		return
@@ -169,3 +195,220 @@ func (h *NodeHandler) WatchNodes(c *gin.Context) {
 		return true
 	})
 }
+
+// CordonNode 将Node标记为不可调度
+func (h *NodeHandler) CordonNode(c *gin.Context) {
+	h.setSchedulable(c, false)
+}
+
+// UncordonNode 恢复Node的可调度状态
+func (h *NodeHandler) UncordonNode(c *gin.Context) {
+	h.setSchedulable(c, true)
+}
+
+func (h *NodeHandler) setSchedulable(c *gin.Context, schedulable bool) {
+	name := c.Param("name")
+	// 1. 参数校验
+	if !utils.ValidateResourceName(name) {
+		respondError(c, http.StatusBadRequest, "无效的Node名称格式")
+		return
+	}
+
+	// 2. 调用服务层修改调度状态
+	var node *corev1.Node
+	var err error
+	if schedulable {
+		node, err = forUser(c, h.service).Uncordon(name)
+	} else {
+		node, err = forUser(c, h.service).Cordon(name)
+	}
+	if err != nil {
+		if errors.IsNotFound(err) {
+			respondError(c, http.StatusNotFound, "Node不存在")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, "修改Node调度状态失败: "+err.Error())
+		return
+	}
+
+	// 3. 返回结果
+	respondSuccess(c, http.StatusOK, models.ToNodeResponse(node))
+}
+
+// DrainNode 封锁并驱逐Node上的Pod，通过 SSE 推送每个 Pod 的驱逐进度
+func (h *NodeHandler) DrainNode(c *gin.Context) {
+	name := c.Param("name")
+	var req models.DrainNodeRequest
+
+	// 1. 参数校验 (请求体可选)
+	if !utils.ValidateResourceName(name) {
+		respondError(c, http.StatusBadRequest, "无效的Node名称格式")
+		return
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "无效的驱逐参数: "+err.Error())
+			return
+		}
+	}
+	if req.TimeoutSeconds < 0 || (req.GracePeriodSeconds != nil && *req.GracePeriodSeconds < 0) {
+		respondError(c, http.StatusBadRequest, "timeoutSeconds 和 gracePeriodSeconds 不能为负数")
+		return
+	}
+	if _, err := forUser(c, h.service).Get(name); err != nil {
+		if errors.IsNotFound(err) {
+			respondError(c, http.StatusNotFound, "Node不存在")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, "获取Node失败: "+err.Error())
+		return
+	}
+
+	// 2. 异步执行驱逐，事件通过 channel 转发给 SSE
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	events := make(chan models.NodeDrainEvent, 64)
+	done := make(chan error, 1)
+	go func() {
+		done <- forUser(c, h.service).Drain(ctx, name, service.DrainOptions{
+			GracePeriodSeconds: req.GracePeriodSeconds,
+			Timeout:            time.Duration(req.TimeoutSeconds) * time.Second,
+			Force:              req.Force,
+			DeleteEmptyDirData: req.DeleteEmptyDirData,
+		}, func(event models.NodeDrainEvent) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	// 3. 推送进度
+	setSSEHeaders(c)
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event := <-events:
+			c.SSEvent("progress", event)
+			return true
+		case err := <-done:
+			// Drain 返回后不会再有新事件，先把缓冲中剩余的事件推送完
+			for len(events) > 0 {
+				c.SSEvent("progress", <-events)
+			}
+			if err != nil {
+				c.SSEvent("error", gin.H{"message": "驱逐Node失败: " + err.Error()})
+			} else {
+				c.SSEvent("done", gin.H{"message": "驱逐完成"})
+			}
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+// UpdateNodeLabels 新增/覆盖/删除Node标签
+func (h *NodeHandler) UpdateNodeLabels(c *gin.Context) {
+	name := c.Param("name")
+	var req models.NodeLabelsRequest
+
+	// 1. 参数校验
+	if !utils.ValidateResourceName(name) {
+		respondError(c, http.StatusBadRequest, "无效的Node名称格式")
+		return
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的标签参数: "+err.Error())
+		return
+	}
+
+	// 2. 调用服务层修改标签
+	node, err := forUser(c, h.service).UpdateLabels(name, &req)
+	if err != nil {
+		respondNodeMetadataError(c, "修改Node标签失败: ", err)
+		return
+	}
+
+	// 3. 返回结果
+	respondSuccess(c, http.StatusOK, models.ToNodeResponse(node))
+}
+
+// UpdateNodeTaints 新增/更新/删除Node污点
+func (h *NodeHandler) UpdateNodeTaints(c *gin.Context) {
+	name := c.Param("name")
+	var req models.NodeTaintsRequest
+
+	// 1. 参数校验
+	if !utils.ValidateResourceName(name) {
+		respondError(c, http.StatusBadRequest, "无效的Node名称格式")
+		return
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的污点参数: "+err.Error())
+		return
+	}
+
+	// 2. 调用服务层修改污点
+	node, err := forUser(c, h.service).UpdateTaints(name, &req)
+	if err != nil {
+		respondNodeMetadataError(c, "修改Node污点失败: ", err)
+		return
+	}
+
+	// 3. 返回结果
+	respondSuccess(c, http.StatusOK, models.ToNodeResponse(node))
+}
+
+// BatchUpdateNodeLabels 按名称列表或标签选择器批量修改Node标签
+func (h *NodeHandler) BatchUpdateNodeLabels(c *gin.Context) {
+	var req models.BatchNodeLabelsRequest
+
+	// 1. 参数校验
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的标签参数: "+err.Error())
+		return
+	}
+
+	// 2. 调用服务层批量修改标签
+	results, err := forUser(c, h.service).BatchUpdateLabels(&req.NodeBatchTarget, &req.NodeLabelsRequest)
+	if err != nil {
+		respondNodeMetadataError(c, "批量修改Node标签失败: ", err)
+		return
+	}
+
+	// 3. 返回每个节点的结果
+	respondSuccess(c, http.StatusOK, results)
+}
+
+// BatchUpdateNodeTaints 按名称列表或标签选择器批量修改Node污点
+func (h *NodeHandler) BatchUpdateNodeTaints(c *gin.Context) {
+	var req models.BatchNodeTaintsRequest
+
+	// 1. 参数校验
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的污点参数: "+err.Error())
+		return
+	}
+
+	// 2. 调用服务层批量修改污点
+	results, err := forUser(c, h.service).BatchUpdateTaints(&req.NodeBatchTarget, &req.NodeTaintsRequest)
+	if err != nil {
+		respondNodeMetadataError(c, "批量修改Node污点失败: ", err)
+		return
+	}
+
+	// 3. 返回每个节点的结果
+	respondSuccess(c, http.StatusOK, results)
+}
+
+func respondNodeMetadataError(c *gin.Context, prefix string, err error) {
+	if _, ok := err.(*service.ValidationError); ok {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.IsNotFound(err) {
+		respondError(c, http.StatusNotFound, "Node不存在")
+		return
+	}
+	respondError(c, http.StatusInternalServerError, prefix+err.Error())
+}
@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/internal/service"
+	"github.com/ciliverse/cilikube/pkg/utils"
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// copyResult 拷贝 goroutine 的执行结果
+type copyResult struct {
+	bytes int64
+	err   error
+}
+
+// DownloadPodFile 通过 tar 从容器下载文件或目录
+// @Summary 从容器下载文件
+// @Description 默认返回 tar 归档；raw=true 时直接返回单个普通文件的内容
+// @Tags Pods
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Pod Name"
+// @Param container query string true "容器名称"
+// @Param path query string true "容器内的绝对路径"
+// @Param raw query bool false "是否直接返回文件内容 (仅限普通文件)"
+// @Param maxBytes query int false "最大传输字节数"
+// @Router /api/v1/namespaces/{namespace}/pods/{name}/files [get]
+func (h *PodHandler) DownloadPodFile(c *gin.Context) {
+	namespace := strings.TrimSpace(c.Param("namespace"))
+	name := strings.TrimSpace(c.Param("name"))
+	container := c.Query("container")
+	raw := c.Query("raw") == "true"
+
+	srcPath, ok := h.validateCopyRequest(c, namespace, name, container)
+	if !ok {
+		return
+	}
+	maxBytes, ok := parseCopyMaxBytes(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	resultCh := make(chan copyResult, 1)
+	go func() {
+		n, err := forUser(c, h.service).CopyFromPod(ctx, service.CopyOptions{
+			Namespace:     namespace,
+			PodName:       name,
+			ContainerName: container,
+			Path:          srcPath,
+			MaxBytes:      maxBytes,
+		}, pw)
+		pw.CloseWithError(err)
+		resultCh <- copyResult{bytes: n, err: err}
+	}()
+
+	// 在写出响应头之前先等待第一段数据，这样路径不存在等错误仍可以 JSON 形式返回
+	br := bufio.NewReader(pr)
+	if _, err := br.Peek(1); err != nil {
+		respondCopyError(c, "从容器下载文件失败", err)
+		return
+	}
+
+	base := path.Base(srcPath)
+	var written int64
+	var copyErr error
+	if raw {
+		tr := tar.NewReader(br)
+		hdr, err := tr.Next()
+		if err != nil {
+			respondCopyError(c, "解析容器返回的 tar 数据失败", err)
+			return
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("'%s' 不是普通文件，请去掉 raw 参数以 tar 归档形式下载", srcPath))
+			return
+		}
+		c.Header("Content-Type", "application/octet-stream")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", base))
+		c.Header("Content-Length", strconv.FormatInt(hdr.Size, 10))
+		c.Status(http.StatusOK)
+		written, copyErr = io.Copy(c.Writer, tr)
+	} else {
+		c.Header("Content-Type", "application/x-tar")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", base+".tar"))
+		c.Header("Trailer", "X-Transferred-Bytes")
+		c.Status(http.StatusOK)
+		written, copyErr = io.Copy(c.Writer, br)
+		c.Writer.Header().Set("X-Transferred-Bytes", strconv.FormatInt(written, 10))
+	}
+
+	// raw 模式只需要第一个文件，客户端断开时也不再需要后续数据：结束 exec 并关闭管道，避免写端阻塞
+	cancel()
+	_ = pr.Close()
+	result := <-resultCh
+	if copyErr != nil {
+		log.Printf("下载容器文件 %s/%s/%s:%s 中断: %v", namespace, name, container, srcPath, copyErr)
+		return
+	}
+	log.Printf("下载容器文件 %s/%s/%s:%s 完成，已传输 %d 字节 (tar 流 %d 字节)", namespace, name, container, srcPath, written, result.bytes)
+}
+
+// UploadPodFile 通过 tar 向容器上传文件
+// @Summary 向容器上传文件
+// @Description 支持 multipart/form-data (字段 file)、application/octet-stream (单个文件，需 Content-Length)
+// @Description 以及 application/x-tar (归档，path 为解包目录)。progress=true 时以 SSE 推送进度。
+// @Tags Pods
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Pod Name"
+// @Param container query string true "容器名称"
+// @Param path query string true "容器内的目标路径，以 / 结尾时视为目录"
+// @Param progress query bool false "是否以 SSE 推送上传进度"
+// @Param maxBytes query int false "最大传输字节数"
+// @Success 200 {object} models.PodFileCopyResponse
+// @Router /api/v1/namespaces/{namespace}/pods/{name}/files [post]
+func (h *PodHandler) UploadPodFile(c *gin.Context) {
+	namespace := strings.TrimSpace(c.Param("namespace"))
+	name := strings.TrimSpace(c.Param("name"))
+	container := c.Query("container")
+	streamProgress := c.Query("progress") == "true"
+
+	destPath, ok := h.validateCopyRequest(c, namespace, name, container)
+	if !ok {
+		return
+	}
+	maxBytes, ok := parseCopyMaxBytes(c)
+	if !ok {
+		return
+	}
+	// 预留 tar/multipart 头部的开销
+	if c.Request.ContentLength > maxBytes+1<<20 {
+		respondError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("上传内容超过大小限制 (%d 字节)", maxBytes))
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+1<<20)
+
+	var src io.Reader
+	var size int64 = -1
+	isArchive := false
+	contentType := c.ContentType()
+	switch {
+	case strings.Contains(contentType, "multipart/form-data"):
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "读取上传文件失败 (字段名应为 file): "+err.Error())
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "打开上传文件失败: "+err.Error())
+			return
+		}
+		defer func(f multipart.File) { _ = f.Close() }(file)
+		src, size = file, fileHeader.Size
+		if strings.HasSuffix(c.Query("path"), "/") {
+			destPath = path.Join(destPath, filepath.Base(fileHeader.Filename))
+		}
+	case strings.Contains(contentType, "x-tar"):
+		src, isArchive = c.Request.Body, true
+	case strings.Contains(contentType, "octet-stream"):
+		if c.Request.ContentLength < 0 {
+			respondError(c, http.StatusLengthRequired, "上传单个文件时必须提供 Content-Length")
+			return
+		}
+		if strings.HasSuffix(c.Query("path"), "/") {
+			respondError(c, http.StatusBadRequest, "application/octet-stream 上传时 path 必须是文件路径")
+			return
+		}
+		src, size = c.Request.Body, c.Request.ContentLength
+	default:
+		respondError(c, http.StatusUnsupportedMediaType, "不支持的 Content-Type，请使用 multipart/form-data、application/octet-stream 或 application/x-tar")
+		return
+	}
+
+	progressCh := make(chan int64, 16)
+	opts := service.CopyOptions{
+		Namespace:     namespace,
+		PodName:       name,
+		ContainerName: container,
+		Path:          destPath,
+		MaxBytes:      maxBytes,
+		Progress: func(n int64) {
+			select {
+			case progressCh <- n:
+			default: // 客户端消费不及时则丢弃中间进度
+			}
+		},
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	resultCh := make(chan copyResult, 1)
+	go func() {
+		n, err := forUser(c, h.service).CopyToPod(ctx, opts, src, isArchive, size)
+		resultCh <- copyResult{bytes: n, err: err}
+	}()
+
+	response := models.PodFileCopyResponse{
+		Namespace: namespace,
+		Pod:       name,
+		Container: container,
+		Path:      destPath,
+	}
+
+	if !streamProgress {
+		result := <-resultCh
+		if result.err != nil {
+			respondCopyError(c, "上传文件到容器失败", result.err)
+			return
+		}
+		response.Bytes = result.bytes
+		respondSuccess(c, http.StatusOK, response)
+		return
+	}
+
+	total := size
+	if total < 0 {
+		total = 0
+	}
+	setSSEHeaders(c)
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case n := <-progressCh:
+			c.SSEvent("progress", models.PodFileCopyProgress{Bytes: n, Total: total})
+			return true
+		case result := <-resultCh:
+			if result.err != nil {
+				c.SSEvent("error", gin.H{"message": "上传文件到容器失败: " + result.err.Error()})
+				return false
+			}
+			response.Bytes = result.bytes
+			c.SSEvent("complete", response)
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+// validateCopyRequest 校验拷贝请求的公共参数以及容器是否存在，返回规范化后的容器路径
+func (h *PodHandler) validateCopyRequest(c *gin.Context, namespace, name, container string) (string, bool) {
+	if !utils.ValidateNamespace(namespace) || !utils.ValidateResourceName(name) {
+		respondError(c, http.StatusBadRequest, "无效的命名空间或 Pod 名称格式")
+		return "", false
+	}
+	if container == "" {
+		respondError(c, http.StatusBadRequest, "必须提供 'container' 查询参数")
+		return "", false
+	}
+	cleanPath, err := service.ValidateContainerPath(c.Query("path"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return "", false
+	}
+
+	pod, err := forUser(c, h.service).Get(namespace, name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			respondError(c, http.StatusNotFound, "Pod 不存在")
+			return "", false
+		}
+		respondError(c, http.StatusInternalServerError, "获取 Pod 信息失败: "+err.Error())
+		return "", false
+	}
+	if !podHasContainer(pod, container) {
+		respondError(c, http.StatusNotFound, fmt.Sprintf("容器 '%s' 在 Pod '%s' 中未找到", container, name))
+		return "", false
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		respondError(c, http.StatusConflict, fmt.Sprintf("Pod 当前状态为 %s，只能对运行中的 Pod 拷贝文件", pod.Status.Phase))
+		return "", false
+	}
+	return cleanPath, true
+}
+
+// parseCopyMaxBytes 解析 maxBytes 查询参数，不允许超过服务端默认上限
+func parseCopyMaxBytes(c *gin.Context) (int64, bool) {
+	maxStr := c.Query("maxBytes")
+	if maxStr == "" {
+		return service.DefaultCopyMaxBytes, true
+	}
+	maxBytes, err := strconv.ParseInt(maxStr, 10, 64)
+	if err != nil || maxBytes <= 0 {
+		respondError(c, http.StatusBadRequest, "无效的 'maxBytes' 参数")
+		return 0, false
+	}
+	if maxBytes > service.DefaultCopyMaxBytes {
+		maxBytes = service.DefaultCopyMaxBytes
+	}
+	return maxBytes, true
+}
+
+// respondCopyError 将拷贝过程中的错误映射为对应的 HTTP 状态码
+func respondCopyError(c *gin.Context, prefix string, err error) {
+	var validationErr *service.ValidationError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &validationErr):
+		respondError(c, http.StatusBadRequest, validationErr.Error())
+	case errors.Is(err, service.ErrCopySizeExceeded), errors.As(err, &maxBytesErr):
+		respondError(c, http.StatusRequestEntityTooLarge, prefix+": "+service.ErrCopySizeExceeded.Error())
+	case errors.Is(err, io.EOF):
+		respondError(c, http.StatusNotFound, prefix+": 容器未返回任何数据，请确认路径存在")
+	default:
+		respondError(c, http.StatusInternalServerError, prefix+": "+err.Error())
+	}
+}
+
+// podHasContainer 判断 Pod 中是否存在指定名称的容器 (包括 init 和 ephemeral 容器)
+func podHasContainer(pod *corev1.Pod, container string) bool {
+	for _, cont := range append(pod.Spec.Containers, pod.Spec.InitContainers...) {
+		if cont.Name == container {
+			return true
+		}
+	}
+	for _, ec := range pod.Spec.EphemeralContainers {
+		if ec.Name == container {
+			return true
+		}
+	}
+	return false
+}
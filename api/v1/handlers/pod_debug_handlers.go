@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/internal/service"
+	"github.com/ciliverse/cilikube/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// CreateDebugContainer 向 Pod 添加临时调试容器，等待其启动后返回 exec WebSocket 地址
+// @Summary 创建临时调试容器
+// @Description 通过 ephemeralcontainers 子资源添加调试容器，适用于没有 shell 的镜像 (如 distroless)
+// @Tags Pods
+// @Accept json
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Pod Name"
+// @Param debug body models.CreateDebugContainerRequest true "调试容器配置"
+// @Success 201 {object} models.DebugContainerResponse
+// @Router /api/v1/namespaces/{namespace}/pods/{name}/debug [post]
+func (h *PodHandler) CreateDebugContainer(c *gin.Context) {
+	namespace := strings.TrimSpace(c.Param("namespace"))
+	name := strings.TrimSpace(c.Param("name"))
+	if !utils.ValidateNamespace(namespace) || !utils.ValidateResourceName(name) {
+		respondError(c, http.StatusBadRequest, "无效的命名空间或 Pod 名称格式")
+		return
+	}
+
+	var req models.CreateDebugContainerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的 JSON 请求格式: "+err.Error())
+		return
+	}
+	if req.Name != "" && !utils.ValidateResourceName(req.Name) {
+		respondError(c, http.StatusBadRequest, "无效的调试容器名称格式")
+		return
+	}
+	if req.TimeoutSeconds < 0 {
+		respondError(c, http.StatusBadRequest, "无效的 'timeoutSeconds' 参数")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	container, err := forUser(c, h.service).CreateDebugContainer(ctx, namespace, name, service.DebugContainerOptions{
+		Name:            req.Name,
+		Image:           req.Image,
+		TargetContainer: req.TargetContainer,
+		Command:         req.Command,
+		Timeout:         time.Duration(req.TimeoutSeconds) * time.Second,
+	})
+	if err != nil {
+		if e, ok := err.(*service.ValidationError); ok {
+			respondError(c, http.StatusBadRequest, e.Error())
+			return
+		}
+		if errors.IsNotFound(err) {
+			respondError(c, http.StatusNotFound, "Pod 不存在")
+			return
+		}
+		if errors.IsForbidden(err) {
+			respondError(c, http.StatusForbidden, "没有权限添加临时容器: "+err.Error())
+			return
+		}
+		if errors.IsInvalid(err) || errors.IsBadRequest(err) {
+			respondError(c, http.StatusBadRequest, "添加临时容器失败: "+err.Error())
+			return
+		}
+		if container != "" {
+			respondError(c, http.StatusInternalServerError, fmt.Sprintf("临时容器 '%s' 已添加但未能启动: %v", container, err))
+			return
+		}
+		respondError(c, http.StatusInternalServerError, "添加临时容器失败: "+err.Error())
+		return
+	}
+
+	shell := req.Shell
+	if shell == "" {
+		shell = "sh"
+	}
+	respondSuccess(c, http.StatusCreated, models.DebugContainerResponse{
+		Namespace:       namespace,
+		Pod:             name,
+		Container:       container,
+		Image:           req.Image,
+		TargetContainer: req.TargetContainer,
+		ExecPath:        buildExecPath(namespace, name, container, shell),
+	})
+}
+
+// buildExecPath 构建连接到指定容器的 exec WebSocket 地址
+func buildExecPath(namespace, pod, container, command string) string {
+	query := url.Values{}
+	query.Set("container", container)
+	query.Set("command", command)
+	query.Set("tty", strconv.FormatBool(true))
+	return fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/exec?%s", namespace, pod, query.Encode())
+}
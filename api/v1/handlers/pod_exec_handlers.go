@@ -114,7 +114,7 @@ func (h *PodHandler) ExecIntoPod(c *gin.Context) {
 	go func() {
 		defer close(execDone)
 		log.Printf("Executing command: %v in %s/%s/%s", command, namespace, name, container)
-		execErr = h.service.ExecIntoPod(ctx, execOptions)
+		execErr = forUser(c, h.service).ExecIntoPod(ctx, execOptions)
 		if execErr != nil {
 			errMsg := []byte(fmt.Sprintf("\r\n--- Command Execution Failed ---\r\nError: %v\r\n", execErr))
 			if err := wsStreamHandler.WriteMessage(websocket.TextMessage, errMsg); err != nil {
@@ -28,7 +28,7 @@ func NewPodHandler(svc *service.PodService) *PodHandler {
 
 // ListNamespaces ... (保持不变)
 func (h *PodHandler) ListNamespaces(c *gin.Context) {
-	namespaces, err := h.service.ListNamespaces()
+	namespaces, err := forUser(c, h.service).ListNamespaces()
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取命名空间失败: "+err.Error())
 		return
@@ -51,7 +51,7 @@ func (h *PodHandler) GetPod(c *gin.Context) {
 		return
 	}
 
-	pod, err := h.service.Get(namespace, name)
+	pod, err := forUser(c, h.service).Get(namespace, name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "Pod不存在")
@@ -86,7 +86,7 @@ func (h *PodHandler) CreatePod(c *gin.Context) {
 			respondError(c, http.StatusBadRequest, "请求体不能为空 (YAML)")
 			return
 		}
-		createdPod, err = h.service.CreateFromYAML(namespace, yamlBody)
+		createdPod, err = forUser(c, h.service).CreateFromYAML(namespace, yamlBody)
 
 	} else if strings.Contains(contentType, "json") { // Explicitly check for JSON
 		var req models.CreatePodRequest
@@ -110,7 +110,7 @@ func (h *PodHandler) CreatePod(c *gin.Context) {
 			Spec: req.Spec,
 		}
 		// Use the original service.Create method for JSON objects
-		createdPod, err = h.service.Create(namespace, pod)
+		createdPod, err = forUser(c, h.service).Create(namespace, pod)
 	} else {
 		respondError(c, http.StatusUnsupportedMediaType, "不支持的 Content-Type，请使用 application/json 或 application/yaml")
 		return
@@ -157,12 +157,12 @@ func (h *PodHandler) UpdatePod(c *gin.Context) {
 			respondError(c, http.StatusBadRequest, "请求体不能为空 (YAML)")
 			return
 		}
-		result, err = h.service.UpdateFromYAML(namespace, name, yamlBody)
+		result, err = forUser(c, h.service).UpdateFromYAML(namespace, name, yamlBody)
 
 	} else if strings.Contains(contentType, "json") { // Explicitly check for JSON
 		// --- Handle JSON Input ---
 		// Get the existing Pod first to apply changes correctly
-		existingPod, errGet := h.service.Get(namespace, name)
+		existingPod, errGet := forUser(c, h.service).Get(namespace, name)
 		if errGet != nil {
 			if errors.IsNotFound(errGet) {
 				respondError(c, http.StatusNotFound, "Pod不存在，无法更新")
@@ -186,7 +186,7 @@ func (h *PodHandler) UpdatePod(c *gin.Context) {
 		updatedPod.Spec = req.Spec               // Replace the entire spec
 
 		// *** Call the correct Update method in the service ***
-		result, err = h.service.Update(namespace, updatedPod) // Use the method taking a Pod object
+		result, err = forUser(c, h.service).Update(namespace, updatedPod) // Use the method taking a Pod object
 
 	} else {
 		respondError(c, http.StatusUnsupportedMediaType, "不支持的 Content-Type，请使用 application/json 或 application/yaml")
@@ -224,7 +224,7 @@ func (h *PodHandler) DeletePod(c *gin.Context) {
 		return
 	}
 
-	err := h.service.Delete(namespace, name)
+	err := forUser(c, h.service).Delete(namespace, name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Idempotent: Return success even if not found
@@ -253,7 +253,7 @@ func (h *PodHandler) ListPods(c *gin.Context) {
 		limit = 500 // Fallback
 	}
 
-	pods, err := h.service.List(namespace, labelSelector, limit)
+	pods, err := forUser(c, h.service).List(namespace, labelSelector, limit)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取Pod列表失败: "+err.Error())
 		return
@@ -286,7 +286,7 @@ func (h *PodHandler) WatchPods(c *gin.Context) {
 	}
 	labelSelector := c.Query("labelSelector")
 
-	watcher, err := h.service.Watch(namespace, labelSelector)
+	watcher, err := forUser(c, h.service).Watch(namespace, labelSelector)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "开始监听Pod失败: "+err.Error())
 		return
@@ -336,7 +336,7 @@ func (h *PodHandler) GetPodYAML(c *gin.Context) {
 		return
 	}
 
-	yamlBytes, err := h.service.GetPodYAML(namespace, name)
+	yamlBytes, err := forUser(c, h.service).GetPodYAML(namespace, name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "Pod 不存在")
@@ -376,7 +376,7 @@ func (h *PodHandler) UpdatePodYAML(c *gin.Context) {
 		return
 	}
 
-	updatedPod, err := h.service.UpdateFromYAML(namespace, name, yamlBody)
+	updatedPod, err := forUser(c, h.service).UpdateFromYAML(namespace, name, yamlBody)
 	if err != nil {
 		if e, ok := err.(*service.ValidationError); ok {
 			respondError(c, http.StatusBadRequest, e.Error())
@@ -33,7 +33,7 @@ func (h *PodHandler) GetPodLogs(c *gin.Context) {
 	}
 
 	// Optional: Check container exists
-	pod, err := h.service.Get(namespace, name)
+	pod, err := forUser(c, h.service).Get(namespace, name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "Pod 不存在")
@@ -67,7 +67,7 @@ func (h *PodHandler) GetPodLogs(c *gin.Context) {
 	}
 
 	// 获取日志流
-	logStream, err := h.service.GetPodLogs(namespace, name, logOptions)
+	logStream, err := forUser(c, h.service).GetPodLogs(namespace, name, logOptions)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取日志失败: "+err.Error())
 		return
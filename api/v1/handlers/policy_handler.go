@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// PolicyHandler 管理 Casbin 策略、自定义角色和角色分配
+type PolicyHandler struct {
+	service *service.PolicyService
+}
+
+func NewPolicyHandler(svc *service.PolicyService) *PolicyHandler {
+	return &PolicyHandler{service: svc}
+}
+
+// ListPolicies 列出策略和角色继承关系，可通过 ?role= 过滤
+func (h *PolicyHandler) ListPolicies(c *gin.Context) {
+	resp, err := h.service.ListPolicies(strings.TrimSpace(c.Query("role")))
+	if err != nil {
+		respondPolicyError(c, "获取策略列表失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, resp)
+}
+
+// UpdatePolicies 批量添加和删除策略及角色继承关系
+func (h *PolicyHandler) UpdatePolicies(c *gin.Context) {
+	var req models.PolicyChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	if err := h.service.ApplyChanges(&req); err != nil {
+		respondPolicyError(c, "更新策略失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, gin.H{"message": "策略已更新"})
+}
+
+// PreviewPolicies 预览策略变更后角色或用户新增和失去的路由，不会保存变更
+func (h *PolicyHandler) PreviewPolicies(c *gin.Context) {
+	var req models.PolicyPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	resp, err := h.service.PreviewChanges(&req)
+	if err != nil {
+		respondPolicyError(c, "预览策略变更失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, resp)
+}
+
+// ListRoleTemplates 列出预置的权限模板
+func (h *PolicyHandler) ListRoleTemplates(c *gin.Context) {
+	respondSuccess(c, http.StatusOK, h.service.ListRoleTemplates())
+}
+
+// ListRoles 列出角色及其策略和成员
+func (h *PolicyHandler) ListRoles(c *gin.Context) {
+	roles, err := h.service.ListRoles()
+	if err != nil {
+		respondPolicyError(c, "获取角色列表失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, roles)
+}
+
+// CreateRole 按权限模板和规则创建自定义角色
+func (h *PolicyHandler) CreateRole(c *gin.Context) {
+	var req models.CreateCasbinRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	role, err := h.service.CreateRole(&req)
+	if err != nil {
+		respondPolicyError(c, "创建角色失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusCreated, role)
+}
+
+// DeleteRole 删除自定义角色
+func (h *PolicyHandler) DeleteRole(c *gin.Context) {
+	if err := h.service.DeleteRole(strings.TrimSpace(c.Param("role"))); err != nil {
+		respondPolicyError(c, "删除角色失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, gin.H{"message": "角色已删除"})
+}
+
+// AssignRole 在指定集群/命名空间中为用户分配角色
+func (h *PolicyHandler) AssignRole(c *gin.Context) {
+	var req models.RoleAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	grouping, err := h.service.AssignRole(&req)
+	if err != nil {
+		respondPolicyError(c, "分配角色失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusCreated, grouping)
+}
+
+// UnassignRole 撤销用户的角色分配，参数通过查询字符串传递
+func (h *PolicyHandler) UnassignRole(c *gin.Context) {
+	var req models.RoleAssignmentRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "参数错误: "+err.Error())
+		return
+	}
+	if err := h.service.UnassignRole(&req); err != nil {
+		respondPolicyError(c, "撤销角色失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, gin.H{"message": "角色分配已撤销"})
+}
+
+func respondPolicyError(c *gin.Context, message string, err error) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		respondError(c, http.StatusBadRequest, validationErr.Error())
+	case errors.Is(err, service.ErrCasbinRoleNotFound), errors.Is(err, service.ErrRoleAssignmentNotFound):
+		respondError(c, http.StatusNotFound, err.Error())
+	default:
+		respondError(c, http.StatusInternalServerError, message+": "+err.Error())
+	}
+}
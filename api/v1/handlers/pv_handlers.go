@@ -99,7 +99,7 @@ func (h *PVHandler) ListPVs(c *gin.Context) {
 	// Frontend pagination will handle displaying pageSize items from this list.
 	limit := utils.ParseInt(c.DefaultQuery("limit", "500"), 500)
 
-	pvList, err := h.service.List(labelSelector, int64(limit))
+	pvList, err := forUser(c, h.service).List(labelSelector, int64(limit))
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取PV列表失败: "+err.Error())
 		return
@@ -139,7 +139,7 @@ func (h *PVHandler) GetPV(c *gin.Context) {
 		return
 	}
 
-	pv, err := h.service.Get(name)
+	pv, err := forUser(c, h.service).Get(name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "PV不存在")
@@ -181,7 +181,7 @@ func (h *PVHandler) CreatePV(c *gin.Context) {
 		pv.APIVersion = "v1"
 	} // Default if missing
 
-	createdPV, err := h.service.Create(&pv)
+	createdPV, err := forUser(c, h.service).Create(&pv)
 	if err != nil {
 		if errors.IsAlreadyExists(err) {
 			respondError(c, http.StatusConflict, "PV已存在")
@@ -239,7 +239,7 @@ func (h *PVHandler) UpdatePV(c *gin.Context) {
 		pv.APIVersion = "v1"
 	}
 
-	updatedPV, err := h.service.Update(&pv) // Service needs to handle potential conflicts
+	updatedPV, err := forUser(c, h.service).Update(&pv) // Service needs to handle potential conflicts
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "PV不存在")
@@ -278,7 +278,7 @@ func (h *PVHandler) DeletePV(c *gin.Context) {
 		return
 	}
 
-	err := h.service.Delete(name)
+	err := forUser(c, h.service).Delete(name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Consider returning 204 even if not found, idempotent delete
@@ -254,7 +254,7 @@ func (h *PVCHandler) ListPVCs(c *gin.Context) {
 	labelSelector := c.Query("labelSelector")
 	limit := utils.ParseInt(c.DefaultQuery("limit", "100"), 100)
 
-	pvcList, err := h.service.List(namespace, labelSelector, int64(limit))
+	pvcList, err := forUser(c, h.service).List(namespace, labelSelector, int64(limit))
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取PVC列表失败: "+err.Error())
 		return
@@ -293,7 +293,7 @@ func (h *PVCHandler) GetPVC(c *gin.Context) {
 		return
 	}
 
-	pvc, err := h.service.Get(namespace, name)
+	pvc, err := forUser(c, h.service).Get(namespace, name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "PVC不存在")
@@ -341,7 +341,7 @@ func (h *PVCHandler) CreatePVC(c *gin.Context) {
 	}
 
 	// Let service handle namespace assignment/validation based on path param
-	createdPVC, err := h.service.Create(namespace, &pvc)
+	createdPVC, err := forUser(c, h.service).Create(namespace, &pvc)
 	if err != nil {
 		if errors.IsAlreadyExists(err) {
 			respondError(c, http.StatusConflict, "PVC已存在")
@@ -402,7 +402,7 @@ func (h *PVCHandler) UpdatePVC(c *gin.Context) {
 	}
 
 	// Service Update handles the actual call, API server enforces immutability
-	updatedPVC, err := h.service.Update(namespace, &pvc)
+	updatedPVC, err := forUser(c, h.service).Update(namespace, &pvc)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "PVC不存在")
@@ -444,7 +444,7 @@ func (h *PVCHandler) DeletePVC(c *gin.Context) {
 		return
 	}
 
-	err := h.service.Delete(namespace, name)
+	err := forUser(c, h.service).Delete(namespace, name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// respondError(c, http.StatusNotFound, "PVC不存在")
@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
 	"github.com/ciliverse/cilikube/internal/service"
 	"github.com/ciliverse/cilikube/pkg/utils"
 	"github.com/gin-gonic/gin"
-	"net/http"
-	"strings"
+	"k8s.io/apimachinery/pkg/api/errors"
 )
 
 type RbacHandler struct {
@@ -21,7 +25,7 @@ func (h *RbacHandler) ListRoles(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "无效的命名空间格式")
 		return
 	}
-	roles, err := h.service.ListRoles(namespace)
+	roles, err := forUser(c, h.service).ListRoles(namespace)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取Role列表失败: "+err.Error())
 		return
@@ -40,7 +44,7 @@ func (h *RbacHandler) GetRole(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "无效的资源名称格式")
 		return
 	}
-	role, err := h.service.GetRole(namespace, name)
+	role, err := forUser(c, h.service).GetRole(namespace, name)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取Role失败: "+err.Error())
 		return
@@ -55,7 +59,7 @@ func (h *RbacHandler) ListRoleBindings(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "无效的命名空间格式")
 		return
 	}
-	roleBindings, err := h.service.ListRoleBindings(namespace)
+	roleBindings, err := forUser(c, h.service).ListRoleBindings(namespace)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取RoleBinding列表失败: "+err.Error())
 		return
@@ -74,7 +78,7 @@ func (h *RbacHandler) GetRoleBindings(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "无效的资源名称格式")
 		return
 	}
-	roleBinding, err := h.service.GetRoleBinding(namespace, name)
+	roleBinding, err := forUser(c, h.service).GetRoleBinding(namespace, name)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取RoleBinding失败: "+err.Error())
 		return
@@ -84,7 +88,7 @@ func (h *RbacHandler) GetRoleBindings(c *gin.Context) {
 
 // ClusterRoles
 func (h *RbacHandler) ListClusterRoles(c *gin.Context) {
-	clusterRoles, err := h.service.ListClusterRoles()
+	clusterRoles, err := forUser(c, h.service).ListClusterRoles()
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取ClusterRole列表失败: "+err.Error())
 		return
@@ -98,7 +102,7 @@ func (h *RbacHandler) GetClusterRoles(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "无效的资源名称格式")
 		return
 	}
-	clusterRole, err := h.service.GetClusterRole(name)
+	clusterRole, err := forUser(c, h.service).GetClusterRole(name)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取ClusterRole失败: "+err.Error())
 		return
@@ -108,7 +112,7 @@ func (h *RbacHandler) GetClusterRoles(c *gin.Context) {
 
 // ClusterRoleBindings
 func (h *RbacHandler) ListClusterRoleBindings(c *gin.Context) {
-	clusterRoleBindings, err := h.service.ListClusterRoleBindings()
+	clusterRoleBindings, err := forUser(c, h.service).ListClusterRoleBindings()
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取ClusterRoleBinding列表失败: "+err.Error())
 		return
@@ -122,7 +126,7 @@ func (h *RbacHandler) GetClusterRoleBindings(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "无效的资源名称格式")
 		return
 	}
-	clusterRoleBinding, err := h.service.GetClusterRoleBinding(name)
+	clusterRoleBinding, err := forUser(c, h.service).GetClusterRoleBinding(name)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取ClusterRoleBinding失败: "+err.Error())
 		return
@@ -137,7 +141,7 @@ func (h *RbacHandler) ListServiceAccounts(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "无效的命名空间格式")
 		return
 	}
-	serviceAccounts, err := h.service.ListServiceAccounts(namespace)
+	serviceAccounts, err := forUser(c, h.service).ListServiceAccounts(namespace)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取ServiceAccount列表失败: "+err.Error())
 		return
@@ -156,10 +160,383 @@ func (h *RbacHandler) GetServiceAccounts(c *gin.Context) {
 		respondError(c, http.StatusBadRequest, "无效的资源名称格式")
 		return
 	}
-	serviceAccount, err := h.service.GetServiceAccounts(namespace, name)
+	serviceAccount, err := forUser(c, h.service).GetServiceAccounts(namespace, name)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取ServiceAccount失败: "+err.Error())
 		return
 	}
 	respondSuccess(c, http.StatusOK, serviceAccount)
 }
+
+// respondRbacError 将服务层错误映射为合适的 HTTP 状态码，kind 用于 404/409 提示，message 为通用失败提示
+func respondRbacError(c *gin.Context, kind, message string, err error) {
+	if _, ok := err.(*service.ValidationError); ok {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	switch {
+	case errors.IsNotFound(err):
+		respondError(c, http.StatusNotFound, kind+"不存在")
+	case errors.IsAlreadyExists(err):
+		respondError(c, http.StatusConflict, kind+"已存在")
+	case errors.IsConflict(err):
+		respondError(c, http.StatusConflict, kind+"已被修改，请刷新后重试: "+err.Error())
+	case errors.IsInvalid(err), errors.IsBadRequest(err):
+		respondError(c, http.StatusBadRequest, message+": "+err.Error())
+	case errors.IsForbidden(err):
+		respondError(c, http.StatusForbidden, message+": "+err.Error())
+	default:
+		respondError(c, http.StatusInternalServerError, message+": "+err.Error())
+	}
+}
+
+// namespacedParams 读取并校验路径中的命名空间和名称，withName 为 false 时只校验命名空间
+func namespacedParams(c *gin.Context, withName bool) (string, string, bool) {
+	namespace := strings.TrimSpace(c.Param("namespace"))
+	name := strings.TrimSpace(c.Param("name"))
+	if !utils.ValidateNamespace(namespace) {
+		respondError(c, http.StatusBadRequest, "无效的命名空间格式")
+		return "", "", false
+	}
+	if withName && !utils.ValidateResourceName(name) {
+		respondError(c, http.StatusBadRequest, "无效的资源名称格式")
+		return "", "", false
+	}
+	return namespace, name, true
+}
+
+// clusterNameParam 读取并校验集群级资源的名称
+func clusterNameParam(c *gin.Context) (string, bool) {
+	name := strings.TrimSpace(c.Param("name"))
+	if !utils.ValidateResourceName(name) {
+		respondError(c, http.StatusBadRequest, "无效的资源名称格式")
+		return "", false
+	}
+	return name, true
+}
+
+func (h *RbacHandler) CreateRole(c *gin.Context) {
+	namespace, _, ok := namespacedParams(c, false)
+	if !ok {
+		return
+	}
+	var req models.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的Role格式: "+err.Error())
+		return
+	}
+	role, err := forUser(c, h.service).CreateRole(namespace, &req)
+	if err != nil {
+		respondRbacError(c, "Role", "创建Role失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusCreated, role)
+}
+
+func (h *RbacHandler) UpdateRole(c *gin.Context) {
+	namespace, name, ok := namespacedParams(c, true)
+	if !ok {
+		return
+	}
+	var req models.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的Role格式: "+err.Error())
+		return
+	}
+	role, err := forUser(c, h.service).UpdateRole(namespace, name, &req)
+	if err != nil {
+		respondRbacError(c, "Role", "更新Role失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, role)
+}
+
+func (h *RbacHandler) DeleteRole(c *gin.Context) {
+	namespace, name, ok := namespacedParams(c, true)
+	if !ok {
+		return
+	}
+	if err := forUser(c, h.service).DeleteRole(namespace, name); err != nil {
+		respondRbacError(c, "Role", "删除Role失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+func (h *RbacHandler) CreateRoleBinding(c *gin.Context) {
+	namespace, _, ok := namespacedParams(c, false)
+	if !ok {
+		return
+	}
+	var req models.CreateRoleBindingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的RoleBinding格式: "+err.Error())
+		return
+	}
+	roleBinding, err := forUser(c, h.service).CreateRoleBinding(namespace, &req)
+	if err != nil {
+		respondRbacError(c, "RoleBinding", "创建RoleBinding失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusCreated, roleBinding)
+}
+
+func (h *RbacHandler) UpdateRoleBinding(c *gin.Context) {
+	namespace, name, ok := namespacedParams(c, true)
+	if !ok {
+		return
+	}
+	var req models.UpdateRoleBindingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的RoleBinding格式: "+err.Error())
+		return
+	}
+	roleBinding, err := forUser(c, h.service).UpdateRoleBinding(namespace, name, &req)
+	if err != nil {
+		respondRbacError(c, "RoleBinding", "更新RoleBinding失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, roleBinding)
+}
+
+func (h *RbacHandler) DeleteRoleBinding(c *gin.Context) {
+	namespace, name, ok := namespacedParams(c, true)
+	if !ok {
+		return
+	}
+	if err := forUser(c, h.service).DeleteRoleBinding(namespace, name); err != nil {
+		respondRbacError(c, "RoleBinding", "删除RoleBinding失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+func (h *RbacHandler) CreateClusterRole(c *gin.Context) {
+	var req models.CreateClusterRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的ClusterRole格式: "+err.Error())
+		return
+	}
+	clusterRole, err := forUser(c, h.service).CreateClusterRole(&req)
+	if err != nil {
+		respondRbacError(c, "ClusterRole", "创建ClusterRole失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusCreated, clusterRole)
+}
+
+func (h *RbacHandler) UpdateClusterRole(c *gin.Context) {
+	name, ok := clusterNameParam(c)
+	if !ok {
+		return
+	}
+	var req models.UpdateClusterRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的ClusterRole格式: "+err.Error())
+		return
+	}
+	clusterRole, err := forUser(c, h.service).UpdateClusterRole(name, &req)
+	if err != nil {
+		respondRbacError(c, "ClusterRole", "更新ClusterRole失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, clusterRole)
+}
+
+func (h *RbacHandler) DeleteClusterRole(c *gin.Context) {
+	name, ok := clusterNameParam(c)
+	if !ok {
+		return
+	}
+	if err := forUser(c, h.service).DeleteClusterRole(name); err != nil {
+		respondRbacError(c, "ClusterRole", "删除ClusterRole失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+func (h *RbacHandler) CreateClusterRoleBinding(c *gin.Context) {
+	var req models.CreateClusterRoleBindingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的ClusterRoleBinding格式: "+err.Error())
+		return
+	}
+	clusterRoleBinding, err := forUser(c, h.service).CreateClusterRoleBinding(&req)
+	if err != nil {
+		respondRbacError(c, "ClusterRoleBinding", "创建ClusterRoleBinding失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusCreated, clusterRoleBinding)
+}
+
+func (h *RbacHandler) UpdateClusterRoleBinding(c *gin.Context) {
+	name, ok := clusterNameParam(c)
+	if !ok {
+		return
+	}
+	var req models.UpdateClusterRoleBindingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的ClusterRoleBinding格式: "+err.Error())
+		return
+	}
+	clusterRoleBinding, err := forUser(c, h.service).UpdateClusterRoleBinding(name, &req)
+	if err != nil {
+		respondRbacError(c, "ClusterRoleBinding", "更新ClusterRoleBinding失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, clusterRoleBinding)
+}
+
+func (h *RbacHandler) DeleteClusterRoleBinding(c *gin.Context) {
+	name, ok := clusterNameParam(c)
+	if !ok {
+		return
+	}
+	if err := forUser(c, h.service).DeleteClusterRoleBinding(name); err != nil {
+		respondRbacError(c, "ClusterRoleBinding", "删除ClusterRoleBinding失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+func (h *RbacHandler) CreateServiceAccount(c *gin.Context) {
+	namespace, _, ok := namespacedParams(c, false)
+	if !ok {
+		return
+	}
+	var req models.CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的ServiceAccount格式: "+err.Error())
+		return
+	}
+	serviceAccount, err := forUser(c, h.service).CreateServiceAccount(namespace, &req)
+	if err != nil {
+		respondRbacError(c, "ServiceAccount", "创建ServiceAccount失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusCreated, serviceAccount)
+}
+
+func (h *RbacHandler) UpdateServiceAccount(c *gin.Context) {
+	namespace, name, ok := namespacedParams(c, true)
+	if !ok {
+		return
+	}
+	var req models.UpdateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的ServiceAccount格式: "+err.Error())
+		return
+	}
+	serviceAccount, err := forUser(c, h.service).UpdateServiceAccount(namespace, name, &req)
+	if err != nil {
+		respondRbacError(c, "ServiceAccount", "更新ServiceAccount失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, serviceAccount)
+}
+
+func (h *RbacHandler) DeleteServiceAccount(c *gin.Context) {
+	namespace, name, ok := namespacedParams(c, true)
+	if !ok {
+		return
+	}
+	if err := forUser(c, h.service).DeleteServiceAccount(namespace, name); err != nil {
+		respondRbacError(c, "ServiceAccount", "删除ServiceAccount失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// ReviewAccess 检查用户、组或 ServiceAccount 能否执行某个操作 (can-i)
+func (h *RbacHandler) ReviewAccess(c *gin.Context) {
+	var req models.AccessReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的访问审查参数: "+err.Error())
+		return
+	}
+	result, err := forUser(c, h.service).ReviewAccess(&req)
+	if err != nil {
+		respondRbacError(c, "访问审查", "执行访问审查失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, result)
+}
+
+// ReviewRules 列出用户或 ServiceAccount 在命名空间中的全部权限
+func (h *RbacHandler) ReviewRules(c *gin.Context) {
+	var req models.RulesReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的权限审查参数: "+err.Error())
+		return
+	}
+	if !utils.ValidateNamespace(req.Namespace) {
+		respondError(c, http.StatusBadRequest, "无效的命名空间格式")
+		return
+	}
+	result, err := forUser(c, h.service).ReviewRules(&req)
+	if err != nil {
+		respondRbacError(c, "权限审查", "执行权限审查失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, result)
+}
+
+// GenerateServiceAccountKubeconfig 为 ServiceAccount 签发令牌并返回 kubeconfig，download=true 时以文件形式下载
+func (h *RbacHandler) GenerateServiceAccountKubeconfig(c *gin.Context) {
+	namespace, name, ok := namespacedParams(c, true)
+	if !ok {
+		return
+	}
+	var req models.ServiceAccountKubeconfigRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "无效的令牌参数: "+err.Error())
+			return
+		}
+	}
+	result, err := forUser(c, h.service).GenerateServiceAccountKubeconfig(namespace, name, &req)
+	if err != nil {
+		respondRbacError(c, "ServiceAccount", "生成ServiceAccount kubeconfig失败", err)
+		return
+	}
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%s.kubeconfig", namespace, name)))
+		c.Data(http.StatusOK, "application/yaml", []byte(result.Kubeconfig))
+		return
+	}
+	respondSuccess(c, http.StatusOK, result)
+}
+
+// GetPermissionMatrix 汇总用户、组或 ServiceAccount 通过所有绑定获得的权限矩阵
+func (h *RbacHandler) GetPermissionMatrix(c *gin.Context) {
+	var req models.PermissionSubject
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的查询参数: "+err.Error())
+		return
+	}
+	result, err := forUser(c, h.service).GetPermissionMatrix(&req)
+	if err != nil {
+		respondRbacError(c, "权限矩阵", "获取权限矩阵失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, result)
+}
+
+// WhoCan 反向查询能对资源执行指定动作的主体
+func (h *RbacHandler) WhoCan(c *gin.Context) {
+	var req models.WhoCanRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的查询参数: "+err.Error())
+		return
+	}
+	if req.Namespace != "" && !utils.ValidateNamespace(req.Namespace) {
+		respondError(c, http.StatusBadRequest, "无效的命名空间格式")
+		return
+	}
+	result, err := forUser(c, h.service).WhoCan(&req)
+	if err != nil {
+		respondRbacError(c, "权限", "反向查询权限失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, result)
+}
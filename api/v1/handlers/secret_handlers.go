@@ -1,15 +1,21 @@
 package handlers
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/ciliverse/cilikube/api/v1/models" // Adjust path
 	"github.com/ciliverse/cilikube/internal/service"
 	"github.com/ciliverse/cilikube/pkg/utils"
 	"github.com/gin-gonic/gin"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 type SecretHandler struct {
@@ -43,7 +49,7 @@ func (h *SecretHandler) ListSecrets(c *gin.Context) {
 	labelSelector := c.Query("labelSelector")
 	limit := utils.ParseInt(c.DefaultQuery("limit", "100"), 100)
 
-	secretList, err := h.service.List(namespace, labelSelector, int64(limit))
+	secretList, err := forUser(c, h.service).List(namespace, labelSelector, int64(limit))
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取Secret列表失败: "+err.Error())
 		return
@@ -81,9 +87,9 @@ func (h *SecretHandler) GetSecret(c *gin.Context) {
 		return
 	}
 
-	secret, err := h.service.Get(namespace, name)
+	secret, err := forUser(c, h.service).Get(namespace, name)
 	if err != nil {
-		if errors.IsNotFound(err) {
+		if k8serrors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "Secret不存在")
 			return
 		}
@@ -133,9 +139,9 @@ func (h *SecretHandler) CreateSecret(c *gin.Context) {
 	// If not, you might need manual decoding here based on how the frontend sends it.
 	// However, K8s usually handles encoding StringData into Data automatically. Prefer using StringData for text.
 
-	createdSecret, err := h.service.Create(namespace, &secret)
+	createdSecret, err := forUser(c, h.service).Create(namespace, &secret)
 	if err != nil {
-		if errors.IsAlreadyExists(err) {
+		if k8serrors.IsAlreadyExists(err) {
 			respondError(c, http.StatusConflict, "Secret已存在")
 			return
 		}
@@ -189,13 +195,13 @@ func (h *SecretHandler) UpdateSecret(c *gin.Context) {
 		secret.APIVersion = "v1"
 	}
 
-	updatedSecret, err := h.service.Update(namespace, &secret)
+	updatedSecret, err := forUser(c, h.service).Update(namespace, &secret)
 	if err != nil {
-		if errors.IsNotFound(err) {
+		if k8serrors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "Secret不存在")
 			return
 		}
-		if errors.IsConflict(err) {
+		if k8serrors.IsConflict(err) {
 			respondError(c, http.StatusConflict, "资源已被修改，请获取最新版本后重试")
 			return
 		}
@@ -230,9 +236,9 @@ func (h *SecretHandler) DeleteSecret(c *gin.Context) {
 		return
 	}
 
-	err := h.service.Delete(namespace, name)
+	err := forUser(c, h.service).Delete(namespace, name)
 	if err != nil {
-		if errors.IsNotFound(err) {
+		if k8serrors.IsNotFound(err) {
 			c.Status(http.StatusNoContent)
 			return
 		} // Idempotent
@@ -242,6 +248,335 @@ func (h *SecretHandler) DeleteSecret(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// GetSecretYAML godoc
+// @Summary Get a Secret as YAML
+// @Description 返回 Secret 的 YAML，data 中的值以占位符遮盖
+// @Tags Secrets
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Secret Name"
+// @Success 200 {string} string "YAML 文本"
+// @Failure 404 {object} handlers.ErrorResponse "Not Found"
+// @Router /api/v1/namespaces/{namespace}/secrets/{name}/yaml [get]
+func (h *SecretHandler) GetSecretYAML(c *gin.Context) {
+	namespace, name, ok := secretPathParams(c)
+	if !ok {
+		return
+	}
+	yamlBytes, err := forUser(c, h.service).GetYAML(namespace, name)
+	if err != nil {
+		respondSecretKeyError(c, "获取Secret YAML失败", err)
+		return
+	}
+	c.Header("Content-Type", "application/yaml")
+	respondSuccess(c, http.StatusOK, string(yamlBytes))
+}
+
+// RevealSecretKey godoc
+// @Summary Reveal a Secret value
+// @Description 解码并返回单个键的值，需要 secrets/keys/reveal 资源的 reveal 权限，每次调用都会记录审计事件。
+// @Description 合法的 UTF-8 文本原样返回，二进制内容或 encoding=base64 时以 base64 返回
+// @Tags Secrets
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Secret Name"
+// @Param key path string true "Key"
+// @Param encoding query string false "强制使用 base64 编码" Enums(base64)
+// @Success 200 {object} models.SecretRevealResponse
+// @Failure 404 {object} handlers.ErrorResponse "Secret 或键不存在"
+// @Router /api/v1/namespaces/{namespace}/secrets/{name}/keys/{key}/reveal [get]
+func (h *SecretHandler) RevealSecretKey(c *gin.Context) {
+	namespace, name, ok := secretPathParams(c)
+	if !ok {
+		return
+	}
+	key := c.Param("key")
+	value, err := forUser(c, h.service).RevealKey(namespace, name, key)
+	if err != nil {
+		respondSecretKeyError(c, "读取Secret值失败", err)
+		return
+	}
+	response := models.SecretRevealResponse{Key: key, Size: len(value), Encoding: models.SecretValueEncodingText, Value: string(value)}
+	if c.Query("encoding") == models.SecretValueEncodingBase64 || !utf8.Valid(value) {
+		response.Encoding = models.SecretValueEncodingBase64
+		response.Value = base64.StdEncoding.EncodeToString(value)
+	}
+	respondSuccess(c, http.StatusOK, response)
+}
+
+// SetSecretKey godoc
+// @Summary Add or replace a Secret value
+// @Description 新增或替换单个键的值，其余键不变。application/json 时请求体为 models.SetSecretKeyRequest，
+// @Description multipart/form-data 时上传字段 file 的内容原样作为值 (适合证书、keystore 等二进制文件)
+// @Tags Secrets
+// @Accept json,mpfd
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Secret Name"
+// @Param key path string true "Key"
+// @Param request body models.SetSecretKeyRequest false "值"
+// @Param file formData file false "上传的文件"
+// @Success 200 {object} models.SecretDetailResponse "更新后的 Secret (值已遮盖)"
+// @Failure 400 {object} handlers.ErrorResponse "Bad Request"
+// @Failure 413 {object} handlers.ErrorResponse "上传内容过大"
+// @Router /api/v1/namespaces/{namespace}/secrets/{name}/keys/{key} [put]
+func (h *SecretHandler) SetSecretKey(c *gin.Context) {
+	namespace, name, ok := secretPathParams(c)
+	if !ok {
+		return
+	}
+	// 预留 multipart 头部的开销，Secret 总大小由 service 校验
+	if c.Request.ContentLength > service.MaxSecretSize*2 {
+		respondError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("上传内容超过大小限制 (%d 字节)", service.MaxSecretSize))
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxSecretSize*2)
+
+	var value []byte
+	if strings.Contains(c.ContentType(), "multipart/form-data") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "读取上传文件失败 (字段名应为 file): "+err.Error())
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "打开上传文件失败: "+err.Error())
+			return
+		}
+		defer func(f multipart.File) { _ = f.Close() }(file)
+		if value, err = io.ReadAll(io.LimitReader(file, service.MaxSecretSize+1)); err != nil {
+			respondError(c, http.StatusBadRequest, "读取上传文件失败: "+err.Error())
+			return
+		}
+		if len(value) > service.MaxSecretSize {
+			respondError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("上传内容超过大小限制 (%d 字节)", service.MaxSecretSize))
+			return
+		}
+	} else {
+		var req models.SetSecretKeyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "无效的请求体格式: "+err.Error())
+			return
+		}
+		switch req.Encoding {
+		case "", models.SecretValueEncodingText:
+			value = []byte(req.Value)
+		case models.SecretValueEncodingBase64:
+			decoded, err := base64.StdEncoding.DecodeString(req.Value)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, "value 不是合法的 base64: "+err.Error())
+				return
+			}
+			value = decoded
+		default:
+			respondError(c, http.StatusBadRequest, "encoding 只能是 text 或 base64")
+			return
+		}
+	}
+
+	secret, err := forUser(c, h.service).SetKey(namespace, name, c.Param("key"), value)
+	if err != nil {
+		respondSecretKeyError(c, "更新Secret失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, models.ToSecretDetailResponse(secret))
+}
+
+// RenameSecretKey godoc
+// @Summary Rename a Secret key
+// @Description 重命名单个键，值保持不变；新键已存在时需要 overwrite=true
+// @Tags Secrets
+// @Accept json
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Secret Name"
+// @Param key path string true "Key"
+// @Param request body models.RenameSecretKeyRequest true "新键名"
+// @Success 200 {object} models.SecretDetailResponse "更新后的 Secret (值已遮盖)"
+// @Failure 404 {object} handlers.ErrorResponse "Secret 或键不存在"
+// @Failure 409 {object} handlers.ErrorResponse "新键已存在"
+// @Router /api/v1/namespaces/{namespace}/secrets/{name}/keys/{key} [patch]
+func (h *SecretHandler) RenameSecretKey(c *gin.Context) {
+	namespace, name, ok := secretPathParams(c)
+	if !ok {
+		return
+	}
+	var req models.RenameSecretKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求体格式: "+err.Error())
+		return
+	}
+	secret, err := forUser(c, h.service).RenameKey(namespace, name, c.Param("key"), req.NewKey, req.Overwrite)
+	if err != nil {
+		respondSecretKeyError(c, "重命名Secret键失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, models.ToSecretDetailResponse(secret))
+}
+
+// DeleteSecretKey godoc
+// @Summary Remove a Secret key
+// @Description 删除单个键，其余键不变
+// @Tags Secrets
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param name path string true "Secret Name"
+// @Param key path string true "Key"
+// @Success 200 {object} models.SecretDetailResponse "更新后的 Secret (值已遮盖)"
+// @Failure 404 {object} handlers.ErrorResponse "Secret 或键不存在"
+// @Router /api/v1/namespaces/{namespace}/secrets/{name}/keys/{key} [delete]
+func (h *SecretHandler) DeleteSecretKey(c *gin.Context) {
+	namespace, name, ok := secretPathParams(c)
+	if !ok {
+		return
+	}
+	secret, err := forUser(c, h.service).RemoveKey(namespace, name, c.Param("key"))
+	if err != nil {
+		respondSecretKeyError(c, "删除Secret键失败", err)
+		return
+	}
+	respondSuccess(c, http.StatusOK, models.ToSecretDetailResponse(secret))
+}
+
+// CreateDockerConfigSecret godoc
+// @Summary Create a docker registry Secret
+// @Description 根据镜像仓库地址、用户名和密码创建 kubernetes.io/dockerconfigjson 类型的 Secret
+// @Tags Secrets
+// @Accept json
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param request body models.DockerConfigSecretRequest true "镜像仓库凭据"
+// @Success 201 {object} models.TypedSecretResponse
+// @Failure 400 {object} handlers.ErrorResponse "Bad Request"
+// @Failure 409 {object} handlers.ErrorResponse "Conflict - Already Exists"
+// @Router /api/v1/namespaces/{namespace}/secrets/dockerconfigjson [post]
+func (h *SecretHandler) CreateDockerConfigSecret(c *gin.Context) {
+	var req models.DockerConfigSecretRequest
+	namespace, ok := bindTypedSecretRequest(c, &req, &req.Name)
+	if !ok {
+		return
+	}
+	secret, err := forUser(c, h.service).CreateDockerConfigSecret(namespace, &req)
+	if err != nil {
+		respondSecretCreateError(c, err)
+		return
+	}
+	respondSuccess(c, http.StatusCreated, models.TypedSecretResponse{SecretResponse: models.ToSecretResponse(secret)})
+}
+
+// CreateTLSSecret godoc
+// @Summary Create a TLS Secret
+// @Description 校验 PEM 格式的证书和私钥 (私钥必须与叶子证书匹配) 后创建 kubernetes.io/tls 类型的 Secret，
+// @Description 返回证书链中每个证书的主题、SAN、签发者和有效期
+// @Tags Secrets
+// @Accept json
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param request body models.TLSSecretRequest true "证书和私钥"
+// @Success 201 {object} models.TypedSecretResponse
+// @Failure 400 {object} handlers.ErrorResponse "Bad Request - 证书或私钥无效"
+// @Failure 409 {object} handlers.ErrorResponse "Conflict - Already Exists"
+// @Router /api/v1/namespaces/{namespace}/secrets/tls [post]
+func (h *SecretHandler) CreateTLSSecret(c *gin.Context) {
+	var req models.TLSSecretRequest
+	namespace, ok := bindTypedSecretRequest(c, &req, &req.Name)
+	if !ok {
+		return
+	}
+	secret, certs, err := forUser(c, h.service).CreateTLSSecret(namespace, &req)
+	if err != nil {
+		respondSecretCreateError(c, err)
+		return
+	}
+	respondSuccess(c, http.StatusCreated, models.TypedSecretResponse{SecretResponse: models.ToSecretResponse(secret), Certificates: certs})
+}
+
+// CreateBasicAuthSecret godoc
+// @Summary Create a basic-auth Secret
+// @Description 创建 kubernetes.io/basic-auth 类型的 Secret
+// @Tags Secrets
+// @Accept json
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Param request body models.BasicAuthSecretRequest true "用户名和密码"
+// @Success 201 {object} models.TypedSecretResponse
+// @Failure 400 {object} handlers.ErrorResponse "Bad Request"
+// @Failure 409 {object} handlers.ErrorResponse "Conflict - Already Exists"
+// @Router /api/v1/namespaces/{namespace}/secrets/basic-auth [post]
+func (h *SecretHandler) CreateBasicAuthSecret(c *gin.Context) {
+	var req models.BasicAuthSecretRequest
+	namespace, ok := bindTypedSecretRequest(c, &req, &req.Name)
+	if !ok {
+		return
+	}
+	secret, err := forUser(c, h.service).CreateBasicAuthSecret(namespace, &req)
+	if err != nil {
+		respondSecretCreateError(c, err)
+		return
+	}
+	respondSuccess(c, http.StatusCreated, models.TypedSecretResponse{SecretResponse: models.ToSecretResponse(secret)})
+}
+
+// bindTypedSecretRequest 校验命名空间、绑定请求体并校验其中的 Secret 名称
+func bindTypedSecretRequest(c *gin.Context, req interface{}, name *string) (string, bool) {
+	namespace := strings.TrimSpace(c.Param("namespace"))
+	if !utils.ValidateNamespace(namespace) {
+		respondError(c, http.StatusBadRequest, "无效的命名空间格式")
+		return "", false
+	}
+	if err := c.ShouldBindJSON(req); err != nil {
+		respondError(c, http.StatusBadRequest, "无效的请求体格式: "+err.Error())
+		return "", false
+	}
+	*name = strings.TrimSpace(*name)
+	if !utils.ValidateResourceName(*name) {
+		respondError(c, http.StatusBadRequest, "无效的Secret名称格式")
+		return "", false
+	}
+	return namespace, true
+}
+
+func respondSecretCreateError(c *gin.Context, err error) {
+	var validationErr *service.ValidationError
+	switch {
+	case k8serrors.IsAlreadyExists(err):
+		respondError(c, http.StatusConflict, "Secret已存在")
+	case errors.As(err, &validationErr):
+		respondError(c, http.StatusBadRequest, err.Error())
+	default:
+		respondError(c, http.StatusInternalServerError, "创建Secret失败: "+err.Error())
+	}
+}
+
+func secretPathParams(c *gin.Context) (string, string, bool) {
+	namespace := strings.TrimSpace(c.Param("namespace"))
+	name := strings.TrimSpace(c.Param("name"))
+	if !utils.ValidateNamespace(namespace) || !utils.ValidateResourceName(name) {
+		respondError(c, http.StatusBadRequest, "无效的命名空间或Secret名称格式")
+		return "", "", false
+	}
+	return namespace, name, true
+}
+
+func respondSecretKeyError(c *gin.Context, message string, err error) {
+	var validationErr *service.ValidationError
+	switch {
+	case k8serrors.IsNotFound(err):
+		respondError(c, http.StatusNotFound, "Secret不存在")
+	case errors.Is(err, service.ErrSecretKeyNotFound):
+		respondError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrSecretKeyExists):
+		respondError(c, http.StatusConflict, err.Error())
+	case k8serrors.IsConflict(err):
+		respondError(c, http.StatusConflict, "资源已被修改，请稍后重试")
+	case errors.As(err, &validationErr):
+		respondError(c, http.StatusBadRequest, err.Error())
+	default:
+		respondError(c, http.StatusInternalServerError, message+": "+err.Error())
+	}
+}
+
 // --- Re-use or define respond helpers ---
 /*
 type ErrorResponse struct { Code int `json:"code"`; Message string `json:"message"`}
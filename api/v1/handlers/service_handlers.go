@@ -34,7 +34,7 @@ func (h *ServiceHandler) ListServices(c *gin.Context) {
 	}
 
 	// 2. 调用服务层获取Service列表
-	services, err := h.service.List(namespace)
+	services, err := forUser(c, h.service).List(namespace)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取Service列表失败: "+err.Error())
 		return
@@ -76,7 +76,7 @@ func (h *ServiceHandler) CreateService(c *gin.Context) {
 		Spec: req.Spec,
 	}
 
-	createdService, err := h.service.Create(namespace, service)
+	createdService, err := forUser(c, h.service).Create(namespace, service)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "创建Service失败: "+err.Error())
 		return
@@ -102,7 +102,7 @@ func (h *ServiceHandler) GetService(c *gin.Context) {
 	}
 
 	// 2. 调用服务层获取Service详情
-	service, err := h.service.Get(namespace, name)
+	service, err := forUser(c, h.service).Get(namespace, name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "Service不存在")
@@ -149,7 +149,7 @@ func (h *ServiceHandler) UpdateService(c *gin.Context) {
 		Spec: req.Spec,
 	}
 
-	updatedService, err := h.service.Update(namespace, service)
+	updatedService, err := forUser(c, h.service).Update(namespace, service)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "更新Service失败: "+err.Error())
 		return
@@ -176,7 +176,7 @@ func (h *ServiceHandler) DeleteService(c *gin.Context) {
 	}
 
 	// 2. 调用服务层删除Service
-	if err := h.service.Delete(namespace, name); err != nil {
+	if err := forUser(c, h.service).Delete(namespace, name); err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "Service不存在")
 			return
@@ -199,7 +199,7 @@ func (h *ServiceHandler) WatchServices(c *gin.Context) {
 	}
 
 	// 2. 调用服务层Watch Services
-	watcher, err := h.service.Watch(namespace, c.Query("selector"))
+	watcher, err := forUser(c, h.service).Watch(namespace, c.Query("selector"))
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "Watch Services失败: "+err.Error())
 		return
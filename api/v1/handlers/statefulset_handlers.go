@@ -33,7 +33,7 @@ func (h *StatefulSetHandler) ListStatefulSets(c *gin.Context) {
 	}
 
 	// 2. 调用服务层获取StatefulSet列表
-	statefulSets, err := h.service.List(namespace, c.Query("selector"), 0)
+	statefulSets, err := forUser(c, h.service).List(namespace, c.Query("selector"), 0)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取StatefulSet列表失败: "+err.Error())
 		return
@@ -70,7 +70,7 @@ func (h *StatefulSetHandler) CreateStatefulSet(c *gin.Context) {
 		Spec: req.Spec,
 	}
 
-	createdStatefulSet, err := h.service.Create(namespace, statefulSet)
+	createdStatefulSet, err := forUser(c, h.service).Create(namespace, statefulSet)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "创建StatefulSet失败: "+err.Error())
 		return
@@ -96,7 +96,7 @@ func (h *StatefulSetHandler) GetStatefulSet(c *gin.Context) {
 	}
 
 	// 2. 调用服务层获取StatefulSet详情
-	statefulSet, err := h.service.Get(namespace, name)
+	statefulSet, err := forUser(c, h.service).Get(namespace, name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "StatefulSet不存在")
@@ -143,7 +143,7 @@ func (h *StatefulSetHandler) UpdateStatefulSet(c *gin.Context) {
 		Spec: req.Spec,
 	}
 
-	updatedStatefulSet, err := h.service.Update(namespace, statefulSet)
+	updatedStatefulSet, err := forUser(c, h.service).Update(namespace, statefulSet)
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "更新StatefulSet失败: "+err.Error())
 		return
@@ -170,7 +170,7 @@ func (h *StatefulSetHandler) DeleteStatefulSet(c *gin.Context) {
 	}
 
 	// 2. 调用服务层删除StatefulSet
-	if err := h.service.Delete(namespace, name); err != nil {
+	if err := forUser(c, h.service).Delete(namespace, name); err != nil {
 		if errors.IsNotFound(err) {
 			respondError(c, http.StatusNotFound, "StatefulSet不存在")
 			return
@@ -193,7 +193,7 @@ func (h *StatefulSetHandler) WatchStatefulSets(c *gin.Context) {
 	}
 
 	// 2. 调用服务层Watch StatefulSets
-	watcher, err := h.service.Watch(namespace, c.Query("selector"))
+	watcher, err := forUser(c, h.service).Watch(namespace, c.Query("selector"))
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "Watch StatefulSets失败: "+err.Error())
 		return
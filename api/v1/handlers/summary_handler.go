@@ -18,7 +18,7 @@ func NewSummaryHandler(svc *service.SummaryService) *SummaryHandler {
 
 // Existing GetResourceSummary handlers...
 func (h *SummaryHandler) GetResourceSummary(c *gin.Context) { /* ... as before ... */
-	summary, _ := h.service.GetResourceSummary()
+	summary, _ := forUser(c, h.service).GetResourceSummary()
 	respondSuccess(c, http.StatusOK, summary)
 }
 
@@ -34,7 +34,7 @@ func (h *SummaryHandler) GetResourceSummary(c *gin.Context) { /* ... as before .
 // @Failure 500 {object} handlers.ErrorResponse "Internal Server Error - Failed to read/parse go.mod"
 // @Router /api/v1/summary/backend-dependencies [get]
 func (h *SummaryHandler) GetBackendDependencies(c *gin.Context) {
-	dependencies, err := h.service.GetBackendDependencies()
+	dependencies, err := forUser(c, h.service).GetBackendDependencies()
 	if err != nil {
 		respondError(c, http.StatusInternalServerError, "获取后端依赖失败: "+err.Error())
 		return
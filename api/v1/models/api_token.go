@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// API token 的权限范围
+const (
+	TokenScopeFull     = "full"      // 与所属用户权限相同
+	TokenScopeReadOnly = "read-only" // 只允许 get、list、watch
+)
+
+// APIToken 用于脚本和自动化的个人访问令牌，数据库中只保存令牌的哈希
+type APIToken struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	UserID    uint   `json:"user_id" gorm:"index;not null"`
+	Name      string `json:"name" gorm:"size:100;not null"`
+	Prefix    string `json:"prefix" gorm:"size:16"` // 令牌明文的前几位，便于用户辨认
+	TokenHash string `json:"-" gorm:"uniqueIndex;size:64;not null"`
+	Scope     string `json:"scope" gorm:"size:20;not null"`
+	// Roles 非空时，请求还必须被其中至少一个角色允许，令牌权限是用户权限与这些角色的交集
+	Roles      string     `json:"-" gorm:"size:255"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"index"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip" gorm:"size:64"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scope         string   `json:"scope" binding:"omitempty,oneof=full read-only"`
+	Roles         []string `json:"roles"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=365"` // 默认 30 天
+}
+
+// APITokenResponse 令牌列表中的一项，Token 只在创建时返回一次
+type APITokenResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scope      string     `json:"scope"`
+	Roles      []string   `json:"roles"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Token      string     `json:"token,omitempty"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// 审计事件类型
+const (
+	AuditActionLoginLockout = "auth.lockout"
+	AuditActionLoginUnlock  = "auth.unlock"
+	// AuditActionAPIRequest 由审计中间件记录的写操作请求
+	AuditActionAPIRequest = "api.request"
+)
+
+// AuditEvent 持久化的审计事件，API 请求和安全事件共用一张表
+type AuditEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	Username  string    `json:"username" gorm:"size:50;index"` // 操作人，未登录时为尝试登录的用户名
+	Role      string    `json:"role,omitempty" gorm:"size:50"`
+	ClientIP  string    `json:"client_ip" gorm:"size:64"`
+	Action    string    `json:"action" gorm:"size:64;index"`
+	Target    string    `json:"target" gorm:"size:255"` // 操作对象，例如被锁定的用户名或 IP
+	Detail    string    `json:"detail" gorm:"type:text"`
+
+	// 以下字段只有 API 请求事件才有
+	Cluster    string `json:"cluster,omitempty" gorm:"size:100;index"`
+	Verb       string `json:"verb,omitempty" gorm:"size:20;index"`
+	Resource   string `json:"resource,omitempty" gorm:"size:100;index"`
+	Namespace  string `json:"namespace,omitempty" gorm:"size:100;index"`
+	Name       string `json:"name,omitempty" gorm:"size:255"`
+	Method     string `json:"method,omitempty" gorm:"size:10"`
+	Path       string `json:"path,omitempty" gorm:"size:500"`
+	BodyHash   string `json:"body_hash,omitempty" gorm:"size:64"` // 请求体的 SHA-256，不保存请求体本身
+	StatusCode int    `json:"status_code,omitempty" gorm:"index"`
+	LatencyMs  int64  `json:"latency_ms,omitempty"`
+}
+
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
+
+// AuditQuery 审计事件查询条件，时间范围为 [From, To)
+type AuditQuery struct {
+	Username  string     `form:"username"`
+	Action    string     `form:"action"`
+	Cluster   string     `form:"cluster"`
+	Verb      string     `form:"verb"`
+	Resource  string     `form:"resource"`
+	Namespace string     `form:"namespace"`
+	Name      string     `form:"name"`
+	Status    int        `form:"status"` // 精确匹配状态码
+	Failed    bool       `form:"failed"` // 只看状态码 >= 400 的请求
+	From      *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To        *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page      int        `form:"page"`
+	PageSize  int        `form:"page_size"`
+}
+
+// AuditEventListResponse 审计事件分页结果
+type AuditEventListResponse struct {
+	Items    []AuditEvent `json:"items"`
+	Total    int64        `json:"total"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"page_size"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// 证书清单的排序字段
+const (
+	CertificateSortByDaysRemaining = "daysRemaining"
+	CertificateSortByName          = "name"
+)
+
+// CertificateInventoryQuery 证书清单的过滤和排序条件
+type CertificateInventoryQuery struct {
+	ThresholdDays *int   `form:"threshold_days"` // 只返回剩余天数不超过该值的证书 (含已过期)，解析失败的条目始终返回
+	SortBy        string `form:"sort_by"`        // daysRemaining (默认) 或 name
+	Order         string `form:"order"`          // asc (默认) 或 desc
+}
+
+// CertificateInventoryItem 一个 TLS Secret 中的证书，Certificate 为叶子证书
+type CertificateInventoryItem struct {
+	Namespace   string           `json:"namespace"`
+	SecretName  string           `json:"secretName"`
+	SecretType  string           `json:"secretType"`
+	Ingresses   []string         `json:"ingresses,omitempty"` // 在 tls 中引用该 Secret 的 Ingress
+	Certificate *CertificateInfo `json:"certificate,omitempty"`
+	ChainLength int              `json:"chainLength"`
+	Error       string           `json:"error,omitempty"` // 证书缺失或无法解析的原因
+}
+
+// DanglingTLSReference Ingress tls 中引用了不存在或不含证书的 Secret
+type DanglingTLSReference struct {
+	Namespace  string   `json:"namespace"`
+	Ingress    string   `json:"ingress"`
+	SecretName string   `json:"secretName"`
+	Hosts      []string `json:"hosts,omitempty"`
+	Reason     string   `json:"reason"`
+}
+
+// CertificateInventoryResponse 证书过期清单
+type CertificateInventoryResponse struct {
+	Items              []CertificateInventoryItem `json:"items"`
+	Total              int                        `json:"total"`
+	DanglingReferences []DanglingTLSReference     `json:"danglingReferences"`
+	ScannedAt          time.Time                  `json:"scannedAt"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// LoginAttempt 按用户名或客户端 IP 统计的连续登录失败，Key 形如 "user:alice" 或 "ip:10.0.0.1"
+type LoginAttempt struct {
+	Key           string     `json:"key" gorm:"column:attempt_key;primaryKey;size:191"`
+	Failures      int        `json:"failures"`
+	LockCount     int        `json:"lock_count"` // 已连续锁定的次数，决定下一次锁定的时长
+	LockedUntil   *time.Time `json:"locked_until"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+}
+
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
+
+// PasswordHistory 本地账号用过的密码哈希，用于禁止重复使用最近的密码
+type PasswordHistory struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	Hash      string    `json:"-" gorm:"size:255;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (PasswordHistory) TableName() string {
+	return "password_histories"
+}
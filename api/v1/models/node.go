@@ -44,3 +44,118 @@ func ToNodeResponse(node *corev1.Node) NodeResponse {
 		CreatedAt:   node.CreationTimestamp,
 	}
 }
+
+// DrainNodeRequest 驱逐节点的请求参数
+type DrainNodeRequest struct {
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds,omitempty"` // 为空时使用 Pod 自身的配置
+	TimeoutSeconds     int    `json:"timeoutSeconds,omitempty"`     // 整体超时时间，默认 300 秒
+	Force              bool   `json:"force,omitempty"`              // 允许驱逐不受控制器管理的 Pod
+	DeleteEmptyDirData bool   `json:"deleteEmptyDirData,omitempty"` // 允许驱逐使用 emptyDir 的 Pod (数据将丢失)
+}
+
+// 驱逐事件类型
+const (
+	DrainEventCordoned = "cordoned"
+	DrainEventSkipped  = "skipped"
+	DrainEventEvicting = "evicting"
+	DrainEventRetrying = "retrying"
+	DrainEventEvicted  = "evicted"
+	DrainEventFailed   = "failed"
+	DrainEventComplete = "complete"
+)
+
+// NodeDrainEvent 驱逐过程中单个 Pod 的进度事件 (SSE)
+type NodeDrainEvent struct {
+	Type      string `json:"type"`
+	Node      string `json:"node"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Timestamp string `json:"timestamp"`
+}
+
+// NodeResourceUsage 单个资源的容量、可分配量以及节点上 Pod 的请求/限制汇总
+type NodeResourceUsage struct {
+	Resource        string  `json:"resource"`
+	Capacity        string  `json:"capacity"`
+	Allocatable     string  `json:"allocatable"`
+	Requests        string  `json:"requests"`
+	Limits          string  `json:"limits"`
+	RequestsPercent float64 `json:"requestsPercent"` // 请求量占可分配量的百分比
+	LimitsPercent   float64 `json:"limitsPercent"`   // 限制量占可分配量的百分比，可能超过 100 (超卖)
+}
+
+// NodePodSummary 调度在节点上的 Pod 摘要
+type NodePodSummary struct {
+	Name           string `json:"name"`
+	Namespace      string `json:"namespace"`
+	Phase          string `json:"phase"`
+	CPURequests    string `json:"cpuRequests"`
+	CPULimits      string `json:"cpuLimits"`
+	MemoryRequests string `json:"memoryRequests"`
+	MemoryLimits   string `json:"memoryLimits"`
+	CreatedAt      string `json:"createdAt"`
+}
+
+// NodeDetailResponse 节点详情：资源分配、Pod 分布、状况、污点等
+type NodeDetailResponse struct {
+	Name           string                 `json:"name"`
+	Labels         map[string]string      `json:"labels,omitempty"`
+	Annotations    map[string]string      `json:"annotations,omitempty"`
+	Unschedulable  bool                   `json:"unschedulable"`
+	Ready          string                 `json:"ready"`    // True, False, Unknown
+	Pressure       map[string]bool        `json:"pressure"` // MemoryPressure, DiskPressure, PIDPressure, NetworkUnavailable
+	Conditions     []corev1.NodeCondition `json:"conditions"`
+	Taints         []corev1.Taint         `json:"taints,omitempty"`
+	Addresses      []corev1.NodeAddress   `json:"addresses,omitempty"`
+	KubeletVersion string                 `json:"kubeletVersion"`
+	NodeInfo       corev1.NodeSystemInfo  `json:"nodeInfo"`
+	Resources      []NodeResourceUsage    `json:"resources"`
+	PodCount       int                    `json:"podCount"`
+	PodCapacity    string                 `json:"podCapacity"`
+	Pods           []NodePodSummary       `json:"pods"`
+	CreatedAt      string                 `json:"createdAt"`
+}
+
+// NodeLabelsRequest 修改节点标签：set 中的标签新增或覆盖，remove 中的标签删除
+type NodeLabelsRequest struct {
+	Set    map[string]string `json:"set,omitempty"`
+	Remove []string          `json:"remove,omitempty"`
+}
+
+// NodeTaintRemoval 指定要删除的污点，effect 为空时删除该 key 的所有污点
+type NodeTaintRemoval struct {
+	Key    string             `json:"key" binding:"required"`
+	Effect corev1.TaintEffect `json:"effect,omitempty"`
+}
+
+// NodeTaintsRequest 修改节点污点：set 中的污点按 key+effect 新增或更新，remove 中的污点删除
+type NodeTaintsRequest struct {
+	Set    []corev1.Taint     `json:"set,omitempty"`
+	Remove []NodeTaintRemoval `json:"remove,omitempty"`
+}
+
+// NodeBatchTarget 批量操作的目标节点，nodes 与 selector 二选一
+type NodeBatchTarget struct {
+	Nodes    []string `json:"nodes,omitempty"`
+	Selector string   `json:"selector,omitempty"`
+}
+
+// BatchNodeLabelsRequest 批量修改节点标签
+type BatchNodeLabelsRequest struct {
+	NodeBatchTarget
+	NodeLabelsRequest
+}
+
+// BatchNodeTaintsRequest 批量修改节点污点
+type BatchNodeTaintsRequest struct {
+	NodeBatchTarget
+	NodeTaintsRequest
+}
+
+// NodeBatchResult 批量操作中单个节点的结果
+type NodeBatchResult struct {
+	Node    string `json:"node"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
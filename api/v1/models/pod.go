@@ -192,3 +192,38 @@ func ToPodResponse(pod *corev1.Pod) PodResponse {
 		},
 	}
 }
+
+// PodFileCopyResponse 容器文件上传结果
+type PodFileCopyResponse struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Path      string `json:"path"`
+	Bytes     int64  `json:"bytes"` // 实际传输的文件内容字节数
+}
+
+// PodFileCopyProgress 上传进度事件 (SSE)
+type PodFileCopyProgress struct {
+	Bytes int64 `json:"bytes"`
+	Total int64 `json:"total,omitempty"` // 未知时为 0 (例如上传 tar 归档)
+}
+
+// CreateDebugContainerRequest 创建临时调试容器的请求
+type CreateDebugContainerRequest struct {
+	Image           string   `json:"image" binding:"required"`
+	Name            string   `json:"name,omitempty"`            // 为空时自动生成 debugger-xxxxx
+	TargetContainer string   `json:"targetContainer,omitempty"` // 共享进程命名空间的目标容器
+	Command         []string `json:"command,omitempty"`         // 覆盖镜像 entrypoint
+	Shell           string   `json:"shell,omitempty"`           // 通过 exec 连接时使用的命令，默认 sh
+	TimeoutSeconds  int      `json:"timeoutSeconds,omitempty"`  // 等待容器启动的超时时间
+}
+
+// DebugContainerResponse 临时调试容器创建结果，ExecPath 可直接用于 exec WebSocket 连接
+type DebugContainerResponse struct {
+	Namespace       string `json:"namespace"`
+	Pod             string `json:"pod"`
+	Container       string `json:"container"`
+	Image           string `json:"image"`
+	TargetContainer string `json:"targetContainer,omitempty"`
+	ExecPath        string `json:"execPath"`
+}
@@ -0,0 +1,93 @@
+package models
+
+// CasbinPolicy 一条 Casbin 权限策略：角色在某个域 (集群/命名空间) 中对资源执行动作
+type CasbinPolicy struct {
+	Role     string `json:"role" binding:"required"`
+	Domain   string `json:"domain" binding:"required"`   // 如 prod/team-a、*/team-a、**
+	Resource string `json:"resource" binding:"required"` // 如 pods、pods*、nodes/drain、*
+	Verb     string `json:"verb" binding:"required"`     // get、list、watch、create、update、patch、delete、reveal、proxy 或 *
+}
+
+// CasbinGrouping 一条角色继承关系：成员 (角色或 user:用户名) 在某个域中拥有指定角色
+type CasbinGrouping struct {
+	Member string `json:"member" binding:"required"`
+	Role   string `json:"role" binding:"required"`
+	Domain string `json:"domain" binding:"required"`
+}
+
+// PolicyListResponse 当前生效的策略和角色继承关系
+type PolicyListResponse struct {
+	Policies  []CasbinPolicy   `json:"policies"`
+	Groupings []CasbinGrouping `json:"groupings"`
+}
+
+// PolicyChangeRequest 一次批量修改策略的请求，先删除再添加
+type PolicyChangeRequest struct {
+	AddPolicies     []CasbinPolicy   `json:"addPolicies,omitempty"`
+	RemovePolicies  []CasbinPolicy   `json:"removePolicies,omitempty"`
+	AddGroupings    []CasbinGrouping `json:"addGroupings,omitempty"`
+	RemoveGroupings []CasbinGrouping `json:"removeGroupings,omitempty"`
+}
+
+// PermissionRule 权限模板中的一条规则
+type PermissionRule struct {
+	Resource string   `json:"resource" binding:"required"`
+	Verbs    []string `json:"verbs" binding:"required,min=1"`
+}
+
+// RoleTemplate 预置的权限模板，创建自定义角色时可以直接引用
+type RoleTemplate struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Rules       []PermissionRule `json:"rules"`
+}
+
+// CreateCasbinRoleRequest 创建自定义角色，权限由模板和额外规则合并而成
+type CreateCasbinRoleRequest struct {
+	Name     string           `json:"name" binding:"required"`
+	Template string           `json:"template,omitempty"`
+	Rules    []PermissionRule `json:"rules,omitempty"`
+	// Domains 角色策略生效的域，为空时对所有域生效，实际范围由分配角色时指定的集群/命名空间限制
+	Domains []string `json:"domains,omitempty"`
+}
+
+// CasbinRole 角色及其策略和成员
+type CasbinRole struct {
+	Name     string           `json:"name"`
+	BuiltIn  bool             `json:"builtIn"`
+	Policies []CasbinPolicy   `json:"policies"`
+	Members  []CasbinGrouping `json:"members"`
+}
+
+// RoleAssignmentRequest 在指定集群/命名空间中为用户分配角色
+type RoleAssignmentRequest struct {
+	Username  string `json:"username" form:"username" binding:"required"`
+	Role      string `json:"role" form:"role" binding:"required"`
+	Cluster   string `json:"cluster,omitempty" form:"cluster"`     // 为空表示所有集群
+	Namespace string `json:"namespace,omitempty" form:"namespace"` // 为空表示所有命名空间，"_" 表示仅集群级资源
+}
+
+// PolicyPreviewRequest 预览策略变更后某个角色或用户可访问的路由变化
+type PolicyPreviewRequest struct {
+	Role      string              `json:"role,omitempty"`
+	Username  string              `json:"username,omitempty"`
+	Cluster   string              `json:"cluster,omitempty"`   // 默认当前集群
+	Namespace string              `json:"namespace,omitempty"` // 命名空间级路由使用的命名空间，默认 default
+	Changes   PolicyChangeRequest `json:"changes"`
+}
+
+// RoutePermission 一条 API 路由及其对应的授权属性
+type RoutePermission struct {
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Domain   string `json:"domain"`
+	Resource string `json:"resource"`
+	Verb     string `json:"verb"`
+}
+
+// PolicyPreviewResponse 策略变更后新增和失去的路由
+type PolicyPreviewResponse struct {
+	Subject string            `json:"subject"`
+	Gained  []RoutePermission `json:"gained"`
+	Lost    []RoutePermission `json:"lost"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"time"
@@ -115,7 +116,7 @@ func ToServiceAccountsResponse(serviceAccount *corev1.ServiceAccount) *ServiceAc
 
 type CreateRoleRequest struct {
 	Name        string              `json:"name" binding:"required"`
-	Namespace   string              `json:"namespace" binding:"required"`
+	Namespace   string              `json:"namespace,omitempty"` // 可省略，默认使用路径中的命名空间
 	Rules       []rbacv1.PolicyRule `json:"rules" binding:"required"`
 	Labels      map[string]string   `json:"labels,omitempty"`
 	Annotations map[string]string   `json:"annotations,omitempty"`
@@ -128,7 +129,7 @@ type UpdateRoleRequest struct {
 }
 type CreateRoleBindingRequest struct {
 	Name        string            `json:"name" binding:"required"`
-	Namespace   string            `json:"namespace" binding:"required"`
+	Namespace   string            `json:"namespace,omitempty"` // 可省略，默认使用路径中的命名空间
 	RoleRef     rbacv1.RoleRef    `json:"roleRef" binding:"required"`
 	Subjects    []rbacv1.Subject  `json:"subjects" binding:"required"`
 	Labels      map[string]string `json:"labels,omitempty"`
@@ -172,7 +173,7 @@ type UpdateClusterRoleBindingRequest struct {
 
 type CreateServiceAccountRequest struct {
 	Name        string            `json:"name" binding:"required"`
-	Namespace   string            `json:"namespace" binding:"required"`
+	Namespace   string            `json:"namespace,omitempty"` // 可省略，默认使用路径中的命名空间
 	Labels      map[string]string `json:"labels,omitempty"`
 	Annotations map[string]string `json:"annotations,omitempty"`
 }
@@ -181,3 +182,138 @@ type UpdateServiceAccountRequest struct {
 	Labels      map[string]string `json:"labels,omitempty"`
 	Annotations map[string]string `json:"annotations,omitempty"`
 }
+
+// ServiceAccountRef 引用某个命名空间下的 ServiceAccount
+type ServiceAccountRef struct {
+	Namespace string `json:"namespace" binding:"required"`
+	Name      string `json:"name" binding:"required"`
+}
+
+// AccessReviewSubject 访问审查的主体：user、groups 和 serviceAccount 至少指定一个
+// 指定 serviceAccount 时不能再指定 user，其用户名和所属组由 API Server 的约定推导
+type AccessReviewSubject struct {
+	User           string             `json:"user,omitempty"`
+	Groups         []string           `json:"groups,omitempty"`
+	ServiceAccount *ServiceAccountRef `json:"serviceAccount,omitempty"`
+}
+
+// AccessReviewRequest 检查主体能否对资源执行某个操作 (can-i)
+// 资源请求填写 resource 等字段，非资源请求填写 nonResourcePath
+type AccessReviewRequest struct {
+	AccessReviewSubject
+	Verb            string `json:"verb" binding:"required"`
+	Namespace       string `json:"namespace,omitempty"`
+	Group           string `json:"group,omitempty"`
+	Resource        string `json:"resource,omitempty"`
+	Subresource     string `json:"subresource,omitempty"`
+	Name            string `json:"name,omitempty"`
+	NonResourcePath string `json:"nonResourcePath,omitempty"`
+}
+
+type AccessReviewResponse struct {
+	User            string   `json:"user,omitempty"`
+	Groups          []string `json:"groups,omitempty"`
+	Allowed         bool     `json:"allowed"`
+	Denied          bool     `json:"denied,omitempty"`
+	Reason          string   `json:"reason,omitempty"`
+	EvaluationError string   `json:"evaluationError,omitempty"`
+}
+
+// RulesReviewRequest 列出主体在命名空间中拥有的全部权限
+type RulesReviewRequest struct {
+	AccessReviewSubject
+	Namespace string `json:"namespace" binding:"required"`
+}
+
+type RulesReviewResponse struct {
+	User             string                            `json:"user"`
+	Groups           []string                          `json:"groups,omitempty"`
+	Namespace        string                            `json:"namespace"`
+	ResourceRules    []authorizationv1.ResourceRule    `json:"resourceRules"`
+	NonResourceRules []authorizationv1.NonResourceRule `json:"nonResourceRules"`
+	Incomplete       bool                              `json:"incomplete"`
+	EvaluationError  string                            `json:"evaluationError,omitempty"`
+}
+
+// ServiceAccountKubeconfigRequest 为 ServiceAccount 签发令牌并生成 kubeconfig
+type ServiceAccountKubeconfigRequest struct {
+	Audiences         []string `json:"audiences,omitempty"`         // 为空时使用 API Server 默认的 audience
+	ExpirationSeconds int64    `json:"expirationSeconds,omitempty"` // 为空时默认 1 小时，最少 600 秒
+	ClusterName       string   `json:"clusterName,omitempty"`       // kubeconfig 中的集群名称
+}
+
+type ServiceAccountKubeconfigResponse struct {
+	Namespace      string `json:"namespace"`
+	ServiceAccount string `json:"serviceAccount"`
+	Server         string `json:"server"`
+	ExpiresAt      string `json:"expiresAt"`
+	Kubeconfig     string `json:"kubeconfig"`
+}
+
+// PermissionSubject 查询有效权限的主体
+// kind 为 User 时可通过 groups 附加用户所属的组；ServiceAccount 自动包含其隐含的组
+type PermissionSubject struct {
+	Kind      string   `json:"kind" form:"kind" binding:"required,oneof=User Group ServiceAccount"`
+	Name      string   `json:"name" form:"name" binding:"required"`
+	Namespace string   `json:"namespace,omitempty" form:"namespace"`
+	Groups    []string `json:"groups,omitempty" form:"groups"`
+}
+
+// PermissionBindingRef 授予权限的绑定及其引用的角色
+type PermissionBindingRef struct {
+	Kind      string `json:"kind"` // RoleBinding 或 ClusterRoleBinding
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	RoleKind  string `json:"roleKind"`
+	RoleName  string `json:"roleName"`
+}
+
+// PermissionMatrixRow 权限矩阵中的一行：某命名空间内某资源允许的动作
+// namespace 为 "*" 表示通过 ClusterRoleBinding 在所有命名空间 (以及集群级资源) 生效
+type PermissionMatrixRow struct {
+	Namespace     string   `json:"namespace"`
+	APIGroup      string   `json:"apiGroup"`
+	Resource      string   `json:"resource"`
+	ResourceNames []string `json:"resourceNames,omitempty"`
+	Verbs         []string `json:"verbs"`
+	Sources       []string `json:"sources"` // 授予该权限的绑定，格式为 Kind/namespace/name
+}
+
+// NonResourcePermission 非资源 URL 权限
+type NonResourcePermission struct {
+	URL     string   `json:"url"`
+	Verbs   []string `json:"verbs"`
+	Sources []string `json:"sources"`
+}
+
+type PermissionMatrixResponse struct {
+	Subject         PermissionSubject       `json:"subject"`
+	Groups          []string                `json:"groups,omitempty"` // 参与匹配的全部组
+	Bindings        []PermissionBindingRef  `json:"bindings"`
+	Rows            []PermissionMatrixRow   `json:"rows"`
+	NonResourceURLs []NonResourcePermission `json:"nonResourceURLs,omitempty"`
+}
+
+// WhoCanRequest 反向查询：哪些主体可以对资源执行某个动作
+type WhoCanRequest struct {
+	Verb      string `form:"verb" binding:"required"`
+	Group     string `form:"group"`
+	Resource  string `form:"resource" binding:"required"` // 子资源使用 "pods/log" 形式
+	Namespace string `form:"namespace"`                   // 为空表示集群级请求，只有 ClusterRoleBinding 生效
+	Name      string `form:"name"`
+}
+
+// WhoCanEntry 可执行该动作的主体及授权来源
+type WhoCanEntry struct {
+	Subject rbacv1.Subject       `json:"subject"`
+	Binding PermissionBindingRef `json:"binding"`
+}
+
+type WhoCanResponse struct {
+	Verb      string        `json:"verb"`
+	Group     string        `json:"group"`
+	Resource  string        `json:"resource"`
+	Namespace string        `json:"namespace,omitempty"`
+	Name      string        `json:"name,omitempty"`
+	Subjects  []WhoCanEntry `json:"subjects"`
+}
@@ -1,7 +1,9 @@
 package models
 
 import (
-	"encoding/base64" // Needed if handling binary data display/edit
+	"sort"
+	"time"
+	"unicode/utf8"
 
 	corev1 "k8s.io/api/core/v1"
 )
@@ -47,38 +49,159 @@ func ToSecretResponse(secret *corev1.Secret) SecretResponse {
 		DataCount:       dataCount,
 		CreatedAt:       secret.CreationTimestamp.Format("2006-01-02T15:04:05Z"),
 		Labels:          secret.Labels,
-		Annotations:     secret.Annotations,
+		Annotations:     MaskSecretAnnotations(secret.Annotations),
 		ResourceVersion: secret.ResourceVersion,
 	}
 }
 
-// SecretDetailResponse includes the actual data (use for Get/Edit via YAML)
-// Data values are typically base64 encoded by K8s in the 'Data' field.
-// We might decode StringData for easier editing in YAML view? Or keep raw.
+// SecretDetailResponse Secret 详情，Data 中的值默认以 SecretMaskedValue 遮盖，
+// 需要查看明文时调用 reveal 接口逐个键解码
 type SecretDetailResponse struct {
 	SecretResponse                   // Embed basic info
-	Data           map[string]string `json:"data,omitempty"`       // Values will be base64 encoded strings
-	StringData     map[string]string `json:"stringData,omitempty"` // Values are plain strings
+	Data           map[string]string `json:"data,omitempty"`       // 遮盖后的值
+	StringData     map[string]string `json:"stringData,omitempty"` // 遮盖后的值
+	Keys           []SecretKeyInfo   `json:"keys"`                 // 按键名排序
 	Type           corev1.SecretType `json:"type"`                 // Re-declare type from corev1 if not embedded fully
 }
 
-// ToSecretDetailResponse converts corev1.Secret to detailed response.
-// It keeps Data base64 encoded as it comes from K8s API.
+// ToSecretDetailResponse converts corev1.Secret to detailed response with masked values.
 func ToSecretDetailResponse(secret *corev1.Secret) SecretDetailResponse {
 	basicResponse := ToSecretResponse(secret)
 
-	// Convert []byte from secret.Data to base64 strings for JSON response
-	encodedData := make(map[string]string)
-	if secret.Data != nil {
-		for key, value := range secret.Data {
-			encodedData[key] = base64.StdEncoding.EncodeToString(value)
-		}
+	keys := make([]SecretKeyInfo, 0, len(secret.Data))
+	for key, value := range secret.Data {
+		keys = append(keys, SecretKeyInfo{Key: key, Size: len(value), Binary: !utf8.Valid(value)})
 	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
 
 	return SecretDetailResponse{
-		SecretResponse: basicResponse,     // Basic info remains the same
-		Data:           encodedData,       // Base64 encoded data
-		StringData:     secret.StringData, // Plain string data
-		Type:           secret.Type,       // Ensure type is present
+		SecretResponse: basicResponse,
+		Data:           MaskSecretValues(secret.Data),
+		StringData:     MaskSecretValues(stringDataBytes(secret.StringData)),
+		Keys:           keys,
+		Type:           secret.Type,
+	}
+}
+
+// SecretMaskedValue 默认视图中代替 Secret 值的占位符。它不是合法的 base64，
+// 把遮盖后的内容原样提交回来会被拒绝，而不会把占位符写进 Secret
+const SecretMaskedValue = "******"
+
+// Secret 值在 reveal 接口中的编码方式
+const (
+	SecretValueEncodingText   = "text"   // 合法的 UTF-8 文本，原样返回
+	SecretValueEncodingBase64 = "base64" // 二进制内容以 base64 返回
+)
+
+// SecretKeyInfo 单个键的元信息，不包含值
+type SecretKeyInfo struct {
+	Key    string `json:"key"`
+	Size   int    `json:"size"`   // 解码后的字节数
+	Binary bool   `json:"binary"` // 值不是合法的 UTF-8 文本
+}
+
+// SecretRevealResponse 单个键解码后的值
+type SecretRevealResponse struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Encoding string `json:"encoding"` // text 或 base64
+	Size     int    `json:"size"`
+}
+
+// SetSecretKeyRequest 新增或替换单个键的值，Encoding 为 base64 时 Value 按 base64 解码后保存
+type SetSecretKeyRequest struct {
+	Value    string `json:"value"`
+	Encoding string `json:"encoding"` // text (默认) 或 base64
+}
+
+// RenameSecretKeyRequest 重命名单个键，新键已存在时需要 Overwrite 才会覆盖
+type RenameSecretKeyRequest struct {
+	NewKey    string `json:"newKey" binding:"required"`
+	Overwrite bool   `json:"overwrite"`
+}
+
+// MaskSecretValues 保留键名，所有值替换为 SecretMaskedValue
+func MaskSecretValues(data map[string][]byte) map[string]string {
+	if len(data) == 0 {
+		return nil
+	}
+	masked := make(map[string]string, len(data))
+	for key := range data {
+		masked[key] = SecretMaskedValue
+	}
+	return masked
+}
+
+// MaskSecretAnnotations 遮盖 kubectl apply 写入的 last-applied-configuration 注解，
+// 该注解保存了完整的 Secret (含 base64 编码的 data)，原样返回等于泄露全部值
+func MaskSecretAnnotations(annotations map[string]string) map[string]string {
+	if _, ok := annotations[corev1.LastAppliedConfigAnnotation]; !ok {
+		return annotations
+	}
+	masked := make(map[string]string, len(annotations))
+	for key, value := range annotations {
+		masked[key] = value
 	}
+	masked[corev1.LastAppliedConfigAnnotation] = SecretMaskedValue
+	return masked
+}
+
+func stringDataBytes(data map[string]string) map[string][]byte {
+	if len(data) == 0 {
+		return nil
+	}
+	result := make(map[string][]byte, len(data))
+	for key, value := range data {
+		result[key] = []byte(value)
+	}
+	return result
+}
+
+// DockerConfigSecretRequest 创建 kubernetes.io/dockerconfigjson 类型的镜像仓库凭据
+type DockerConfigSecretRequest struct {
+	Name     string            `json:"name" binding:"required"`
+	Server   string            `json:"server" binding:"required"` // 镜像仓库地址，如 registry.example.com 或 https://index.docker.io/v1/
+	Username string            `json:"username" binding:"required"`
+	Password string            `json:"password" binding:"required"`
+	Email    string            `json:"email,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+// TLSSecretRequest 创建 kubernetes.io/tls 类型的证书，Cert 可以包含完整的证书链 (叶子证书在前)
+type TLSSecretRequest struct {
+	Name   string            `json:"name" binding:"required"`
+	Cert   string            `json:"cert" binding:"required"` // PEM 格式证书
+	Key    string            `json:"key" binding:"required"`  // PEM 格式私钥 (PKCS#1、PKCS#8 或 EC)，不支持加密的私钥
+	CA     string            `json:"ca,omitempty"`            // 可选的 CA 证书，保存为 ca.crt
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// BasicAuthSecretRequest 创建 kubernetes.io/basic-auth 类型的用户名密码
+type BasicAuthSecretRequest struct {
+	Name     string            `json:"name" binding:"required"`
+	Username string            `json:"username" binding:"required"`
+	Password string            `json:"password" binding:"required"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+// CertificateInfo 从 X.509 证书中解析出的信息
+type CertificateInfo struct {
+	Subject        string    `json:"subject"`
+	Issuer         string    `json:"issuer"`
+	SerialNumber   string    `json:"serialNumber"`
+	DNSNames       []string  `json:"dnsNames,omitempty"`
+	IPAddresses    []string  `json:"ipAddresses,omitempty"`
+	EmailAddresses []string  `json:"emailAddresses,omitempty"`
+	URIs           []string  `json:"uris,omitempty"`
+	NotBefore      time.Time `json:"notBefore"`
+	NotAfter       time.Time `json:"notAfter"`
+	DaysRemaining  int       `json:"daysRemaining"` // 距离过期的天数，不足一天的部分舍去，已过期时为负数
+	Expired        bool      `json:"expired"`
+	IsCA           bool      `json:"isCA"`
+}
+
+// TypedSecretResponse 通过类型化接口创建的 Secret，TLS 类型同时返回解析出的证书链
+type TypedSecretResponse struct {
+	SecretResponse
+	Certificates []CertificateInfo `json:"certificates,omitempty"`
 }
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Session 一次登录会话，保存哈希后的刷新令牌；每次刷新都会轮换刷新令牌和 access token
+type Session struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	UserID            uint       `json:"user_id" gorm:"index;not null"`
+	RefreshTokenHash  string     `json:"-" gorm:"uniqueIndex;size:64;not null"`
+	PreviousTokenHash string     `json:"-" gorm:"index;size:64"` // 上一个刷新令牌，再次使用说明令牌已泄露
+	AccessTokenID     string     `json:"-" gorm:"size:64"`       // 当前有效的 access token (jti)
+	AccessExpiresAt   time.Time  `json:"-"`
+	UserAgent         string     `json:"user_agent" gorm:"size:255"`
+	ClientIP          string     `json:"client_ip" gorm:"size:64"`
+	ExpiresAt         time.Time  `json:"expires_at" gorm:"index"`
+	RevokedAt         *time.Time `json:"revoked_at"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+func (Session) TableName() string {
+	return "user_sessions"
+}
+
+// RevokedToken 已吊销但尚未过期的 access token，过期后即可清理
+type RevokedToken struct {
+	TokenID   string    `gorm:"primaryKey;size:64"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
+
+// ClientInfo 发起登录或刷新请求的客户端信息
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// SessionResponse 会话列表中的一项，Current 表示发起请求的会话
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	ClientIP   string    `json:"client_ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// SecurityPolicy 管理员维护的全局安全策略，表中只有一行
+type SecurityPolicy struct {
+	ID uint `json:"-" gorm:"primaryKey"`
+	// RequireTwoFactorForWriters 拥有写权限 (任意域中可执行 get、list、watch 以外动作) 的用户必须启用两步验证
+	RequireTwoFactorForWriters bool      `json:"require_two_factor_for_writers"`
+	UpdatedAt                  time.Time `json:"updated_at"`
+}
+
+func (SecurityPolicy) TableName() string {
+	return "security_policies"
+}
+
+type UpdateSecurityPolicyRequest struct {
+	RequireTwoFactorForWriters *bool `json:"require_two_factor_for_writers"`
+}
+
+// TOTPSetupResponse 绑定身份验证器所需的信息，URI 可直接渲染为二维码
+type TOTPSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// RecoveryCodesResponse 恢复码只在生成时返回一次
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorLoginRequest 登录第二步，Code 可以是身份验证器中的验证码或恢复码
+type TwoFactorLoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type TwoFactorSetupLoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+// TwoFactorEnrollResponse 登录时被要求绑定两步验证，完成绑定后同时返回恢复码和登录令牌
+type TwoFactorEnrollResponse struct {
+	RecoveryCodes []string       `json:"recovery_codes"`
+	Login         *LoginResponse `json:"login"`
+}
@@ -9,16 +9,23 @@ import (
 
 // User 用户模型
 type User struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Username  string         `json:"username" gorm:"uniqueIndex;not null;size:50"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null;size:100"`
-	Password  string         `json:"-" gorm:"not null"`
-	Role      string         `json:"role" gorm:"default:user;size:20"`
-	IsActive  bool           `json:"is_active" gorm:"default:true"`
-	LastLogin *time.Time     `json:"last_login"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                 uint           `json:"id" gorm:"primaryKey"`
+	Username           string         `json:"username" gorm:"uniqueIndex;not null;size:50"`
+	Email              string         `json:"email" gorm:"uniqueIndex;not null;size:100"`
+	Password           string         `json:"-" gorm:"not null"`
+	Role               string         `json:"role" gorm:"default:user;size:20"`
+	IsActive           bool           `json:"is_active" gorm:"default:true"`
+	AuthProvider       string         `json:"auth_provider" gorm:"default:local;size:20"` // 账号来源，外部账号不能使用本地密码登录
+	ExternalID         string         `json:"-" gorm:"size:255;index"`                    // 外部身份提供方中的用户标识 (OIDC 的 sub)
+	TOTPSecret         string         `json:"-" gorm:"size:64"`                           // TOTP 密钥，绑定时生成，确认验证码后才启用
+	TOTPEnabled        bool           `json:"totp_enabled" gorm:"default:false"`          // 启用后登录需要第二步验证
+	TOTPLastCounter    int64          `json:"-"`                                          // 最近一次使用的时间步，防止验证码重放
+	TOTPRecoveryCodes  string         `json:"-" gorm:"type:text"`                         // 恢复码的 SHA-256 摘要，逗号分隔，每个只能使用一次
+	LastLogin          *time.Time     `json:"last_login"`
+	MustChangePassword bool           `json:"must_change_password" gorm:"default:false"` // 为 true 时只能修改密码，不能访问其他接口
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 //// UserRole 用户角色关联表
@@ -33,6 +40,13 @@ type User struct {
 //	return "user_roles"
 //}
 
+// 用户账号来源
+const (
+	AuthProviderLocal = "local"
+	AuthProviderOIDC  = "oidc"
+	AuthProviderLDAP  = "ldap"
+)
+
 // LoginRequest
 type LoginRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
@@ -43,12 +57,12 @@ type LoginRequest struct {
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required,max=128"` // 复杂度由密码策略校验
 }
 
 type ChangePasswordRequest struct {
 	OldPassword string `json:"old_password" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=6"`
+	NewPassword string `json:"new_password" binding:"required,max=128"`
 }
 
 type UpdateProfileRequest struct {
@@ -63,12 +77,25 @@ type UserResponse struct {
 	IsActive  bool       `json:"is_active"`
 	LastLogin *time.Time `json:"last_login"`
 	CreatedAt time.Time  `json:"created_at"`
+	// TwoFactorEnabled 是否已启用 TOTP 两步验证
+	TwoFactorEnabled bool `json:"two_factor_enabled"`
+	// MustChangePassword 需要先修改密码才能使用其他功能
+	MustChangePassword bool `json:"must_change_password"`
 }
 
+// LoginResponse 登录结果。需要两步验证时不包含令牌，客户端使用 MFAToken 完成第二步
 type LoginResponse struct {
-	Token     string       `json:"token"`
-	ExpiresAt time.Time    `json:"expires_at"`
-	User      UserResponse `json:"user"`
+	Token            string       `json:"token,omitempty"`
+	ExpiresAt        time.Time    `json:"expires_at"`
+	RefreshToken     string       `json:"refresh_token,omitempty"`
+	RefreshExpiresAt time.Time    `json:"refresh_expires_at"`
+	User             UserResponse `json:"user"`
+
+	// TwoFactorRequired 已启用两步验证，需要提交验证码或恢复码
+	TwoFactorRequired bool `json:"two_factor_required,omitempty"`
+	// TwoFactorSetupRequired 安全策略要求该用户启用两步验证，需要先完成绑定
+	TwoFactorSetupRequired bool   `json:"two_factor_setup_required,omitempty"`
+	MFAToken               string `json:"mfa_token,omitempty"`
 }
 
 // TableName 指定表名
@@ -102,9 +129,17 @@ func (u *User) ToResponse() UserResponse {
 		IsActive:  u.IsActive,
 		LastLogin: u.LastLogin,
 		CreatedAt: u.CreatedAt,
+
+		TwoFactorEnabled:   u.TOTPEnabled,
+		MustChangePassword: u.MustChangePassword,
 	}
 }
 
+// IsExternal 是否为外部身份源 (OIDC、LDAP) 的账号，这类账号的密码不由本系统管理
+func (u *User) IsExternal() bool {
+	return u.AuthProvider != "" && u.AuthProvider != AuthProviderLocal
+}
+
 // IsAdmin 检查是否为管理员
 func (u *User) IsAdmin() bool {
 	return u.Role == "admin"
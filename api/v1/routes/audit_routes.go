@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/ciliverse/cilikube/api/v1/handlers"
+	"github.com/ciliverse/cilikube/pkg/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAuditRoutes 注册审计日志路由，除 Casbin 校验外还要求管理员身份
+func RegisterAuditRoutes(router *gin.RouterGroup, handler *handlers.AuditHandler) {
+	auditGroup := router.Group("/audit", auth.AdminRequiredMiddleware())
+	{
+		auditGroup.GET("/events", handler.ListAuditEvents)
+		auditGroup.GET("/events/export", handler.ExportAuditEvents)
+	}
+}
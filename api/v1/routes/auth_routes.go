@@ -6,15 +6,19 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupAuthRoutes(router *gin.Engine) {
-	authHandler := handlers.NewAuthHandler()
-
+func SetupAuthRoutes(router *gin.Engine, authHandler *handlers.AuthHandler) {
 	// 认证路由组
 	authGroup := router.Group("/api/v1/auth")
 	{
 		// 公开路由（不需要认证）
 		authGroup.POST("/login", authHandler.Login)
 		authGroup.POST("/register", authHandler.Register)
+		authGroup.POST("/refresh", authHandler.RefreshToken)
+		authGroup.POST("/login/2fa", authHandler.VerifyTwoFactorLogin)
+		authGroup.POST("/login/2fa/setup", authHandler.BeginTwoFactorEnrollment)
+		authGroup.POST("/login/2fa/enable", authHandler.CompleteTwoFactorEnrollment)
+		authGroup.GET("/oidc/login", authHandler.OIDCLogin)
+		authGroup.GET("/oidc/callback", authHandler.OIDCCallback)
 
 		// 需要认证的路由
 		authenticated := authGroup.Group("")
@@ -24,6 +28,16 @@ func SetupAuthRoutes(router *gin.Engine) {
 			authenticated.PUT("/profile", authHandler.UpdateProfile)
 			authenticated.POST("/change-password", authHandler.ChangePassword)
 			authenticated.POST("/logout", authHandler.Logout)
+			authenticated.POST("/logout-all", authHandler.LogoutAll)
+			authenticated.GET("/sessions", authHandler.ListSessions)
+			authenticated.DELETE("/sessions/:id", authHandler.RevokeSession)
+			authenticated.GET("/tokens", authHandler.ListAPITokens)
+			authenticated.POST("/tokens", authHandler.CreateAPIToken)
+			authenticated.DELETE("/tokens/:id", authHandler.RevokeAPIToken)
+			authenticated.POST("/2fa/setup", authHandler.BeginTOTPSetup)
+			authenticated.POST("/2fa/enable", authHandler.EnableTOTP)
+			authenticated.POST("/2fa/disable", authHandler.DisableTOTP)
+			authenticated.POST("/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
 		}
 
 		// 管理员专用路由
@@ -33,6 +47,10 @@ func SetupAuthRoutes(router *gin.Engine) {
 			admin.GET("/users", authHandler.GetUserList)
 			admin.PUT("/users/:id/status", authHandler.UpdateUserStatus)
 			admin.DELETE("/users/:id", authHandler.DeleteUser)
+			admin.DELETE("/users/:id/2fa", authHandler.ResetTwoFactor)
+			admin.POST("/users/:id/unlock", authHandler.UnlockUser)
+			admin.GET("/security-policy", authHandler.GetSecurityPolicy)
+			admin.PUT("/security-policy", authHandler.UpdateSecurityPolicy)
 		}
 	}
 }
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"github.com/ciliverse/cilikube/api/v1/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterCertificateRoutes 注册证书过期清单路由
+func RegisterCertificateRoutes(router *gin.RouterGroup, handler *handlers.CertificateHandler) {
+	router.GET("/certificates", handler.ListCertificates)
+	router.GET("/namespaces/:namespace/certificates", handler.ListNamespaceCertificates)
+}
@@ -13,8 +13,20 @@ func RegisterNodeRoutes(router *gin.RouterGroup, handler *handlers.NodeHandler)
 		nodeGroup.GET("", handler.ListNodes)
 		nodeGroup.POST("", handler.CreateNode)
 		nodeGroup.GET("/:name", handler.GetNode)
+		nodeGroup.GET("/:name/detail", handler.GetNodeDetail)
 		nodeGroup.PUT("/:name", handler.UpdateNode)
 		nodeGroup.DELETE("/:name", handler.DeleteNode)
+
+		// 调度与维护操作
+		nodeGroup.POST("/:name/cordon", handler.CordonNode)
+		nodeGroup.POST("/:name/uncordon", handler.UncordonNode)
+		nodeGroup.POST("/:name/drain", handler.DrainNode) // SSE 推送驱逐进度
+
+		// 标签与污点管理
+		nodeGroup.PATCH("/:name/labels", handler.UpdateNodeLabels)
+		nodeGroup.PATCH("/:name/taints", handler.UpdateNodeTaints)
+		nodeGroup.PATCH("/batch/labels", handler.BatchUpdateNodeLabels) // 按名称列表或 selector 批量修改
+		nodeGroup.PATCH("/batch/taints", handler.BatchUpdateNodeTaints)
 	}
 
 	// Watch端点
@@ -26,10 +26,13 @@ func RegisterPodRoutes(router *gin.RouterGroup, handler *handlers.PodHandler) {
 				podNameGroup.DELETE("", handler.DeletePod) // Delete Pod
 
 				// --- New Endpoints ---
-				podNameGroup.GET("/logs", handler.GetPodLogs)    // Get Pod Logs
-				podNameGroup.GET("/exec", handler.ExecIntoPod)   // Execute command in Pod (WebSocket)
-				podNameGroup.GET("/yaml", handler.GetPodYAML)    // Get Pod as YAML
-				podNameGroup.PUT("/yaml", handler.UpdatePodYAML) // Update Pod from YAML
+				podNameGroup.GET("/logs", handler.GetPodLogs)             // Get Pod Logs
+				podNameGroup.GET("/exec", handler.ExecIntoPod)            // Execute command in Pod (WebSocket)
+				podNameGroup.GET("/yaml", handler.GetPodYAML)             // Get Pod as YAML
+				podNameGroup.PUT("/yaml", handler.UpdatePodYAML)          // Update Pod from YAML
+				podNameGroup.GET("/files", handler.DownloadPodFile)       // Download file/dir from container (tar)
+				podNameGroup.POST("/files", handler.UploadPodFile)        // Upload file/tar into container
+				podNameGroup.POST("/debug", handler.CreateDebugContainer) // Add ephemeral debug container
 			}
 		}
 
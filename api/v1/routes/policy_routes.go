@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/ciliverse/cilikube/api/v1/handlers"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterPolicyRoutes 注册 Casbin 策略管理路由，权限由 Casbin 中间件按 policies 资源校验
+func RegisterPolicyRoutes(router *gin.RouterGroup, handler *handlers.PolicyHandler) {
+	policyGroup := router.Group("/policies")
+	{
+		policyGroup.GET("", handler.ListPolicies)
+		policyGroup.PATCH("", handler.UpdatePolicies)
+		policyGroup.POST("/preview", handler.PreviewPolicies)
+		policyGroup.GET("/templates", handler.ListRoleTemplates)
+
+		policyGroup.GET("/roles", handler.ListRoles)
+		policyGroup.POST("/roles", handler.CreateRole)
+		policyGroup.DELETE("/roles/:role", handler.DeleteRole)
+
+		policyGroup.POST("/assignments", handler.AssignRole)
+		policyGroup.DELETE("/assignments", handler.UnassignRole)
+	}
+}
@@ -1,6 +1,9 @@
 package routes
 
 import (
+	"net/http"
+	"strings"
+
 	"github.com/ciliverse/cilikube/api/v1/handlers"
 	"github.com/gin-gonic/gin"
 )
@@ -12,34 +15,80 @@ func RegisterRbacRoutes(router *gin.RouterGroup, handler *handlers.RbacHandler)
 	rolesRouter := rbacRouter.Group("/roles")
 	{
 		rolesRouter.GET("", handler.ListRoles)
+		rolesRouter.POST("", handler.CreateRole)
 		rolesRouter.GET("/:name", handler.GetRole)
+		rolesRouter.PUT("/:name", handler.UpdateRole)
+		rolesRouter.DELETE("/:name", handler.DeleteRole)
 	}
 
 	// RolesBinding
 	roleBindingsRouter := rbacRouter.Group("/roleBindings")
 	{
 		roleBindingsRouter.GET("", handler.ListRoleBindings)
+		roleBindingsRouter.POST("", handler.CreateRoleBinding)
 		roleBindingsRouter.GET("/:name", handler.GetRoleBindings)
+		roleBindingsRouter.PUT("/:name", handler.UpdateRoleBinding)
+		roleBindingsRouter.DELETE("/:name", handler.DeleteRoleBinding)
 	}
 
+	// ServiceAccount
+	serviceAccountsRouter := rbacRouter.Group("/serviceAccounts")
+	{
+		serviceAccountsRouter.GET("", handler.ListServiceAccounts)
+		serviceAccountsRouter.POST("", handler.CreateServiceAccount)
+		serviceAccountsRouter.GET("/:name", handler.GetServiceAccounts)
+		serviceAccountsRouter.PUT("/:name", handler.UpdateServiceAccount)
+		serviceAccountsRouter.DELETE("/:name", handler.DeleteServiceAccount)
+		serviceAccountsRouter.POST("/:name/kubeconfig", handler.GenerateServiceAccountKubeconfig) // 签发令牌并生成 kubeconfig
+	}
+
+	clusterRbacRouter := router.Group("/rbac")
+
+	// 集群级资源旧路径，重定向到 /rbac/clusterRoles 等路径，使授权在集群级的域中进行，
+	// 避免只拥有命名空间权限的用户借旧路径读取 ClusterRole
+	legacyRedirect := redirectClusterRbac(clusterRbacRouter.BasePath())
+	rbacRouter.GET("/clusterRoles", legacyRedirect)
+	rbacRouter.GET("/clusterRoles/:name", legacyRedirect)
+	rbacRouter.GET("/clusterRoleBindings", legacyRedirect)
+	rbacRouter.GET("/clusterRoleBindings/:name", legacyRedirect)
+
 	// ClusterRoles
-	clusterRolesRouter := rbacRouter.Group("/clusterRoles")
+	clusterRolesRouter := clusterRbacRouter.Group("/clusterRoles")
 	{
 		clusterRolesRouter.GET("", handler.ListClusterRoles)
+		clusterRolesRouter.POST("", handler.CreateClusterRole)
 		clusterRolesRouter.GET("/:name", handler.GetClusterRoles)
+		clusterRolesRouter.PUT("/:name", handler.UpdateClusterRole)
+		clusterRolesRouter.DELETE("/:name", handler.DeleteClusterRole)
 	}
 
 	// ClusterRolesBinding
-	clusterRoleBindingsRouter := rbacRouter.Group("/clusterRoleBindings")
+	clusterRoleBindingsRouter := clusterRbacRouter.Group("/clusterRoleBindings")
 	{
 		clusterRoleBindingsRouter.GET("", handler.ListClusterRoleBindings)
+		clusterRoleBindingsRouter.POST("", handler.CreateClusterRoleBinding)
 		clusterRoleBindingsRouter.GET("/:name", handler.GetClusterRoleBindings)
+		clusterRoleBindingsRouter.PUT("/:name", handler.UpdateClusterRoleBinding)
+		clusterRoleBindingsRouter.DELETE("/:name", handler.DeleteClusterRoleBinding)
 	}
 
-	// ServiceAccount
-	serviceAccountsRouter := rbacRouter.Group("/serviceAccounts")
-	{
-		serviceAccountsRouter.GET("", handler.ListServiceAccounts)
-		serviceAccountsRouter.GET("/:name", handler.GetServiceAccounts)
+	// 访问审查：SubjectAccessReview (can-i) 和基于模拟身份的 SelfSubjectRulesReview (权限列表)
+	clusterRbacRouter.POST("/access-review", handler.ReviewAccess)
+	clusterRbacRouter.POST("/rules-review", handler.ReviewRules)
+
+	// 有效权限矩阵与反向查询，仅基于 RBAC 绑定计算
+	clusterRbacRouter.GET("/permissions", handler.GetPermissionMatrix)
+	clusterRbacRouter.GET("/who-can", handler.WhoCan)
+}
+
+// redirectClusterRbac 将 /namespaces/:namespace/rbac/... 重定向到 basePath 下的同名路径，保留查询参数
+func redirectClusterRbac(basePath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, rest, _ := strings.Cut(c.Request.URL.Path, "/rbac/")
+		target := basePath + "/" + rest
+		if c.Request.URL.RawQuery != "" {
+			target += "?" + c.Request.URL.RawQuery
+		}
+		c.Redirect(http.StatusPermanentRedirect, target)
 	}
 }
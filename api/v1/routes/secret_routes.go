@@ -12,9 +12,20 @@ func RegisterSecretRoutes(router *gin.RouterGroup, handler *handlers.SecretHandl
 	{
 		secretGroup.GET("", handler.ListSecrets)
 		secretGroup.POST("", handler.CreateSecret)
+		// 按类型组装数据的创建接口，不需要调用方自己拼 base64
+		secretGroup.POST("/dockerconfigjson", handler.CreateDockerConfigSecret)
+		secretGroup.POST("/tls", handler.CreateTLSSecret)
+		secretGroup.POST("/basic-auth", handler.CreateBasicAuthSecret)
 		secretGroup.GET("/:name", handler.GetSecret)
 		secretGroup.PUT("/:name", handler.UpdateSecret)
 		secretGroup.DELETE("/:name", handler.DeleteSecret)
+		secretGroup.GET("/:name/yaml", handler.GetSecretYAML)
+
+		// 单个键的查看和编辑，不需要回传整个 Secret
+		secretGroup.GET("/:name/keys/:key/reveal", handler.RevealSecretKey)
+		secretGroup.PUT("/:name/keys/:key", handler.SetSecretKey)
+		secretGroup.PATCH("/:name/keys/:key", handler.RenameSecretKey)
+		secretGroup.DELETE("/:name/keys/:key", handler.DeleteSecretKey)
 	}
 
 	// // Watch端点
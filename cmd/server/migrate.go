@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/ciliverse/cilikube/configs"
+	"github.com/ciliverse/cilikube/pkg/database"
+)
+
+const migrateUsage = `用法: cilikube [-config 配置文件] migrate <命令>
+
+命令:
+  status          查看每个迁移的执行状态
+  up              执行所有未执行的迁移
+  down [n]        回滚最近执行的 n 个迁移，默认 1 个
+  to <version>    迁移到指定版本，0 表示回滚全部迁移`
+
+// runMigrate 执行 migrate 子命令，返回进程退出码
+func runMigrate(cfg *configs.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	if !cfg.Database.Enabled {
+		log.Println("数据库未启用 (database.enabled=false)，无需迁移。")
+		return 1
+	}
+	if err := database.InitDatabase(); err != nil {
+		log.Printf("数据库连接失败: %v", err)
+		return 1
+	}
+	defer database.CloseDatabase()
+
+	var err error
+	switch args[0] {
+	case "status":
+		err = printMigrationStatus()
+	case "up":
+		err = database.MigrateTo(database.DB, database.LatestVersion())
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				log.Printf("无效的回滚数量: %s", args[1])
+				return 2
+			}
+		}
+		err = database.Rollback(database.DB, steps)
+	case "to":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			log.Printf("无效的版本号: %s", args[1])
+			return 2
+		}
+		err = database.MigrateTo(database.DB, version)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	if err != nil {
+		log.Printf("迁移失败: %v", err)
+		return 1
+	}
+	if args[0] != "status" {
+		version, _ := database.CurrentVersion(database.DB)
+		log.Printf("迁移完成，当前数据库版本 %d (程序最新版本 %d)", version, database.LatestVersion())
+	}
+	return 0
+}
+
+func printMigrationStatus() error {
+	statuses, err := database.MigrationStatuses(database.DB)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		state, appliedAt := "pending", ""
+		if s.Applied {
+			state, appliedAt = "applied", s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if s.Unknown {
+			state = "unknown (newer binary)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
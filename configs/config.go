@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -15,6 +16,9 @@ type Config struct {
 	Installer  InstallerConfig  `yaml:"installer" json:"installer"`
 	Database   DatabaseConfig   `yaml:"database" json:"database"`
 	JWT        JWTConfig        `yaml:"jwt" json:"jwt"`
+	OIDC       OIDCConfig       `yaml:"oidc" json:"oidc"`
+	LDAP       LDAPConfig       `yaml:"ldap" json:"ldap"`
+	Security   SecurityConfig   `yaml:"security" json:"security"`
 	Clusters   []ClusterInfo    `yaml:"clusters" json:"clusters"`
 }
 
@@ -27,7 +31,19 @@ type ServerConfig struct {
 }
 
 type KubernetesConfig struct {
-	Kubeconfig string `yaml:"kubeconfig" json:"kubeconfig"`
+	Kubeconfig    string              `yaml:"kubeconfig" json:"kubeconfig"`
+	Impersonation ImpersonationConfig `yaml:"impersonation" json:"impersonation"`
+}
+
+// ImpersonationConfig 开启后 API 请求以登录用户的身份 (Impersonate-User/Group) 访问 API Server，
+// 由集群自身的 RBAC 授权，审计日志中也能看到真实用户。kubeconfig 中的账号需要拥有 impersonate 权限
+type ImpersonationConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// UserPrefix 和 GroupPrefix 加在模拟的用户名和组名前，避免与集群内其他身份冲突
+	UserPrefix  string `yaml:"user_prefix" json:"user_prefix"`
+	GroupPrefix string `yaml:"group_prefix" json:"group_prefix"`
+	// Groups 所有用户额外附加的组 (不加前缀)
+	Groups []string `yaml:"groups" json:"groups"`
 }
 
 type InstallerConfig struct {
@@ -36,20 +52,104 @@ type InstallerConfig struct {
 	DownloadDir    string `yaml:"downloadDir" json:"downloadDir"`
 }
 
+// 支持的数据库类型
+const (
+	DatabaseTypeMySQL    = "mysql"
+	DatabaseTypePostgres = "postgres"
+	DatabaseTypeSQLite   = "sqlite"
+)
+
 type DatabaseConfig struct {
-	Enabled  bool   `yaml:"enabled" json:"enabled"`
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Type 数据库类型：mysql (默认)、postgres 或 sqlite
+	Type     string `yaml:"type" json:"type"`
 	Host     string `yaml:"host" json:"host"`
 	Port     int    `yaml:"port" json:"port"`
 	Username string `yaml:"username" json:"username"`
 	Password string `yaml:"password" json:"password"`
 	Database string `yaml:"database" json:"database"`
-	Charset  string `yaml:"charset" json:"charset"`
+	Charset  string `yaml:"charset" json:"charset"` // 仅 MySQL
+	SSLMode  string `yaml:"sslmode" json:"sslmode"` // 仅 PostgreSQL
+	File     string `yaml:"file" json:"file"`       // 仅 SQLite，数据库文件路径
 }
 
 type JWTConfig struct {
 	SecretKey      string        `yaml:"secret_key" json:"secret_key"`
-	ExpireDuration time.Duration `yaml:"expire_duration" json:"expire_duration"`
+	ExpireDuration time.Duration `yaml:"expire_duration" json:"expire_duration"` // access token 有效期，应尽量短
 	Issuer         string        `yaml:"issuer" json:"issuer"`
+	// RefreshExpireDuration 刷新令牌有效期，每次刷新都会轮换并重新计时
+	RefreshExpireDuration time.Duration `yaml:"refresh_expire_duration" json:"refresh_expire_duration"`
+}
+
+// OIDCConfig OpenID Connect 单点登录配置 (授权码模式 + PKCE)
+type OIDCConfig struct {
+	Enabled      bool     `yaml:"enabled" json:"enabled"`
+	IssuerURL    string   `yaml:"issuer_url" json:"issuer_url"` // 用于发现 /.well-known/openid-configuration
+	ClientID     string   `yaml:"client_id" json:"client_id"`
+	ClientSecret string   `yaml:"client_secret" json:"-"`
+	RedirectURL  string   `yaml:"redirect_url" json:"redirect_url"` // 指向 /api/v1/auth/oidc/callback
+	Scopes       []string `yaml:"scopes" json:"scopes"`
+	// FrontendURL 登录完成后跳转的前端地址，令牌放在 URL fragment 中；为空时回调直接返回 JSON
+	FrontendURL   string `yaml:"frontend_url" json:"frontend_url"`
+	UsernameClaim string `yaml:"username_claim" json:"username_claim"`
+	GroupsClaim   string `yaml:"groups_claim" json:"groups_claim"`
+	// GroupRoles 按顺序匹配 IdP 分组，第一个匹配的分组决定用户的 Casbin 角色
+	GroupRoles  []GroupRole `yaml:"group_roles" json:"group_roles"`
+	DefaultRole string      `yaml:"default_role" json:"default_role"` // 没有匹配的分组时使用的角色
+}
+
+// SecurityConfig 登录防暴力破解和本地账号密码策略
+type SecurityConfig struct {
+	Login    LoginProtectionConfig `yaml:"login" json:"login"`
+	Password PasswordPolicyConfig  `yaml:"password" json:"password"`
+}
+
+// LoginProtectionConfig 按用户名和客户端 IP 分别统计失败次数，达到上限后锁定，每次锁定时长翻倍
+type LoginProtectionConfig struct {
+	MaxAttempts   int           `yaml:"max_attempts" json:"max_attempts"`       // 同一用户名允许连续失败的次数
+	IPMaxAttempts int           `yaml:"ip_max_attempts" json:"ip_max_attempts"` // 同一 IP 允许连续失败的次数
+	Window        time.Duration `yaml:"window" json:"window"`                   // 超过该时间没有失败则重新计数
+	LockDuration  time.Duration `yaml:"lock_duration" json:"lock_duration"`     // 第一次锁定的时长
+	MaxLockout    time.Duration `yaml:"max_lockout" json:"max_lockout"`         // 锁定时长上限
+}
+
+// PasswordPolicyConfig 本地账号的密码复杂度和历史规则
+type PasswordPolicyConfig struct {
+	MinLength int `yaml:"min_length" json:"min_length"`
+	// MinCharClasses 至少包含的字符类型数 (大写字母、小写字母、数字、符号)
+	MinCharClasses int `yaml:"min_char_classes" json:"min_char_classes"`
+	// HistorySize 新密码不能与最近使用过的多少个密码 (含当前密码) 相同，0 表示只检查当前密码
+	HistorySize int `yaml:"history_size" json:"history_size"`
+}
+
+// GroupRole 外部身份源 (OIDC、LDAP) 中的分组到 Casbin 角色的映射
+type GroupRole struct {
+	Group string `yaml:"group" json:"group"`
+	Role  string `yaml:"role" json:"role"`
+}
+
+// LDAPConfig LDAP 认证配置：先用服务账号查找用户 DN，再以用户身份 bind 校验密码
+type LDAPConfig struct {
+	Enabled            bool   `yaml:"enabled" json:"enabled"`
+	URL                string `yaml:"url" json:"url"` // ldap://host:389 或 ldaps://host:636
+	StartTLS           bool   `yaml:"start_tls" json:"start_tls"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" json:"insecure_skip_verify"`
+	BindDN             string `yaml:"bind_dn" json:"bind_dn"`
+	BindPassword       string `yaml:"bind_password" json:"-"`
+	BaseDN             string `yaml:"base_dn" json:"base_dn"`
+	// UserFilter 查找用户的过滤器，%s 替换为转义后的用户名
+	UserFilter        string `yaml:"user_filter" json:"user_filter"`
+	UsernameAttribute string `yaml:"username_attribute" json:"username_attribute"`
+	EmailAttribute    string `yaml:"email_attribute" json:"email_attribute"`
+	// GroupBaseDN 和 GroupFilter 查找用户所属分组，%s 替换为转义后的用户 DN
+	GroupBaseDN        string `yaml:"group_base_dn" json:"group_base_dn"`
+	GroupFilter        string `yaml:"group_filter" json:"group_filter"`
+	GroupNameAttribute string `yaml:"group_name_attribute" json:"group_name_attribute"`
+	// DisabledFilter 匹配目录中已禁用账号的过滤器，如 AD 的 (userAccountControl:1.2.840.113556.1.4.803:=2)
+	DisabledFilter string        `yaml:"disabled_filter" json:"disabled_filter"`
+	SyncInterval   time.Duration `yaml:"sync_interval" json:"sync_interval"` // 同步禁用账号的间隔，0 表示不同步
+	GroupRoles     []GroupRole   `yaml:"group_roles" json:"group_roles"`
+	DefaultRole    string        `yaml:"default_role" json:"default_role"`
 }
 
 type ClusterInfo struct {
@@ -119,18 +219,6 @@ func setDefaults() {
 	if GlobalConfig.Server.WriteTimeout == 0 {
 		GlobalConfig.Server.WriteTimeout = 30 // 默认 30 秒
 	}
-	if GlobalConfig.Database.Host == "" {
-		GlobalConfig.Database.Host = "localhost"
-	}
-	if GlobalConfig.Database.Port == 0 {
-		GlobalConfig.Database.Port = 3306
-	}
-	if GlobalConfig.Database.Username == "" {
-		GlobalConfig.Database.Username = "root"
-	}
-	if GlobalConfig.Database.Charset == "" {
-		GlobalConfig.Database.Charset = "utf8mb4"
-	}
 	if GlobalConfig.JWT.SecretKey == "" {
 		GlobalConfig.JWT.SecretKey = os.Getenv("JWT_SECRET")
 		if GlobalConfig.JWT.SecretKey == "" {
@@ -138,11 +226,45 @@ func setDefaults() {
 		}
 	}
 	if GlobalConfig.JWT.ExpireDuration == 0 {
-		GlobalConfig.JWT.ExpireDuration = 24 * time.Hour
+		GlobalConfig.JWT.ExpireDuration = 15 * time.Minute
+	}
+	if GlobalConfig.JWT.RefreshExpireDuration == 0 {
+		GlobalConfig.JWT.RefreshExpireDuration = 7 * 24 * time.Hour
+	}
+	if len(GlobalConfig.OIDC.Scopes) == 0 {
+		GlobalConfig.OIDC.Scopes = []string{"openid", "profile", "email", "groups"}
+	}
+	if GlobalConfig.OIDC.UsernameClaim == "" {
+		GlobalConfig.OIDC.UsernameClaim = "preferred_username"
+	}
+	if GlobalConfig.OIDC.GroupsClaim == "" {
+		GlobalConfig.OIDC.GroupsClaim = "groups"
+	}
+	if GlobalConfig.OIDC.DefaultRole == "" {
+		GlobalConfig.OIDC.DefaultRole = "user"
+	}
+	if GlobalConfig.LDAP.UserFilter == "" {
+		GlobalConfig.LDAP.UserFilter = "(uid=%s)"
+	}
+	if GlobalConfig.LDAP.UsernameAttribute == "" {
+		GlobalConfig.LDAP.UsernameAttribute = "uid"
+	}
+	if GlobalConfig.LDAP.EmailAttribute == "" {
+		GlobalConfig.LDAP.EmailAttribute = "mail"
+	}
+	if GlobalConfig.LDAP.GroupFilter == "" {
+		GlobalConfig.LDAP.GroupFilter = "(member=%s)"
+	}
+	if GlobalConfig.LDAP.GroupNameAttribute == "" {
+		GlobalConfig.LDAP.GroupNameAttribute = "cn"
+	}
+	if GlobalConfig.LDAP.DefaultRole == "" {
+		GlobalConfig.LDAP.DefaultRole = "user"
 	}
 	if GlobalConfig.JWT.Issuer == "" {
 		GlobalConfig.JWT.Issuer = "cilikube"
 	}
+	GlobalConfig.Security.setDefaults()
 	if GlobalConfig.Installer.MinikubeDriver == "" {
 		GlobalConfig.Installer.MinikubeDriver = "docker"
 	}
@@ -156,35 +278,93 @@ func setDefaults() {
 			GlobalConfig.Kubernetes.Kubeconfig = filepath.Join(os.Getenv("HOME"), ".kube", "config")
 		}
 	}
-	// 数据库默认值
-	if GlobalConfig.Database.Enabled {
-		if GlobalConfig.Database.Host == "" {
-			GlobalConfig.Database.Host = "localhost"
-		}
-		if GlobalConfig.Database.Port == 0 {
-			GlobalConfig.Database.Port = 3306
-		}
-		if GlobalConfig.Database.Username == "" {
-			GlobalConfig.Database.Username = "root"
-		}
-		if GlobalConfig.Database.Database == "" {
-			GlobalConfig.Database.Database = "cilikube"
+	GlobalConfig.Database.setDefaults()
+}
+
+func (c *DatabaseConfig) setDefaults() {
+	if c.Type == "" {
+		c.Type = DatabaseTypeMySQL
+	}
+	if c.Type == DatabaseTypeSQLite {
+		if c.File == "" {
+			c.File = "cilikube.db"
 		}
-		if GlobalConfig.Database.Password == "" {
-			GlobalConfig.Database.Password = "cilikube-password-change-in-production"
+		return
+	}
+	if c.Host == "" {
+		c.Host = "localhost"
+	}
+	if c.Port == 0 {
+		if c.Type == DatabaseTypePostgres {
+			c.Port = 5432
+		} else {
+			c.Port = 3306
 		}
-		if GlobalConfig.Database.Charset == "" {
-			GlobalConfig.Database.Charset = "utf8mb4"
+	}
+	if c.Username == "" {
+		if c.Type == DatabaseTypePostgres {
+			c.Username = "postgres"
+		} else {
+			c.Username = "root"
 		}
 	}
+	if c.Database == "" {
+		c.Database = "cilikube"
+	}
+	if c.Enabled && c.Password == "" {
+		c.Password = "cilikube-password-change-in-production"
+	}
+	if c.Charset == "" {
+		c.Charset = "utf8mb4"
+	}
+	if c.SSLMode == "" {
+		c.SSLMode = "disable"
+	}
 }
 
+// GetDSN 按数据库类型生成连接字符串
 func (c *Config) GetDSN() string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=true",
-		c.Database.Username,
-		c.Database.Password,
-		c.Database.Host,
-		c.Database.Port,
-		c.Database.Database,
-		c.Database.Charset)
+	db := c.Database
+	switch db.Type {
+	case DatabaseTypePostgres:
+		return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s TimeZone=UTC",
+			db.Host, db.Port, db.Username, db.Password, db.Database, db.SSLMode)
+	case DatabaseTypeSQLite:
+		// 开启外键约束；等待写锁而不是立即返回 database is locked
+		separator := "?"
+		if strings.Contains(db.File, "?") {
+			separator = "&"
+		}
+		return db.File + separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	default:
+		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=true",
+			db.Username, db.Password, db.Host, db.Port, db.Database, db.Charset)
+	}
+}
+
+func (c *SecurityConfig) setDefaults() {
+	if c.Login.MaxAttempts == 0 {
+		c.Login.MaxAttempts = 5
+	}
+	if c.Login.IPMaxAttempts == 0 {
+		c.Login.IPMaxAttempts = 20
+	}
+	if c.Login.Window == 0 {
+		c.Login.Window = 15 * time.Minute
+	}
+	if c.Login.LockDuration == 0 {
+		c.Login.LockDuration = time.Minute
+	}
+	if c.Login.MaxLockout == 0 {
+		c.Login.MaxLockout = time.Hour
+	}
+	if c.Password.MinLength == 0 {
+		c.Password.MinLength = 8
+	}
+	if c.Password.MinCharClasses == 0 {
+		c.Password.MinCharClasses = 3
+	}
+	if c.Password.HistorySize == 0 {
+		c.Password.HistorySize = 5
+	}
 }
@@ -0,0 +1,85 @@
+package initialization
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	router := setupAuthTestRouter(t)
+	adminToken := login(t, router, "admin", "admin123")
+	viewerToken := login(t, router, "viewer", "viewer123")
+	start := time.Now().Add(-time.Second)
+
+	w := doRequest(router, http.MethodPost, "/api/v1/nodes/node-1/cordon", adminToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doRequest(router, http.MethodPost, "/api/v1/nodes/node-1/cordon", viewerToken, nil)
+	require.Equal(t, http.StatusForbidden, w.Code)
+	// 只读请求和登录请求不记录
+	doRequest(router, http.MethodGet, "/api/v1/nodes", viewerToken, nil)
+
+	query := func(token string, params url.Values) models.AuditEventListResponse {
+		w := doRequest(router, http.MethodGet, "/api/v1/audit/events?"+params.Encode(), token, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp models.AuditEventListResponse
+		decodeData(t, w, &resp)
+		return resp
+	}
+
+	all := query(adminToken, url.Values{"action": {models.AuditActionAPIRequest}})
+	require.EqualValues(t, 2, all.Total)
+	denied := all.Items[0]
+	assert.Equal(t, "viewer", denied.Username)
+	assert.Equal(t, "user", denied.Role)
+	assert.Equal(t, "default", denied.Cluster)
+	assert.Equal(t, "create", denied.Verb)
+	assert.Equal(t, "nodes/cordon", denied.Resource)
+	assert.Equal(t, "node-1", denied.Name)
+	assert.Equal(t, http.StatusForbidden, denied.StatusCode)
+
+	failed := query(adminToken, url.Values{"failed": {"true"}})
+	require.EqualValues(t, 1, failed.Total)
+	assert.Equal(t, "viewer", failed.Items[0].Username)
+
+	byUser := query(adminToken, url.Values{"username": {"admin"}, "from": {start.UTC().Format(time.RFC3339)}})
+	require.EqualValues(t, 1, byUser.Total)
+	assert.Equal(t, http.StatusOK, byUser.Items[0].StatusCode)
+	future := query(adminToken, url.Values{"from": {time.Now().Add(time.Hour).UTC().Format(time.RFC3339)}})
+	assert.EqualValues(t, 0, future.Total)
+
+	w = doRequest(router, http.MethodGet, "/api/v1/audit/events?from=2030-01-01T00:00:00Z&to=2020-01-01T00:00:00Z", adminToken, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 导出
+	w = doRequest(router, http.MethodGet, "/api/v1/audit/events/export?format=csv&action=api.request", adminToken, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), ".csv")
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "username", records[0][2])
+	assert.Equal(t, "admin", records[1][2])
+
+	w = doRequest(router, http.MethodGet, "/api/v1/audit/events/export?format=json&username=viewer", adminToken, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var exported []models.AuditEvent
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &exported))
+	require.Len(t, exported, 1)
+	assert.Equal(t, "nodes/cordon", exported[0].Resource)
+
+	w = doRequest(router, http.MethodGet, "/api/v1/audit/events/export?format=xml", adminToken, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 只有管理员可以查看审计日志
+	w = doRequest(router, http.MethodGet, "/api/v1/audit/events", viewerToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
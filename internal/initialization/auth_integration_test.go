@@ -0,0 +1,342 @@
+package initialization
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/handlers"
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/configs"
+	"github.com/ciliverse/cilikube/internal/repository"
+	"github.com/ciliverse/cilikube/internal/service"
+	"github.com/ciliverse/cilikube/pkg/auth"
+	"github.com/ciliverse/cilikube/pkg/database"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// setupAuthTestRouter 使用内存 SQLite 数据库和 fake Kubernetes 客户端搭建完整的路由
+func setupAuthTestRouter(t *testing.T) *gin.Engine {
+	return setupAuthTestRouterWithConfig(t, nil)
+}
+
+// setupAuthTestRouterWithConfig 在搭建路由前允许调整配置
+func setupAuthTestRouterWithConfig(t *testing.T, configure func(cfg *configs.Config)) *gin.Engine {
+	return setupAuthTestRouterWithHandlers(t, configure, nil)
+}
+
+// setupAuthTestRouterWithHandlers 在默认 handler 的基础上由 customize 调整后再注册路由
+func setupAuthTestRouterWithHandlers(t *testing.T, configure func(cfg *configs.Config), customize func(h *AppHandlers)) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cfg := &configs.Config{
+		Server:   configs.ServerConfig{ActiveCluster: "default"},
+		Database: configs.DatabaseConfig{Enabled: true},
+		JWT:      configs.JWTConfig{SecretKey: "test-secret", ExpireDuration: time.Hour, RefreshExpireDuration: 24 * time.Hour, Issuer: "cilikube-test"},
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+	configs.GlobalConfig = cfg
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	database.DB = db
+	t.Cleanup(func() {
+		sqlDB.Close()
+		database.DB = nil
+	})
+
+	require.NoError(t, database.Migrate())
+	require.NoError(t, database.CreateDefaultAdmin())
+	// 大多数用例不关心初始密码流程，直接视为已修改过密码
+	require.NoError(t, db.Model(&models.User{}).Where("username = ?", "admin").Update("must_change_password", false).Error)
+	require.NoError(t, db.Create(&models.User{Username: "viewer", Email: "viewer@cilikube.com", Password: "viewer123", Role: "user", IsActive: true}).Error)
+	require.NoError(t, db.Create(&models.User{Username: "alice", Email: "alice@cilikube.com", Password: "alice123", Role: "team-a", IsActive: true}).Error)
+
+	e, err := auth.InitCasbin(db)
+	require.NoError(t, err)
+	// team-a 只能管理 default 集群中 team-a 命名空间内的资源
+	_, err = e.AddPolicy("team-a", "default/team-a", "*", "*")
+	require.NoError(t, err)
+
+	clientset := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}})
+	appHandlers := &AppHandlers{
+		AuthHandler: handlers.NewAuthHandler(service.NewAuthService(repository.NewGormUserRepository(db), db, e)),
+		NodeHandler: handlers.NewNodeHandler(service.NewNodeService(clientset)),
+		RbacHandler: handlers.NewRbacHandler(service.NewRbacService(clientset, nil)),
+	}
+	if customize != nil {
+		customize(appHandlers)
+	}
+	return SetupRouter(cfg, appHandlers, true, e)
+}
+
+func doRequest(router *gin.Engine, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func login(t *testing.T, router *gin.Engine, username, password string) string {
+	return loginResponse(t, router, username, password).Token
+}
+
+func loginResponse(t *testing.T, router *gin.Engine, username, password string) models.LoginResponse {
+	w := doRequest(router, http.MethodPost, "/api/v1/auth/login", "", models.LoginRequest{Username: username, Password: password})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	return decodeLoginResponse(t, w)
+}
+
+func decodeLoginResponse(t *testing.T, w *httptest.ResponseRecorder) models.LoginResponse {
+	return decodeLoginResponseBody(t, w.Body.String())
+}
+
+func decodeLoginResponseBody(t *testing.T, body string) models.LoginResponse {
+	var resp struct {
+		Data models.LoginResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &resp))
+	require.NotEmpty(t, resp.Data.Token)
+	require.NotEmpty(t, resp.Data.RefreshToken)
+	return resp.Data
+}
+
+// 测试登录 -> 获取 token -> 按角色允许或拒绝请求的完整流程
+func TestAuthPipeline(t *testing.T) {
+	router := setupAuthTestRouter(t)
+
+	// 未携带 token 的请求被 JWT 中间件拒绝
+	w := doRequest(router, http.MethodGet, "/api/v1/nodes", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 错误的密码无法登录
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/login", "", models.LoginRequest{Username: "admin", Password: "wrong-password"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 普通用户只有 GET 权限
+	userToken := login(t, router, "viewer", "viewer123")
+	w = doRequest(router, http.MethodGet, "/api/v1/nodes", userToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(router, http.MethodPost, "/api/v1/nodes/node-1/cordon", userToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// 管理员拥有全部权限
+	adminToken := login(t, router, "admin", "admin123")
+	w = doRequest(router, http.MethodPost, "/api/v1/nodes/node-1/cordon", adminToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// 认证路由使用同一套上下文键读取当前用户
+	w = doRequest(router, http.MethodGet, "/api/v1/auth/profile", userToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(router, http.MethodGet, "/api/v1/auth/users", userToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(router, http.MethodGet, "/api/v1/auth/users", adminToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 篡改过的 token 无效
+	w = doRequest(router, http.MethodGet, "/api/v1/nodes", adminToken+"x", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// 测试按命名空间授权：团队角色只能操作自己的命名空间
+func TestAuthPipelineNamespaceScoped(t *testing.T) {
+	router := setupAuthTestRouter(t)
+	token := login(t, router, "alice", "alice123")
+
+	sa := models.CreateServiceAccountRequest{Name: "ci"}
+	w := doRequest(router, http.MethodPost, "/api/v1/namespaces/team-a/rbac/serviceAccounts", token, sa)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = doRequest(router, http.MethodGet, "/api/v1/namespaces/team-a/rbac/serviceAccounts/ci", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 其他命名空间和集群级资源均无权限
+	w = doRequest(router, http.MethodPost, "/api/v1/namespaces/team-b/rbac/serviceAccounts", token, sa)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(router, http.MethodGet, "/api/v1/nodes", token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// 旧的命名空间路径重定向到集群级路径，在集群级的域中授权
+	w = doRequest(router, http.MethodGet, "/api/v1/namespaces/team-a/rbac/clusterRoles/view?watch=false", token, nil)
+	require.Equal(t, http.StatusPermanentRedirect, w.Code)
+	assert.Equal(t, "/api/v1/rbac/clusterRoles/view?watch=false", w.Header().Get("Location"))
+	w = doRequest(router, http.MethodGet, w.Header().Get("Location"), token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(router, http.MethodGet, "/api/v1/namespaces/team-a/rbac/clusterRoleBindings", token, nil)
+	require.Equal(t, http.StatusPermanentRedirect, w.Code)
+	w = doRequest(router, http.MethodGet, w.Header().Get("Location"), token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// 只有管理员可以通过策略管理接口为用户分配角色，分配后立即生效
+	assignment := models.RoleAssignmentRequest{Username: "alice", Role: auth.RoleNormalUser, Cluster: "default"}
+	w = doRequest(router, http.MethodPost, "/api/v1/policies/assignments", token, assignment)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	adminToken := login(t, router, "admin", "admin123")
+	w = doRequest(router, http.MethodPost, "/api/v1/policies/assignments", adminToken, assignment)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = doRequest(router, http.MethodGet, "/api/v1/nodes", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+// 测试刷新令牌轮换、重复使用检测、登出和登出所有会话
+func TestAuthSessions(t *testing.T) {
+	router := setupAuthTestRouter(t)
+
+	first := loginResponse(t, router, "viewer", "viewer123")
+	second := loginResponse(t, router, "viewer", "viewer123")
+
+	// 刷新后旧的 access token 和刷新令牌都失效
+	w := doRequest(router, http.MethodPost, "/api/v1/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: first.RefreshToken})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	rotated := decodeLoginResponse(t, w)
+	assert.NotEqual(t, first.RefreshToken, rotated.RefreshToken)
+	w = doRequest(router, http.MethodGet, "/api/v1/nodes", first.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(router, http.MethodGet, "/api/v1/nodes", rotated.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doRequest(router, http.MethodGet, "/api/v1/auth/sessions", rotated.Token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var sessions struct {
+		Data []models.SessionResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sessions))
+	require.Len(t, sessions.Data, 2)
+	assert.Equal(t, 1, countCurrent(sessions.Data))
+
+	// 重复使用已轮换的刷新令牌会吊销整个会话
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: first.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(router, http.MethodGet, "/api/v1/nodes", rotated.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: rotated.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 登出后 token 立即失效，其他会话不受影响
+	third := loginResponse(t, router, "viewer", "viewer123")
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/logout", third.Token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(router, http.MethodGet, "/api/v1/nodes", third.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(router, http.MethodGet, "/api/v1/nodes", second.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 登出所有会话
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/logout-all", second.Token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(router, http.MethodGet, "/api/v1/nodes", second.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: second.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAPITokens(t *testing.T) {
+	router := setupAuthTestRouter(t)
+	adminToken := login(t, router, "admin", "admin123")
+
+	createToken := func(req models.CreateAPITokenRequest) models.APITokenResponse {
+		w := doRequest(router, http.MethodPost, "/api/v1/auth/tokens", adminToken, req)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var resp struct {
+			Data models.APITokenResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.True(t, auth.IsAPIToken(resp.Data.Token))
+		return resp.Data
+	}
+
+	// 只读令牌只能执行读操作
+	readOnly := createToken(models.CreateAPITokenRequest{Name: "monitoring", Scope: models.TokenScopeReadOnly})
+	w := doRequest(router, http.MethodGet, "/api/v1/nodes", readOnly.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(router, http.MethodPost, "/api/v1/nodes/node-1/cordon", readOnly.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// 令牌只能限定为用户已拥有的角色
+	viewerToken := login(t, router, "viewer", "viewer123")
+	for _, role := range []string{auth.RoleSuperAdmin, "admin", "team-a"} {
+		w := doRequest(router, http.MethodPost, "/api/v1/auth/tokens", viewerToken, models.CreateAPITokenRequest{Name: "escalate", Roles: []string{role}})
+		assert.Equal(t, http.StatusBadRequest, w.Code, role)
+	}
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/tokens", viewerToken, models.CreateAPITokenRequest{Name: "viewer-ci", Roles: []string{"user", auth.RoleNormalUser}})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/tokens", adminToken, models.CreateAPITokenRequest{Name: "ci", Roles: []string{auth.RoleNormalUser}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 限定角色的令牌权限是用户权限与角色的交集
+	assignment := models.RoleAssignmentRequest{Username: "admin", Role: auth.RoleNormalUser, Cluster: "default"}
+	w = doRequest(router, http.MethodPost, "/api/v1/policies/assignments", adminToken, assignment)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	restricted := createToken(models.CreateAPITokenRequest{Name: "ci", Roles: []string{auth.RoleNormalUser}, ExpiresInDays: 7})
+	w = doRequest(router, http.MethodGet, "/api/v1/nodes", restricted.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(router, http.MethodPost, "/api/v1/nodes/node-1/cordon", restricted.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(router, http.MethodGet, "/api/v1/auth/users", restricted.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	full := createToken(models.CreateAPITokenRequest{Name: "deploy"})
+	w = doRequest(router, http.MethodPost, "/api/v1/nodes/node-1/cordon", full.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doRequest(router, http.MethodGet, "/api/v1/auth/users", full.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 令牌不能用来创建新的令牌，名称不能重复
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/tokens", full.Token, models.CreateAPITokenRequest{Name: "other"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/tokens", adminToken, models.CreateAPITokenRequest{Name: "deploy"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequest(router, http.MethodGet, "/api/v1/auth/tokens", adminToken, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Data []models.APITokenResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Data, 3)
+	for _, token := range list.Data {
+		assert.Empty(t, token.Token, "列表中不返回令牌明文")
+		assert.NotNil(t, token.LastUsedAt, "记录最后使用时间")
+	}
+
+	// 吊销后立即失效
+	w = doRequest(router, http.MethodDelete, fmt.Sprintf("/api/v1/auth/tokens/%d", full.ID), adminToken, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(router, http.MethodGet, "/api/v1/nodes", full.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(router, http.MethodDelete, fmt.Sprintf("/api/v1/auth/tokens/%d", full.ID), adminToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func countCurrent(sessions []models.SessionResponse) int {
+	count := 0
+	for _, session := range sessions {
+		if session.Current {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,62 @@
+package initialization
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/configs"
+	"github.com/ciliverse/cilikube/pkg/auth"
+	"github.com/ciliverse/cilikube/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseDialector(t *testing.T) {
+	for dbType, name := range map[string]string{
+		"":                           "mysql",
+		configs.DatabaseTypeMySQL:    "mysql",
+		configs.DatabaseTypePostgres: "postgres",
+		configs.DatabaseTypeSQLite:   "sqlite",
+	} {
+		cfg := &configs.Config{Database: configs.DatabaseConfig{Type: dbType}}
+		dialector, err := database.Dialector(cfg)
+		require.NoError(t, err, dbType)
+		assert.Equal(t, name, dialector.Name(), dbType)
+	}
+
+	_, err := database.Dialector(&configs.Config{Database: configs.DatabaseConfig{Type: "oracle"}})
+	assert.Error(t, err)
+
+	postgres := &configs.Config{Database: configs.DatabaseConfig{Type: configs.DatabaseTypePostgres, Host: "db", Port: 5432, Username: "u", Password: "p", Database: "cilikube", SSLMode: "require"}}
+	assert.Equal(t, "host=db port=5432 user=u password=p dbname=cilikube sslmode=require TimeZone=UTC", postgres.GetDSN())
+}
+
+// SQLite 文件数据库上跑通迁移、默认管理员和 Casbin 适配器
+func TestSQLiteDatabase(t *testing.T) {
+	previous := configs.GlobalConfig
+	configs.GlobalConfig = &configs.Config{Database: configs.DatabaseConfig{
+		Enabled: true,
+		Type:    configs.DatabaseTypeSQLite,
+		File:    filepath.Join(t.TempDir(), "cilikube.db"),
+	}}
+	t.Cleanup(func() {
+		database.CloseDatabase()
+		database.DB = nil
+		configs.GlobalConfig = previous
+	})
+
+	require.NoError(t, database.InitDatabase())
+	require.NoError(t, database.Migrate())
+	require.NoError(t, database.CreateDefaultAdmin())
+
+	var admin models.User
+	require.NoError(t, database.DB.Where("username = ?", "admin").First(&admin).Error)
+	assert.True(t, admin.MustChangePassword)
+
+	e, err := auth.InitCasbin(database.DB)
+	require.NoError(t, err)
+	allowed, err := e.Enforce("admin", "default/_", "nodes", "delete")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
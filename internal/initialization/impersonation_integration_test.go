@@ -0,0 +1,119 @@
+package initialization
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ciliverse/cilikube/api/v1/handlers"
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/configs"
+	"github.com/ciliverse/cilikube/internal/service"
+	"github.com/ciliverse/cilikube/pkg/auth"
+	"github.com/ciliverse/cilikube/pkg/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// impersonationRecorder 模拟 API Server，记录每个请求的模拟身份
+type impersonationRecorder struct {
+	mu     sync.Mutex
+	users  []string
+	groups [][]string
+}
+
+func (r *impersonationRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	r.users = append(r.users, req.Header.Get("Impersonate-User"))
+	r.groups = append(r.groups, req.Header.Values("Impersonate-Group"))
+	r.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"kind":"NodeList","apiVersion":"v1","items":[]}`))
+}
+
+func (r *impersonationRecorder) last() (string, []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.users[len(r.users)-1], r.groups[len(r.groups)-1]
+}
+
+func TestKubernetesImpersonation(t *testing.T) {
+	recorder := &impersonationRecorder{}
+	apiServer := httptest.NewServer(recorder)
+	t.Cleanup(apiServer.Close)
+
+	impersonation := configs.ImpersonationConfig{Enabled: true, UserPrefix: "cilikube:", GroupPrefix: "cilikube:", Groups: []string{"cilikube:users"}}
+	restConfig := &rest.Config{Host: apiServer.URL}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	require.NoError(t, err)
+
+	router := setupAuthTestRouterWithHandlers(t, nil, func(h *AppHandlers) {
+		h.NodeHandler = handlers.NewNodeHandler(service.NewNodeService(clientset))
+		h.Impersonator = k8s.NewImpersonator(restConfig, impersonation)
+	})
+
+	token := login(t, router, "viewer", "viewer123")
+	w := doRequest(router, http.MethodGet, "/api/v1/nodes", token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	user, groups := recorder.last()
+	assert.Equal(t, "cilikube:viewer", user)
+	assert.Equal(t, []string{"cilikube:user", "cilikube:users"}, groups)
+
+	// 令牌限定的角色包含用户角色时照常映射为组
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/tokens", token, models.CreateAPITokenRequest{Name: "ci", Scope: models.TokenScopeReadOnly, Roles: []string{"user"}})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct {
+		Token string `json:"token"`
+	}
+	decodeData(t, w, &created)
+	w = doRequest(router, http.MethodGet, "/api/v1/nodes", created.Token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	user, groups = recorder.last()
+	assert.Equal(t, "cilikube:viewer", user)
+	assert.Equal(t, []string{"cilikube:user", "cilikube:users"}, groups)
+
+	// 令牌限定的角色 (即使是 Casbin 中继承到的角色) 不会作为组发送给 API Server，只保留与用户角色的交集
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/tokens", token, models.CreateAPITokenRequest{Name: "scoped", Scope: models.TokenScopeReadOnly, Roles: []string{auth.RoleNormalUser}})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	decodeData(t, w, &created)
+	w = doRequest(router, http.MethodGet, "/api/v1/nodes", created.Token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	user, groups = recorder.last()
+	assert.Equal(t, "cilikube:viewer", user)
+	assert.Equal(t, []string{"cilikube:users"}, groups)
+
+	// 管理员的令牌限定为 super_admin 时同样不会出现 cilikube:super_admin 组
+	adminToken := login(t, router, "admin", "admin123")
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/tokens", adminToken, models.CreateAPITokenRequest{Name: "scoped", Roles: []string{auth.RoleSuperAdmin}})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	decodeData(t, w, &created)
+	w = doRequest(router, http.MethodGet, "/api/v1/nodes", created.Token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	user, groups = recorder.last()
+	assert.Equal(t, "cilikube:admin", user)
+	assert.Equal(t, []string{"cilikube:users"}, groups)
+}
+
+func TestImpersonatorRejectsSystemIdentity(t *testing.T) {
+	impersonator := k8s.NewImpersonator(&rest.Config{Host: "https://127.0.0.1:6443"}, configs.ImpersonationConfig{Enabled: true, Groups: []string{"system:authenticated"}})
+
+	_, _, err := impersonator.Identity("system:admin", []string{"user"})
+	assert.Error(t, err)
+	_, _, err = impersonator.Identity("bob", []string{"system:masters"})
+	assert.Error(t, err)
+
+	user, groups, err := impersonator.Identity("bob", []string{"user"})
+	require.NoError(t, err)
+	assert.Equal(t, "bob", user)
+	assert.Equal(t, []string{"system:authenticated", "user"}, groups)
+
+	first, err := impersonator.ClientFor("bob", []string{"user"})
+	require.NoError(t, err)
+	second, err := impersonator.ClientFor("bob", []string{"user"})
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+	assert.Equal(t, "bob", first.Config.Impersonate.UserName)
+}
@@ -1,15 +1,19 @@
 package initialization
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/casbin/casbin/v2"
 	"github.com/ciliverse/cilikube/api/v1/handlers"
+	"github.com/ciliverse/cilikube/api/v1/models"
 	"github.com/ciliverse/cilikube/api/v1/routes"
 	"github.com/ciliverse/cilikube/configs"
+	"github.com/ciliverse/cilikube/internal/repository"
 	"github.com/ciliverse/cilikube/internal/service"
+	"github.com/ciliverse/cilikube/pkg/audit"
 	"github.com/ciliverse/cilikube/pkg/auth"
 	"github.com/ciliverse/cilikube/pkg/database"
 	"github.com/ciliverse/cilikube/pkg/k8s"
@@ -20,11 +24,6 @@ import (
 	// depending on how the k8s.Client struct is defined and used.
 )
 
-// AppConfig holds all initialized Repositories
-// type AppRepository struct {
-// 	AuthRepository *repository.AuthRepository
-// }
-
 // AppServices holds all initialized services
 // Moved from main.go
 type AppServices struct {
@@ -36,6 +35,7 @@ type AppServices struct {
 	NetworkPolicyService *service.NetworkPolicyService
 	ConfigMapService     *service.ConfigMapService
 	SecretService        *service.SecretService
+	CertificateService   *service.CertificateService
 	PVCService           *service.PVCService
 	PVService            *service.PVService
 	StatefulSetService   *service.StatefulSetService
@@ -45,8 +45,10 @@ type AppServices struct {
 	EventsService        *service.EventsService
 	RbacService          *service.RbacService
 	InstallerService     service.InstallerService // Non-k8s service
-	AuthService          *service.AuthService     // auth service
+	AuthService          *service.AuthService     // auth service，数据库可用时非空
 	ProxyService         *service.ProxyService    // proxy service
+	Impersonator         *k8s.Impersonator        // 开启用户模拟时非空
+	Enforcer             *casbin.Enforcer         // 数据库可用时非空
 }
 
 // AppHandlers holds all initialized handlers
@@ -60,6 +62,7 @@ type AppHandlers struct {
 	NetworkPolicyHandler *handlers.NetworkPolicyHandler
 	ConfigMapHandler     *handlers.ConfigMapHandler
 	SecretHandler        *handlers.SecretHandler
+	CertificateHandler   *handlers.CertificateHandler
 	PVCHandler           *handlers.PVCHandler
 	PVHandler            *handlers.PVHandler
 	StatefulSetHandler   *handlers.StatefulSetHandler
@@ -71,18 +74,9 @@ type AppHandlers struct {
 	InstallerHandler     *handlers.InstallerHandler // Non-k8s handlers
 	AuthHandler          *handlers.AuthHandler      // auth handler
 	ProxyHandler         *handlers.ProxyHandler     // proxy handler
+	Impersonator         *k8s.Impersonator          // 开启用户模拟时非空
 }
 
-// InitializeRepository initializes the database repository.
-// func InitializeRepositories(DB *gorm.DB) *AppRepository {
-
-// 	// 初始化 Repository
-// 	appRepository := &AppRepository{}
-// 	appRepository.AuthRepository = repository.NewAuthRepository(DB)
-
-// 	return appRepository
-// }
-
 // InitializeServices initializes all application services.
 // K8s-dependent services are only initialized if k8sAvailable is true.
 // Moved from main.go
@@ -102,8 +96,26 @@ func InitializeServices(k8sClient *k8s.Client, k8sAvailable bool, cfg *configs.C
 		// 只有 InitDatabase 成功 (DB != nil) 才会继续
 		if database.DB != nil {
 			// log.Println("数据库连接成功。") // 这条日志现在只会在真正连接后打印
-			if err := database.AutoMigrate(); err != nil {
-				log.Fatalf("初始化失败: 数据库自动迁移失败: %v", err)
+			if err := database.Migrate(); err != nil {
+				log.Fatalf("初始化失败: 数据库迁移失败: %v", err)
+			}
+			if err := database.CreateDefaultAdmin(); err != nil {
+				log.Fatalf("初始化失败: 创建默认管理员失败: %v", err)
+			}
+			enforcer, err := auth.InitCasbin(database.DB)
+			if err != nil {
+				log.Fatalf("初始化 Casbin 失败: %v", err)
+			}
+			services.Enforcer = enforcer
+			log.Println("Casbin 初始化成功。")
+
+			// --- Auth Initialization ---
+			users := repository.NewGormUserRepository(database.DB)
+			services.AuthService = service.NewAuthService(users, database.DB, enforcer)
+			log.Println("Auth 服务初始化完成。")
+			if cfg.LDAP.Enabled && cfg.LDAP.SyncInterval > 0 {
+				go service.NewLDAPAuthenticator(cfg.LDAP, users, database.DB).RunSync(context.Background(), cfg.LDAP.SyncInterval)
+				log.Printf("LDAP 禁用账号同步已启动，间隔 %s", cfg.LDAP.SyncInterval)
 			}
 
 		} else {
@@ -113,12 +125,6 @@ func InitializeServices(k8sClient *k8s.Client, k8sAvailable bool, cfg *configs.C
 	} else {
 		log.Println("警告: 数据库未启用，相关服务将无法使用。")
 	}
-	// Initialize AuthService
-
-	// --- Auth Initialization ---
-	// - 这里初始化 AuthService
-	// - 需要确保 database.DB 已经成功连接
-
 	// Initialize K8s-dependent services (conditionally)
 	if k8sAvailable && k8sClient != nil && k8sClient.Clientset != nil {
 		log.Println("Kubernetes 可用，初始化 Kubernetes 相关服务...")
@@ -137,6 +143,7 @@ func InitializeServices(k8sClient *k8s.Client, k8sAvailable bool, cfg *configs.C
 		services.NetworkPolicyService = service.NewNetworkPolicyService(k8sClient.Clientset)
 		services.ConfigMapService = service.NewConfigMapService(k8sClient.Clientset)
 		services.SecretService = service.NewSecretService(k8sClient.Clientset)
+		services.CertificateService = service.NewCertificateService(services.SecretService, services.IngressService)
 		services.PVCService = service.NewPVCService(k8sClient.Clientset)
 		services.PVService = service.NewPVService(k8sClient.Clientset)
 		services.StatefulSetService = service.NewStatefulSetService(k8sClient.Clientset)
@@ -144,8 +151,16 @@ func InitializeServices(k8sClient *k8s.Client, k8sAvailable bool, cfg *configs.C
 		services.NamespaceService = service.NewNamespaceService(k8sClient.Clientset)
 		services.SummaryService = service.NewSummaryService(k8sClient.Clientset)
 		services.EventsService = service.NewEventsService(k8sClient.Clientset)
-		services.RbacService = service.NewRbacService(k8sClient.Clientset)
+		services.RbacService = service.NewRbacService(k8sClient.Clientset, k8sClient.Config)
 		services.ProxyService = service.NewProxyService(k8sClient.Config)
+		if cfg.Kubernetes.Impersonation.Enabled {
+			if k8sClient.Config != nil {
+				services.Impersonator = k8s.NewImpersonator(k8sClient.Config, cfg.Kubernetes.Impersonation)
+				log.Println("Kubernetes 用户模拟已开启，API 请求将以登录用户身份访问集群。")
+			} else {
+				log.Println("警告: k8sClient.Config 为 nil，无法开启 Kubernetes 用户模拟。")
+			}
+		}
 		log.Println("Kubernetes 相关服务初始化完成。")
 	} else {
 		log.Println("Kubernetes 不可用，跳过相关服务初始化。")
@@ -170,6 +185,9 @@ func InitializeHandlers(services *AppServices) *AppHandlers {
 	} else {
 		log.Println("警告: Installer 服务未初始化，跳过 Installer 处理器初始化。")
 	}
+	if services.AuthService != nil {
+		appHandlers.AuthHandler = handlers.NewAuthHandler(services.AuthService)
+	}
 
 	// Initialize K8s-dependent handlers (conditionally based on service)
 	// Check if the specific service pointer is non-nil
@@ -197,6 +215,9 @@ func InitializeHandlers(services *AppServices) *AppHandlers {
 	if services.SecretService != nil {
 		appHandlers.SecretHandler = handlers.NewSecretHandler(services.SecretService)
 	}
+	if services.CertificateService != nil {
+		appHandlers.CertificateHandler = handlers.NewCertificateHandler(services.CertificateService)
+	}
 	if services.PVCService != nil {
 		appHandlers.PVCHandler = handlers.NewPVCHandler(services.PVCService)
 	}
@@ -224,6 +245,7 @@ func InitializeHandlers(services *AppServices) *AppHandlers {
 	if services.ProxyService != nil {
 		appHandlers.ProxyHandler = handlers.NewProxyHandler(services.ProxyService)
 	}
+	appHandlers.Impersonator = services.Impersonator
 	log.Println("处理器初始化尝试完成 (部分可能因服务未初始化而跳过)。")
 	return appHandlers
 }
@@ -233,7 +255,9 @@ func InitializeHandlers(services *AppServices) *AppHandlers {
 func SetupRouter(cfg *configs.Config, handlers *AppHandlers, k8sAvailable bool, e *casbin.Enforcer) *gin.Engine {
 	log.Println("设置 Gin 路由器...")
 	// gin.SetMode(gin.ReleaseMode) // Uncomment for production
-	router := gin.Default()
+	// 不使用 gin.Default() 自带的 Logger：流式请求的查询参数中带有 access token，需要脱敏后再输出
+	router := gin.New()
+	router.Use(auth.RequestLogger(), gin.Recovery())
 
 	// 从配置或环境变量加载允许的源
 	router.Use(cors.New(cors.Config{
@@ -246,6 +270,11 @@ func SetupRouter(cfg *configs.Config, handlers *AppHandlers, k8sAvailable bool,
 	}))
 	// --- Middlewares ---
 	log.Println("应用 CORS 中间件...")
+	// 审计中间件必须在注册路由前应用，覆盖认证路由和 Kubernetes API 路由
+	if cfg.Database.Enabled && database.DB != nil {
+		log.Println("应用审计日志中间件...")
+		router.Use(audit.Middleware(cfg.Server.ActiveCluster))
+	}
 	//router.Use(utils.Cors(origins)) // Ensure utils.Cors() is correctly configured
 
 	// Prometheus Metrics Middleware (if enabled) - Example
@@ -277,33 +306,34 @@ func SetupRouter(cfg *configs.Config, handlers *AppHandlers, k8sAvailable bool,
 	// API v1 Routes Group
 	log.Println("注册 API v1 路由...")
 	v1 := router.Group("/api/v1")
+	var policyService *service.PolicyService
 	{
-		// Register non-k8s routes first (if handlers are initialized)
 		// --- Auth Routes ---
-		// 首先先从环境变量中获取 secret key
-		// secretKey := os.Getenv("CILIKUBE_JWT_SECRET")
+		// 登录、注册等认证路由注册在 /api/v1/auth 下，自带 JWT 中间件，不经过下面 v1 组上的 Casbin 校验
+		if handlers.AuthHandler != nil {
+			log.Println("注册认证路由...")
+			routes.SetupAuthRoutes(router, handlers.AuthHandler)
+		} else {
+			log.Println("数据库未启用，跳过认证路由注册。")
+		}
 
 		// --- Protected Routes ---
-		// 将与 k8s 相关操作放进 v1 中，将 login
-		// 或者其他不需要受保护的路由 Ignore (这里需要注意的事一定要用v1，否则不会生效)
-		log.Println("初始化 JWT 中间件...")
-		// 添加后会用 JWT 中间件来保护 API 路由
-		// v1.Use(auth.JWTAuthMiddleware())
-
-		// --- RBAC Middleware ---
-		// 注册 RBAC 中间件，这个中间件会在每个受保护的路由上应用
-		// log.Println("初始化 RBAC 中间件...")
+		// 先由 JWT 中间件校验 token 并写入用户信息，再由 Casbin 按角色校验权限
+		// 两者都依赖数据库中的用户和策略，Casbin 未初始化时 API 不做认证
 		if e != nil {
-			log.Println("应用 RBAC 中间件...")
-			v1.Use(auth.NewCasbinBuilder().
-				IgnorePath("/api/v1/auth/login"). // 忽略登录路径
-				CasbinMiddleware(e))              // 应用 Casbin
+			log.Println("应用 JWT 与 RBAC 中间件...")
+			v1.Use(auth.JWTAuthMiddleware(), auth.NewCasbinBuilder().WithCluster(cfg.Server.ActiveCluster).CasbinMiddleware(e))
+			// Casbin 放行后再以当前用户身份访问集群，由 Kubernetes RBAC 做最终授权
+			if handlers.Impersonator != nil {
+				v1.Use(auth.KubernetesImpersonationMiddleware(handlers.Impersonator))
+			}
 		} else {
-			log.Println("Casbin 未初始化，跳过 RBAC 中间件。")
+			log.Println("警告: Casbin 未初始化，API 路由未启用认证与权限校验。")
+			if handlers.Impersonator != nil {
+				log.Println("警告: 未启用认证，Kubernetes 用户模拟不会生效。")
+			}
 		}
 
-		// --- Auth Routes ---
-
 		// Register K8s related routes only if handlers were initialized
 		// We check if the specific handlers pointer is non-nil
 		if k8sAvailable { // Optional log: k8sAvailable check here gives context
@@ -348,6 +378,11 @@ func SetupRouter(cfg *configs.Config, handlers *AppHandlers, k8sAvailable bool,
 			} else {
 				log.Println("跳过 Secret 路由注册: Handler 未初始化。")
 			}
+			if handlers.CertificateHandler != nil {
+				routes.RegisterCertificateRoutes(v1, handlers.CertificateHandler)
+			} else {
+				log.Println("跳过证书清单路由注册: Handler 未初始化。")
+			}
 			if handlers.PVCHandler != nil {
 				routes.RegisterPVCRoutes(v1, handlers.PVCHandler)
 			} else {
@@ -398,7 +433,7 @@ func SetupRouter(cfg *configs.Config, handlers *AppHandlers, k8sAvailable bool,
 			// This check is still a bit manual, could be more abstract, but works.
 			if handlers.PodHandler == nil && handlers.DeploymentHandler == nil && // ... check all k8s handlers ...
 				handlers.DaemonSetHandler == nil && handlers.ServiceHandler == nil && handlers.IngressHandler == nil &&
-				handlers.NetworkPolicyHandler == nil && handlers.ConfigMapHandler == nil && handlers.SecretHandler == nil &&
+				handlers.NetworkPolicyHandler == nil && handlers.ConfigMapHandler == nil && handlers.SecretHandler == nil && handlers.CertificateHandler == nil &&
 				handlers.PVCHandler == nil && handlers.PVHandler == nil && handlers.StatefulSetHandler == nil &&
 				handlers.NodeHandler == nil && handlers.NamespaceHandler == nil && handlers.SummaryHandler == nil &&
 				handlers.EventsHandler == nil && handlers.RbacHandler == nil {
@@ -426,11 +461,40 @@ func SetupRouter(cfg *configs.Config, handlers *AppHandlers, k8sAvailable bool,
 			})
 		}
 
+		// Casbin 策略管理路由，同样受上面的 Casbin 中间件保护
+		if e != nil {
+			policyService = service.NewPolicyService(e, cfg.Server.ActiveCluster)
+			routes.RegisterPolicyRoutes(v1, newPolicyHandler(policyService))
+			if database.DB != nil {
+				routes.RegisterAuditRoutes(v1, newAuditHandler(service.NewAuditService()))
+			}
+		}
+	}
+	// 所有路由注册完成后再交给策略服务，用于预览策略变更影响的路由
+	if policyService != nil {
+		policyService.SetRoutes(collectRoutes(router))
 	}
 	log.Println("API 路由注册完成。")
 	return router
 }
 
+func newPolicyHandler(svc *service.PolicyService) *handlers.PolicyHandler {
+	return handlers.NewPolicyHandler(svc)
+}
+
+func newAuditHandler(svc *service.AuditService) *handlers.AuditHandler {
+	return handlers.NewAuditHandler(svc)
+}
+
+// collectRoutes 返回路由器上已注册的所有路由
+func collectRoutes(router *gin.Engine) []models.RoutePermission {
+	var result []models.RoutePermission
+	for _, route := range router.Routes() {
+		result = append(result, models.RoutePermission{Method: route.Method, Path: route.Path})
+	}
+	return result
+}
+
 // InitializeDefaultConfig 初始化默认配置
 
 // InitializeDefaultUser 创建超级管理员账户和游客账户
@@ -0,0 +1,91 @@
+package initialization
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/configs"
+	"github.com/ciliverse/cilikube/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginLockout(t *testing.T) {
+	router := setupAuthTestRouterWithConfig(t, func(cfg *configs.Config) {
+		cfg.Security.Login = configs.LoginProtectionConfig{MaxAttempts: 3, IPMaxAttempts: 5, Window: 15 * time.Minute, LockDuration: time.Minute, MaxLockout: time.Hour}
+	})
+	wrong := models.LoginRequest{Username: "viewer", Password: "wrong-password"}
+
+	for i := 0; i < 3; i++ {
+		w := doRequest(router, http.MethodPost, "/api/v1/auth/login", "", wrong)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+	// 锁定期内即使密码正确也拒绝
+	w := doRequest(router, http.MethodPost, "/api/v1/auth/login", "", models.LoginRequest{Username: "viewer", Password: "viewer123"})
+	require.Equal(t, http.StatusTooManyRequests, w.Code, w.Body.String())
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	var events []models.AuditEvent
+	require.NoError(t, database.DB.Where("action = ?", models.AuditActionLoginLockout).Find(&events).Error)
+	require.Len(t, events, 1)
+	assert.Equal(t, "user:viewer", events[0].Target)
+
+	// 管理员解锁后可以正常登录
+	adminToken := login(t, router, "admin", "admin123")
+	var viewer models.User
+	require.NoError(t, database.DB.Where("username = ?", "viewer").First(&viewer).Error)
+	w = doRequest(router, http.MethodPost, fmt.Sprintf("/api/v1/auth/users/%d/unlock", viewer.ID), adminToken, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	loginResponse(t, router, "viewer", "viewer123")
+
+	// 同一 IP 对不同用户名的失败累计到 IP 上限后，整个 IP 被锁定
+	for _, username := range []string{"nobody-1", "nobody-2"} {
+		w = doRequest(router, http.MethodPost, "/api/v1/auth/login", "", models.LoginRequest{Username: username, Password: "wrong-password"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/login", "", models.LoginRequest{Username: "alice", Password: "alice123"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	router := setupAuthTestRouterWithConfig(t, func(cfg *configs.Config) {
+		cfg.Security.Password = configs.PasswordPolicyConfig{MinLength: 10, MinCharClasses: 3, HistorySize: 3}
+	})
+	require.NoError(t, database.DB.Model(&models.User{}).Where("username = ?", "admin").Update("must_change_password", true).Error)
+
+	// 使用初始密码登录后只能修改密码
+	first := loginResponse(t, router, "admin", "admin123")
+	assert.True(t, first.User.MustChangePassword)
+	w := doRequest(router, http.MethodGet, "/api/v1/nodes", first.Token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(router, http.MethodGet, "/api/v1/auth/profile", first.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	for _, weak := range []string{"Short1!", "alllowercaseletters", "Admin-Rocks-2024"} {
+		w = doRequest(router, http.MethodPost, "/api/v1/auth/change-password", first.Token, models.ChangePasswordRequest{OldPassword: "admin123", NewPassword: weak})
+		assert.Equal(t, http.StatusBadRequest, w.Code, weak)
+	}
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/change-password", first.Token, models.ChangePasswordRequest{OldPassword: "admin123", NewPassword: "Correct-Horse-1"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// 修改密码后旧会话失效，新会话不再受限
+	w = doRequest(router, http.MethodGet, "/api/v1/auth/profile", first.Token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	second := loginResponse(t, router, "admin", "Correct-Horse-1")
+	assert.False(t, second.User.MustChangePassword)
+	w = doRequest(router, http.MethodGet, "/api/v1/nodes", second.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 不能重复使用最近的密码
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/change-password", second.Token, models.ChangePasswordRequest{OldPassword: "Correct-Horse-1", NewPassword: "Battery-Staple-2"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	third := loginResponse(t, router, "admin", "Battery-Staple-2")
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/change-password", third.Token, models.ChangePasswordRequest{OldPassword: "Battery-Staple-2", NewPassword: "Correct-Horse-1"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/register", "", models.RegisterRequest{Username: "bob", Email: "bob@cilikube.com", Password: "bob12345"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
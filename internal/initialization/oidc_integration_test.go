@@ -0,0 +1,170 @@
+package initialization
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/configs"
+	"github.com/ciliverse/cilikube/pkg/database"
+	"github.com/ciliverse/cilikube/pkg/oidc/oidctest"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// oidcLogin 走完整的授权码流程：/oidc/login -> IdP 授权端点 -> /oidc/callback，回调时带上登录时设置的 state cookie
+func oidcLogin(t *testing.T, router *gin.Engine) (int, string) {
+	callback, cookies := oidcAuthorize(t, router)
+	w := oidcCallback(router, callback, cookies)
+	return w.Code, w.Body.String()
+}
+
+// oidcAuthorize 发起登录并在 IdP 完成授权，返回回调地址和浏览器收到的 cookie
+func oidcAuthorize(t *testing.T, router *gin.Engine) (*url.URL, []*http.Cookie) {
+	w := doRequest(router, http.MethodGet, "/api/v1/auth/oidc/login", "", nil)
+	require.Equal(t, http.StatusFound, w.Code, w.Body.String())
+	authURL, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+	assert.Empty(t, authURL.Query().Get("code_verifier"))
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL.String())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return callback, w.Result().Cookies()
+}
+
+func oidcCallback(router *gin.Engine, callback *url.URL, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func setupOIDCTestRouter(t *testing.T, issuer *oidctest.Server) *gin.Engine {
+	return setupAuthTestRouterWithConfig(t, func(cfg *configs.Config) {
+		cfg.OIDC = configs.OIDCConfig{
+			Enabled:       true,
+			IssuerURL:     issuer.URL,
+			ClientID:      "cilikube",
+			ClientSecret:  "client-secret",
+			RedirectURL:   "http://cilikube.test/api/v1/auth/oidc/callback",
+			Scopes:        []string{"openid", "profile", "email", "groups"},
+			UsernameClaim: "preferred_username",
+			GroupsClaim:   "groups",
+			GroupRoles:    []configs.GroupRole{{Group: "k8s-admins", Role: "admin"}, {Group: "dev", Role: "user"}},
+			DefaultRole:   "guest",
+		}
+	})
+}
+
+func TestOIDCLogin(t *testing.T) {
+	issuer := oidctest.NewServer("cilikube", "client-secret")
+	defer issuer.Close()
+	router := setupOIDCTestRouter(t, issuer)
+
+	// 首次登录自动创建用户，按分组映射角色
+	issuer.SetUser(map[string]interface{}{"sub": "sso-1", "preferred_username": "sso-alice", "email": "alice@example.com", "groups": []string{"dev"}})
+	code, body := oidcLogin(t, router)
+	require.Equal(t, http.StatusOK, code, body)
+	var user models.User
+	require.NoError(t, database.DB.Where("username = ?", "sso-alice").First(&user).Error)
+	assert.Equal(t, models.AuthProviderOIDC, user.AuthProvider)
+	assert.Equal(t, "sso-1", user.ExternalID)
+	assert.Equal(t, "user", user.Role)
+
+	// 再次登录时按最新分组更新角色，不会重复创建用户
+	issuer.SetUser(map[string]interface{}{"sub": "sso-1", "preferred_username": "sso-alice", "email": "alice@example.com", "groups": []string{"dev", "k8s-admins"}})
+	code, body = oidcLogin(t, router)
+	require.Equal(t, http.StatusOK, code, body)
+	require.NoError(t, database.DB.First(&user, user.ID).Error)
+	assert.Equal(t, "admin", user.Role)
+	var count int64
+	database.DB.Model(&models.User{}).Where("external_id = ?", "sso-1").Count(&count)
+	assert.EqualValues(t, 1, count)
+
+	// 签发的令牌可以访问受保护的 API
+	w := doRequest(router, http.MethodPost, "/api/v1/nodes/node-1/cordon", decodeLoginResponseBody(t, body).Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 外部账号不能使用本地密码登录
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/login", "", models.LoginRequest{Username: "sso-alice", Password: "anything"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 与本地账号用户名冲突时拒绝自动创建，避免接管本地账号
+	issuer.SetUser(map[string]interface{}{"sub": "sso-2", "preferred_username": "admin", "email": "other@example.com"})
+	code, _ = oidcLogin(t, router)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	// 回调链接必须由发起登录的浏览器打开：攻击者把自己的回调链接发给受害者时，受害者没有对应的 state cookie
+	issuer.SetUser(map[string]interface{}{"sub": "sso-1", "preferred_username": "sso-alice", "email": "alice@example.com", "groups": []string{"dev"}})
+	callback, attackerCookies := oidcAuthorize(t, router)
+	require.Len(t, attackerCookies, 1)
+	assert.True(t, attackerCookies[0].HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, attackerCookies[0].SameSite)
+	w = oidcCallback(router, callback, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	_, victimCookies := oidcAuthorize(t, router)
+	w = oidcCallback(router, callback, victimCookies)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = oidcCallback(router, callback, attackerCookies)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// state 只能使用一次
+	w = doRequest(router, http.MethodGet, "/api/v1/auth/oidc/callback?state=unknown&code=abc", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// OIDC 登录与密码登录一样遵守两步验证设置和安全策略
+func TestOIDCLoginTwoFactor(t *testing.T) {
+	issuer := oidctest.NewServer("cilikube", "client-secret")
+	defer issuer.Close()
+	router := setupOIDCTestRouter(t, issuer)
+
+	issuer.SetUser(map[string]interface{}{"sub": "sso-1", "preferred_username": "sso-bob", "email": "bob@example.com", "groups": []string{"dev"}})
+	code, body := oidcLogin(t, router)
+	require.Equal(t, http.StatusOK, code, body)
+	token := decodeLoginResponseBody(t, body).Token
+
+	w := doRequest(router, http.MethodPost, "/api/v1/auth/2fa/setup", token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var setup models.TOTPSetupResponse
+	decodeData(t, w, &setup)
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/2fa/enable", token, models.TOTPCodeRequest{Code: totpCode(t, setup.Secret, 0)})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// 启用两步验证后 IdP 登录只返回 mfa_token
+	code, body = oidcLogin(t, router)
+	require.Equal(t, http.StatusOK, code, body)
+	var pending struct {
+		Data models.LoginResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(body), &pending))
+	assert.True(t, pending.Data.TwoFactorRequired)
+	assert.Empty(t, pending.Data.Token)
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/login/2fa", "", models.TwoFactorLoginRequest{MFAToken: pending.Data.MFAToken, Code: totpCode(t, setup.Secret, 1)})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// 安全策略要求写权限用户绑定两步验证时，未绑定的 IdP 管理员需要先完成绑定
+	adminToken := login(t, router, "admin", "admin123")
+	enabled := true
+	w = doRequest(router, http.MethodPut, "/api/v1/auth/security-policy", adminToken, models.UpdateSecurityPolicyRequest{RequireTwoFactorForWriters: &enabled})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	issuer.SetUser(map[string]interface{}{"sub": "sso-2", "preferred_username": "sso-carol", "email": "carol@example.com", "groups": []string{"k8s-admins"}})
+	code, body = oidcLogin(t, router)
+	require.Equal(t, http.StatusOK, code, body)
+	require.NoError(t, json.Unmarshal([]byte(body), &pending))
+	assert.True(t, pending.Data.TwoFactorSetupRequired)
+	assert.Empty(t, pending.Data.Token)
+}
@@ -0,0 +1,67 @@
+package initialization
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ciliverse/cilikube/api/v1/handlers"
+	"github.com/ciliverse/cilikube/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+)
+
+// setupProxyTestRouter 注册透传代理，上游 API Server 对任意路径都返回 Secret 明文
+func setupProxyTestRouter(t *testing.T) *gin.Engine {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"kind":"Secret","data":{"password":"czNjcjN0"}}`))
+	}))
+	t.Cleanup(upstream.Close)
+	return setupAuthTestRouterWithHandlers(t, nil, func(h *AppHandlers) {
+		h.ProxyHandler = handlers.NewProxyHandler(service.NewProxyService(&rest.Config{Host: upstream.URL}))
+	})
+}
+
+// 只读用户不能通过透传代理读取 Secret 或建立 exec 连接
+func TestProxyRequiresProxyVerb(t *testing.T) {
+	router := setupProxyTestRouter(t)
+	secretPath := "/api/v1/proxy/api/v1/namespaces/team-a/secrets/db"
+	execPath := "/api/v1/proxy/api/v1/namespaces/team-a/pods/web/exec?command=sh&stdin=true"
+
+	viewerToken := login(t, router, "viewer", "viewer123")
+	w := doRequest(router, http.MethodGet, secretPath, viewerToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "czNjcjN0")
+
+	req := httptest.NewRequest(http.MethodGet, execPath, nil)
+	req.Header.Set("Authorization", "Bearer "+viewerToken)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+
+	// 命名空间管理员也只能在自己的命名空间内访问，不能借代理访问任意资源
+	aliceToken := login(t, router, "alice", "alice123")
+	w = doRequest(router, http.MethodGet, secretPath, aliceToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// 反向代理需要真实的连接 (ResponseRecorder 不支持 CloseNotify)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	adminToken := login(t, router, "admin", "admin123")
+	req = httptest.NewRequest(http.MethodGet, server.URL+secretPath, nil)
+	req.RequestURI = ""
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Contains(t, string(body), "czNjcjN0")
+}
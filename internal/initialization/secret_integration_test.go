@@ -0,0 +1,291 @@
+package initialization
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ciliverse/cilikube/api/v1/handlers"
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/internal/service"
+	"github.com/ciliverse/cilikube/pkg/database"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSecretRevealAndKeyEditing(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "team-a"},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{"password": []byte("s3cr3t"), "cert": {0xff, 0x00}},
+	})
+	router := setupAuthTestRouterWithHandlers(t, nil, func(h *AppHandlers) {
+		h.SecretHandler = handlers.NewSecretHandler(service.NewSecretService(clientset))
+	})
+	adminToken := login(t, router, "admin", "admin123")
+	viewerToken := login(t, router, "viewer", "viewer123")
+	aliceToken := login(t, router, "alice", "alice123")
+	base := "/api/v1/namespaces/team-a/secrets/db"
+
+	// 详情和 YAML 默认遮盖值
+	w := doRequest(router, http.MethodGet, base, viewerToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var detail models.SecretDetailResponse
+	decodeData(t, w, &detail)
+	assert.Equal(t, map[string]string{"password": models.SecretMaskedValue, "cert": models.SecretMaskedValue}, detail.Data)
+	assert.Equal(t, []models.SecretKeyInfo{{Key: "cert", Size: 2, Binary: true}, {Key: "password", Size: 6}}, detail.Keys)
+
+	w = doRequest(router, http.MethodGet, base+"/yaml", viewerToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var yamlText string
+	decodeData(t, w, &yamlText)
+	assert.Contains(t, yamlText, "password: '******'")
+	assert.NotContains(t, yamlText, "czNjcjN0", "YAML 中不包含 base64 形式的明文")
+
+	// 只读角色不能查看明文，有 reveal 权限的角色可以，每次查看都被审计
+	w = doRequest(router, http.MethodGet, base+"/keys/password/reveal", viewerToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(router, http.MethodGet, base+"/keys/password/reveal", aliceToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var revealed models.SecretRevealResponse
+	decodeData(t, w, &revealed)
+	assert.Equal(t, models.SecretRevealResponse{Key: "password", Value: "s3cr3t", Encoding: models.SecretValueEncodingText, Size: 6}, revealed)
+	w = doRequest(router, http.MethodGet, base+"/keys/cert/reveal", aliceToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	decodeData(t, w, &revealed)
+	assert.Equal(t, models.SecretValueEncodingBase64, revealed.Encoding)
+	assert.Equal(t, "/wA=", revealed.Value)
+	w = doRequest(router, http.MethodGet, base+"/keys/missing/reveal", aliceToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(router, http.MethodGet, "/api/v1/audit/events?"+url.Values{"verb": {"reveal"}}.Encode(), adminToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var events models.AuditEventListResponse
+	decodeData(t, w, &events)
+	require.EqualValues(t, 4, events.Total)
+	assert.Equal(t, "alice", events.Items[1].Username)
+	assert.Equal(t, base+"/keys/cert/reveal", events.Items[1].Path)
+	assert.Equal(t, "db", events.Items[1].Name)
+	assert.Equal(t, "viewer", events.Items[3].Username)
+	assert.Equal(t, http.StatusForbidden, events.Items[3].StatusCode)
+
+	// 单个键的新增、重命名和删除
+	w = doRequest(router, http.MethodPut, base+"/keys/token", aliceToken, models.SetSecretKeyRequest{Value: "abc"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doRequest(router, http.MethodPut, base+"/keys/raw", aliceToken, models.SetSecretKeyRequest{Value: "AQI=", Encoding: models.SecretValueEncodingBase64})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doRequest(router, http.MethodPut, base+"/keys/bad%20key", aliceToken, models.SetSecretKeyRequest{Value: "x"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(router, http.MethodPut, base+"/keys/token", viewerToken, models.SetSecretKeyRequest{Value: "x"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doRequest(router, http.MethodPatch, base+"/keys/token", aliceToken, models.RenameSecretKeyRequest{NewKey: "password"})
+	assert.Equal(t, http.StatusConflict, w.Code, "新键已存在时需要 overwrite")
+	w = doRequest(router, http.MethodPatch, base+"/keys/token", aliceToken, models.RenameSecretKeyRequest{NewKey: "api-token"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doRequest(router, http.MethodDelete, base+"/keys/cert", aliceToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doRequest(router, http.MethodDelete, base+"/keys/cert", aliceToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// 上传二进制文件作为值
+	keystore := []byte{0x00, 0xfe, 0xed, 0xfe, 0xed}
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "keystore.jks")
+	require.NoError(t, err)
+	_, err = part.Write(keystore)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	req := httptest.NewRequest(http.MethodPut, base+"/keys/keystore.jks", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+aliceToken)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	secret, err := clientset.CoreV1().Secrets("team-a").Get(context.Background(), "db", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"password":     []byte("s3cr3t"),
+		"api-token":    []byte("abc"),
+		"raw":          {0x01, 0x02},
+		"keystore.jks": keystore,
+	}, secret.Data)
+
+	// 遮盖后的内容不能原样提交回来
+	w = doRequest(router, http.MethodPut, base, aliceToken, gin.H{
+		"apiVersion": "v1", "kind": "Secret",
+		"metadata": gin.H{"name": "db", "namespace": "team-a"},
+		"data":     gin.H{"password": models.SecretMaskedValue},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestTypedSecretRoutes(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	router := setupAuthTestRouterWithHandlers(t, nil, func(h *AppHandlers) {
+		h.SecretHandler = handlers.NewSecretHandler(service.NewSecretService(clientset))
+	})
+	viewerToken := login(t, router, "viewer", "viewer123")
+	aliceToken := login(t, router, "alice", "alice123")
+	base := "/api/v1/namespaces/team-a/secrets"
+
+	req := models.BasicAuthSecretRequest{Name: "git", Username: "git", Password: "token"}
+	w := doRequest(router, http.MethodPost, base+"/basic-auth", viewerToken, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(router, http.MethodPost, base+"/basic-auth", aliceToken, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created models.TypedSecretResponse
+	decodeData(t, w, &created)
+	assert.Equal(t, corev1.SecretTypeBasicAuth, created.Type)
+	w = doRequest(router, http.MethodPost, base+"/basic-auth", aliceToken, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = doRequest(router, http.MethodPost, base+"/tls", aliceToken, models.TLSSecretRequest{Name: "web-tls", Cert: "bad", Key: "bad"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(router, http.MethodPost, base+"/dockerconfigjson", aliceToken, models.DockerConfigSecretRequest{Name: "Bad_Name", Server: "r", Username: "u", Password: "p"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCertificateInventoryRoutes(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "team-a"},
+		Spec:       networkingv1.IngressSpec{TLS: []networkingv1.IngressTLS{{SecretName: "missing"}}},
+	})
+	router := setupAuthTestRouterWithHandlers(t, nil, func(h *AppHandlers) {
+		h.CertificateHandler = handlers.NewCertificateHandler(service.NewCertificateService(
+			service.NewSecretService(clientset), service.NewIngressService(clientset)))
+	})
+	viewerToken := login(t, router, "viewer", "viewer123")
+	aliceToken := login(t, router, "alice", "alice123")
+
+	w := doRequest(router, http.MethodGet, "/api/v1/certificates?threshold_days=30", viewerToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var inventory models.CertificateInventoryResponse
+	decodeData(t, w, &inventory)
+	require.Len(t, inventory.DanglingReferences, 1)
+	assert.Equal(t, "missing", inventory.DanglingReferences[0].SecretName)
+
+	// 命名空间角色只能查看自己命名空间的清单
+	w = doRequest(router, http.MethodGet, "/api/v1/certificates", aliceToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(router, http.MethodGet, "/api/v1/namespaces/team-a/certificates", aliceToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doRequest(router, http.MethodGet, "/api/v1/namespaces/team-a/certificates?sort_by=issuer", aliceToken, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSecretRevealGrantedSeparately(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "team-a"},
+		Data:       map[string][]byte{"password": []byte("s3cr3t")},
+	})
+	router := setupAuthTestRouterWithHandlers(t, nil, func(h *AppHandlers) {
+		h.SecretHandler = handlers.NewSecretHandler(service.NewSecretService(clientset))
+	})
+	adminToken := login(t, router, "admin", "admin123")
+	base := "/api/v1/namespaces/team-a/secrets/db"
+
+	// 自定义角色只授予 reveal，不附带任何写权限
+	role := models.CreateCasbinRoleRequest{Name: "secret-revealer", Rules: []models.PermissionRule{{Resource: "secrets*", Verbs: []string{"reveal"}}}}
+	w := doRequest(router, http.MethodPost, "/api/v1/policies/roles", adminToken, role)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assignment := models.RoleAssignmentRequest{Username: "viewer", Role: "secret-revealer", Cluster: "default", Namespace: "team-a"}
+	w = doRequest(router, http.MethodPost, "/api/v1/policies/assignments", adminToken, assignment)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	viewerToken := login(t, router, "viewer", "viewer123")
+	w = doRequest(router, http.MethodGet, base+"/keys/password/reveal", viewerToken, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doRequest(router, http.MethodPut, base+"/keys/password", viewerToken, models.SetSecretKeyRequest{Value: "x"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(router, http.MethodGet, "/api/v1/namespaces/team-b/secrets/db/keys/password/reveal", viewerToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// reveal 只对仪表盘自己的 Secret 接口生效，不能借 API Server 透传代理绕过，代理请求会被审计
+func TestSecretRevealNotBypassedByProxy(t *testing.T) {
+	router := setupProxyTestRouter(t)
+	adminToken := login(t, router, "admin", "admin123")
+	role := models.CreateCasbinRoleRequest{Name: "secret-revealer", Rules: []models.PermissionRule{
+		{Resource: "*", Verbs: []string{"get", "list", "watch"}},
+		{Resource: "secrets*", Verbs: []string{"reveal"}},
+	}}
+	w := doRequest(router, http.MethodPost, "/api/v1/policies/roles", adminToken, role)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assignment := models.RoleAssignmentRequest{Username: "viewer", Role: "secret-revealer", Cluster: "default"}
+	w = doRequest(router, http.MethodPost, "/api/v1/policies/assignments", adminToken, assignment)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	viewerToken := login(t, router, "viewer", "viewer123")
+	for _, path := range []string{
+		"/api/v1/proxy/api/v1/namespaces/team-a/secrets/db",
+		"/api/v1/proxy/api/v1/secrets",
+	} {
+		w = doRequest(router, http.MethodGet, path, viewerToken, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+		assert.NotContains(t, w.Body.String(), "czNjcjN0", path)
+	}
+
+	var events []models.AuditEvent
+	require.NoError(t, database.DB.Where("username = ? AND verb = ?", "viewer", "proxy").Find(&events).Error)
+	require.Len(t, events, 2)
+	assert.Equal(t, http.StatusForbidden, events[0].StatusCode)
+	assert.Equal(t, "/api/v1/proxy/api/v1/namespaces/team-a/secrets/db", events[0].Path)
+}
+
+func TestSecretLastAppliedConfigurationMasked(t *testing.T) {
+	lastApplied := `{"apiVersion":"v1","data":{"password":"czNjcjN0"},"kind":"Secret","metadata":{"name":"db","namespace":"team-a"}}`
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "team-a", Annotations: map[string]string{
+			corev1.LastAppliedConfigAnnotation: lastApplied,
+			"owner":                            "team-a",
+		}},
+		Data: map[string][]byte{"password": []byte("s3cr3t")},
+	})
+	router := setupAuthTestRouterWithHandlers(t, nil, func(h *AppHandlers) {
+		h.SecretHandler = handlers.NewSecretHandler(service.NewSecretService(clientset))
+	})
+	viewerToken := login(t, router, "viewer", "viewer123")
+	aliceToken := login(t, router, "alice", "alice123")
+	base := "/api/v1/namespaces/team-a/secrets"
+
+	// 列表、详情、YAML 和单键编辑的响应都不包含注解中的 data
+	for _, w := range []*httptest.ResponseRecorder{
+		doRequest(router, http.MethodGet, base, viewerToken, nil),
+		doRequest(router, http.MethodGet, base+"/db", viewerToken, nil),
+		doRequest(router, http.MethodGet, base+"/db/yaml", viewerToken, nil),
+		doRequest(router, http.MethodPut, base+"/db/keys/token", aliceToken, models.SetSecretKeyRequest{Value: "abc"}),
+	} {
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.NotContains(t, w.Body.String(), "czNjcjN0")
+	}
+	w := doRequest(router, http.MethodGet, base+"/db", viewerToken, nil)
+	var detail models.SecretDetailResponse
+	decodeData(t, w, &detail)
+	assert.Equal(t, models.SecretMaskedValue, detail.Annotations[corev1.LastAppliedConfigAnnotation])
+	assert.Equal(t, "team-a", detail.Annotations["owner"])
+
+	// 把遮盖后的注解原样提交回来时保留集群中的原值
+	w = doRequest(router, http.MethodPut, base+"/db", aliceToken, gin.H{
+		"apiVersion": "v1", "kind": "Secret",
+		"metadata": gin.H{"name": "db", "namespace": "team-a", "annotations": detail.Annotations},
+		"data":     gin.H{"password": "bmV3"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "czNjcjN0")
+	secret, err := clientset.CoreV1().Secrets("team-a").Get(context.Background(), "db", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, lastApplied, secret.Annotations[corev1.LastAppliedConfigAnnotation])
+	assert.Equal(t, []byte("new"), secret.Data["password"])
+}
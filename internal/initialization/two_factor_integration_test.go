@@ -0,0 +1,124 @@
+package initialization
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/pkg/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeData(t *testing.T, w *httptest.ResponseRecorder, out interface{}) {
+	var resp struct {
+		Data json.RawMessage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+	require.NoError(t, json.Unmarshal(resp.Data, out))
+}
+
+func totpCode(t *testing.T, secret string, offset int64) string {
+	code, err := totp.Code(secret, totp.Counter(time.Now())+offset)
+	require.NoError(t, err)
+	return code
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	router := setupAuthTestRouter(t)
+	token := login(t, router, "viewer", "viewer123")
+
+	// 绑定并启用两步验证
+	w := doRequest(router, http.MethodPost, "/api/v1/auth/2fa/setup", token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var setup models.TOTPSetupResponse
+	decodeData(t, w, &setup)
+	assert.Contains(t, setup.ProvisioningURI, "otpauth://totp/CiliKube:viewer")
+
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/2fa/enable", token, models.TOTPCodeRequest{Code: "000000"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/2fa/enable", token, models.TOTPCodeRequest{Code: totpCode(t, setup.Secret, 0)})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var recovery models.RecoveryCodesResponse
+	decodeData(t, w, &recovery)
+	require.Len(t, recovery.RecoveryCodes, 10)
+
+	// 密码正确后只返回 mfa_token
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/login", "", models.LoginRequest{Username: "viewer", Password: "viewer123"})
+	require.Equal(t, http.StatusOK, w.Code)
+	var first models.LoginResponse
+	decodeData(t, w, &first)
+	assert.True(t, first.TwoFactorRequired)
+	assert.Empty(t, first.Token)
+	require.NotEmpty(t, first.MFAToken)
+
+	// 启用时使用过的时间步不能再次使用
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/login/2fa", "", models.TwoFactorLoginRequest{MFAToken: first.MFAToken, Code: totpCode(t, setup.Secret, 0)})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/login/2fa", "", models.TwoFactorLoginRequest{MFAToken: first.MFAToken, Code: totpCode(t, setup.Secret, 1)})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	decodeLoginResponse(t, w)
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/login/2fa", "", models.TwoFactorLoginRequest{MFAToken: first.MFAToken, Code: totpCode(t, setup.Secret, 1)})
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "mfa_token 只能使用一次")
+
+	// 恢复码只能使用一次
+	for i, want := range []int{http.StatusOK, http.StatusBadRequest} {
+		w = doRequest(router, http.MethodPost, "/api/v1/auth/login", "", models.LoginRequest{Username: "viewer", Password: "viewer123"})
+		var pending models.LoginResponse
+		decodeData(t, w, &pending)
+		w = doRequest(router, http.MethodPost, "/api/v1/auth/login/2fa", "", models.TwoFactorLoginRequest{MFAToken: pending.MFAToken, Code: recovery.RecoveryCodes[0]})
+		assert.Equal(t, want, w.Code, "第 %d 次使用恢复码", i+1)
+	}
+
+	// 关闭后恢复普通登录
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/2fa/disable", token, models.TOTPCodeRequest{Code: recovery.RecoveryCodes[1]})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	loginResponse(t, router, "viewer", "viewer123")
+}
+
+func TestTwoFactorPolicy(t *testing.T) {
+	router := setupAuthTestRouter(t)
+	adminToken := login(t, router, "admin", "admin123")
+	aliceSession := loginResponse(t, router, "alice", "alice123")
+
+	enabled := true
+	w := doRequest(router, http.MethodPut, "/api/v1/auth/security-policy", adminToken, models.UpdateSecurityPolicyRequest{RequireTwoFactorForWriters: &enabled})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// 只读用户不受影响
+	loginResponse(t, router, "viewer", "viewer123")
+
+	// 有写权限的用户不能再刷新旧会话，登录时必须先绑定
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/refresh", "", models.RefreshTokenRequest{RefreshToken: aliceSession.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/login", "", models.LoginRequest{Username: "alice", Password: "alice123"})
+	require.Equal(t, http.StatusOK, w.Code)
+	var pending models.LoginResponse
+	decodeData(t, w, &pending)
+	require.True(t, pending.TwoFactorSetupRequired)
+	assert.Empty(t, pending.Token)
+
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/login/2fa", "", models.TwoFactorLoginRequest{MFAToken: pending.MFAToken, Code: "123456"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "绑定用的 mfa_token 不能直接登录")
+
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/login/2fa/setup", "", models.TwoFactorSetupLoginRequest{MFAToken: pending.MFAToken})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var setup models.TOTPSetupResponse
+	decodeData(t, w, &setup)
+
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/login/2fa/enable", "", models.TwoFactorLoginRequest{MFAToken: pending.MFAToken, Code: totpCode(t, setup.Secret, 0)})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var enrolled models.TwoFactorEnrollResponse
+	decodeData(t, w, &enrolled)
+	assert.Len(t, enrolled.RecoveryCodes, 10)
+	require.NotNil(t, enrolled.Login)
+	require.NotEmpty(t, enrolled.Login.Token)
+
+	// 策略要求两步验证时不能关闭
+	w = doRequest(router, http.MethodPost, "/api/v1/auth/2fa/disable", enrolled.Login.Token, models.TOTPCodeRequest{Code: enrolled.RecoveryCodes[0]})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
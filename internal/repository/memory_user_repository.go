@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"gorm.io/gorm/schema"
+)
+
+// MemoryUserRepository 保存在内存中的用户仓库，用于测试和不需要持久化的场景。
+// 用户名、邮箱唯一，删除即移除；返回值都是副本，修改后需要调用 Update 才会保存
+type MemoryUserRepository struct {
+	mu     sync.Mutex
+	users  map[uint]*models.User
+	nextID uint
+	schema *schema.Schema
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	// 借用 gorm 的模型解析得到列名到字段的映射，保证 Update 使用的列名与数据库一致
+	userSchema, err := schema.Parse(&models.User{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		panic(fmt.Sprintf("解析用户模型失败: %v", err))
+	}
+	return &MemoryUserRepository{users: map[uint]*models.User{}, nextID: 1, schema: userSchema}
+}
+
+func (r *MemoryUserRepository) FindByID(id uint) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.ID == id })
+}
+
+func (r *MemoryUserRepository) FindByUsername(username string) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.Username == username })
+}
+
+func (r *MemoryUserRepository) FindByExternalID(provider, externalID string) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.AuthProvider == provider && u.ExternalID == externalID })
+}
+
+func (r *MemoryUserRepository) find(match func(*models.User) bool) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if match(user) {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (r *MemoryUserRepository) ExistsByUsername(username string) (bool, error) {
+	_, err := r.FindByUsername(username)
+	return err == nil, nil
+}
+
+func (r *MemoryUserRepository) ExistsByEmail(email string, excludeID uint) (bool, error) {
+	_, err := r.find(func(u *models.User) bool { return u.Email == email && u.ID != excludeID })
+	return err == nil, nil
+}
+
+func (r *MemoryUserRepository) List(offset, limit int) ([]models.User, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	users := make([]models.User, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, *user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if !users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].CreatedAt.After(users[j].CreatedAt)
+		}
+		return users[i].ID > users[j].ID
+	})
+	total := int64(len(users))
+	if offset >= len(users) {
+		return []models.User{}, total, nil
+	}
+	users = users[offset:]
+	if limit >= 0 && limit < len(users) {
+		users = users[:limit]
+	}
+	return users, total, nil
+}
+
+func (r *MemoryUserRepository) ListActiveByProvider(provider string) ([]models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var users []models.User
+	for _, user := range r.users {
+		if user.IsActive && user.AuthProvider == provider {
+			users = append(users, *user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+// Create 与 gorm 的模型默认值和 BeforeCreate 钩子保持一致：哈希密码、补齐账号来源和角色
+func (r *MemoryUserRepository) Create(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.users {
+		if existing.Username == user.Username {
+			return fmt.Errorf("用户名 %s 已存在", user.Username)
+		}
+		if existing.Email == user.Email {
+			return fmt.Errorf("邮箱 %s 已存在", user.Email)
+		}
+	}
+	if err := user.HashPassword(); err != nil {
+		return err
+	}
+	if user.Role == "" {
+		user.Role = "user"
+	}
+	if user.AuthProvider == "" {
+		user.AuthProvider = models.AuthProviderLocal
+	}
+	now := time.Now()
+	user.ID = r.nextID
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	r.nextID++
+	stored := *user
+	r.users[user.ID] = &stored
+	return nil
+}
+
+func (r *MemoryUserRepository) Update(user *models.User, columns ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.users[user.ID]
+	if !ok || len(columns) == 0 {
+		return nil
+	}
+	updated := *stored
+	src := reflect.ValueOf(user).Elem()
+	dst := reflect.ValueOf(&updated).Elem()
+	for _, column := range columns {
+		field := r.schema.LookUpField(column)
+		if field == nil {
+			return fmt.Errorf("用户表没有列 %s", column)
+		}
+		field.Set(context.Background(), dst, field.ReflectValueOf(context.Background(), src).Interface())
+	}
+	for id, existing := range r.users {
+		if id == updated.ID {
+			continue
+		}
+		if existing.Username == updated.Username || existing.Email == updated.Email {
+			return fmt.Errorf("用户名 %s 或邮箱 %s 已被其他用户使用", updated.Username, updated.Email)
+		}
+	}
+	updated.UpdatedAt = time.Now()
+	r.users[updated.ID] = &updated
+	return nil
+}
+
+func (r *MemoryUserRepository) Delete(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.users, id)
+	return nil
+}
+
+func (r *MemoryUserRepository) AdvanceTOTPCounter(id uint, counter int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok || user.TOTPLastCounter >= counter {
+		return false, nil
+	}
+	user.TOTPLastCounter = counter
+	return true, nil
+}
+
+func (r *MemoryUserRepository) ReplaceRecoveryCodes(id uint, current, next string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok || user.TOTPRecoveryCodes != current {
+		return false, nil
+	}
+	user.TOTPRecoveryCodes = next
+	return true, nil
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"gorm.io/gorm"
+)
+
+// ErrUserNotFound 按条件找不到用户 (已删除的用户同样视为不存在)
+var ErrUserNotFound = errors.New("用户不存在")
+
+// UserRepository 用户表的读写接口，AuthService 只通过它访问用户数据，
+// 会话、令牌等其他认证数据保存在创建 AuthService 时传入的数据库中
+type UserRepository interface {
+	FindByID(id uint) (*models.User, error)
+	FindByUsername(username string) (*models.User, error)
+	// FindByExternalID 按身份源和外部 ID 查找外部账号
+	FindByExternalID(provider, externalID string) (*models.User, error)
+	ExistsByUsername(username string) (bool, error)
+	// ExistsByEmail excludeID 不为 0 时忽略该用户自己
+	ExistsByEmail(email string, excludeID uint) (bool, error)
+	// List 按创建时间倒序分页，同时返回总数
+	List(offset, limit int) ([]models.User, int64, error)
+	ListActiveByProvider(provider string) ([]models.User, error)
+	// Create 创建用户，user.Password 为明文，保存前会被哈希
+	Create(user *models.User) error
+	// Update 按 user.ID 只更新 columns 中列出的列 (使用数据库列名)，零值也会写入
+	Update(user *models.User, columns ...string) error
+	Delete(id uint) error
+	// AdvanceTOTPCounter 只有 counter 大于已使用的时间步时才更新，返回是否更新成功，用于防止验证码被并发重放
+	AdvanceTOTPCounter(id uint, counter int64) (bool, error)
+	// ReplaceRecoveryCodes 只有当前恢复码仍为 current 时才替换为 next，返回是否替换成功
+	ReplaceRecoveryCodes(id uint, current, next string) (bool, error)
+}
+
+// GormUserRepository 基于 gorm 的用户仓库
+type GormUserRepository struct {
+	db *gorm.DB
+}
+
+func NewGormUserRepository(db *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{db: db}
+}
+
+func (r *GormUserRepository) FindByID(id uint) (*models.User, error) {
+	return r.first("id = ?", id)
+}
+
+func (r *GormUserRepository) FindByUsername(username string) (*models.User, error) {
+	return r.first("username = ?", username)
+}
+
+func (r *GormUserRepository) FindByExternalID(provider, externalID string) (*models.User, error) {
+	return r.first("auth_provider = ? AND external_id = ?", provider, externalID)
+}
+
+func (r *GormUserRepository) first(query string, args ...interface{}) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where(query, args...).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *GormUserRepository) ExistsByUsername(username string) (bool, error) {
+	return r.exists(r.db.Where("username = ?", username))
+}
+
+func (r *GormUserRepository) ExistsByEmail(email string, excludeID uint) (bool, error) {
+	query := r.db.Where("email = ?", email)
+	if excludeID != 0 {
+		query = query.Where("id != ?", excludeID)
+	}
+	return r.exists(query)
+}
+
+func (r *GormUserRepository) exists(query *gorm.DB) (bool, error) {
+	var count int64
+	if err := query.Model(&models.User{}).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *GormUserRepository) List(offset, limit int) ([]models.User, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.User{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []models.User
+	if err := r.db.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (r *GormUserRepository) ListActiveByProvider(provider string) ([]models.User, error) {
+	var users []models.User
+	if err := r.db.Where("auth_provider = ? AND is_active = ?", provider, true).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Create 密码由 User 的 BeforeCreate 钩子哈希
+func (r *GormUserRepository) Create(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+func (r *GormUserRepository) Update(user *models.User, columns ...string) error {
+	if len(columns) == 0 {
+		return nil
+	}
+	return r.db.Model(user).Select(columns).Updates(user).Error
+}
+
+func (r *GormUserRepository) Delete(id uint) error {
+	return r.db.Delete(&models.User{}, id).Error
+}
+
+func (r *GormUserRepository) AdvanceTOTPCounter(id uint, counter int64) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_counter < ?", id, counter).
+		Update("totp_last_counter", counter)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *GormUserRepository) ReplaceRecoveryCodes(id uint, current, next string) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_recovery_codes = ?", id, current).
+		Update("totp_recovery_codes", next)
+	return result.RowsAffected > 0, result.Error
+}
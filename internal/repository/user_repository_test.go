@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 两种实现运行同一组用例，保证内存实现可以替代数据库实现用于测试
+func TestUserRepositories(t *testing.T) {
+	implementations := map[string]func(t *testing.T) UserRepository{
+		"gorm": func(t *testing.T) UserRepository {
+			db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+			require.NoError(t, err)
+			sqlDB, err := db.DB()
+			require.NoError(t, err)
+			sqlDB.SetMaxOpenConns(1)
+			t.Cleanup(func() { sqlDB.Close() })
+			require.NoError(t, db.AutoMigrate(&models.User{}))
+			return NewGormUserRepository(db)
+		},
+		"memory": func(t *testing.T) UserRepository { return NewMemoryUserRepository() },
+	}
+	for name, newRepo := range implementations {
+		t.Run(name, func(t *testing.T) {
+			testUserRepository(t, newRepo(t))
+		})
+	}
+}
+
+func testUserRepository(t *testing.T, repo UserRepository) {
+	alice := &models.User{Username: "alice", Email: "alice@example.com", Password: "alice-pw", Role: "admin", IsActive: true}
+	require.NoError(t, repo.Create(alice))
+	assert.NotZero(t, alice.ID)
+	assert.NotEqual(t, "alice-pw", alice.Password, "保存前哈希密码")
+	time.Sleep(time.Millisecond)
+	bob := &models.User{Username: "bob", Email: "bob@example.com", Password: "bob-pw", IsActive: true,
+		AuthProvider: models.AuthProviderLDAP, ExternalID: "uid=bob"}
+	require.NoError(t, repo.Create(bob))
+	assert.Error(t, repo.Create(&models.User{Username: "alice", Email: "other@example.com", Password: "x"}), "用户名唯一")
+
+	found, err := repo.FindByUsername("alice")
+	require.NoError(t, err)
+	assert.True(t, found.CheckPassword("alice-pw"))
+	assert.Equal(t, models.AuthProviderLocal, found.AuthProvider)
+	found, err = repo.FindByExternalID(models.AuthProviderLDAP, "uid=bob")
+	require.NoError(t, err)
+	assert.Equal(t, bob.ID, found.ID)
+	_, err = repo.FindByID(999)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	exists, err := repo.ExistsByUsername("bob")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = repo.ExistsByEmail("alice@example.com", alice.ID)
+	require.NoError(t, err)
+	assert.False(t, exists, "排除自己")
+	exists, err = repo.ExistsByEmail("alice@example.com", bob.ID)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	users, total, err := repo.List(0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, users, 1)
+	assert.Equal(t, "bob", users[0].Username, "按创建时间倒序")
+
+	// 只更新指定的列，零值同样写入
+	update := &models.User{ID: alice.ID, Email: "new@example.com", Role: "ignored", IsActive: false}
+	require.NoError(t, repo.Update(update, "email", "is_active"))
+	found, err = repo.FindByID(alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", found.Email)
+	assert.False(t, found.IsActive)
+	assert.Equal(t, "admin", found.Role)
+
+	active, err := repo.ListActiveByProvider(models.AuthProviderLDAP)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, "bob", active[0].Username)
+
+	advanced, err := repo.AdvanceTOTPCounter(bob.ID, 10)
+	require.NoError(t, err)
+	assert.True(t, advanced)
+	advanced, err = repo.AdvanceTOTPCounter(bob.ID, 10)
+	require.NoError(t, err)
+	assert.False(t, advanced, "同一时间步不能重复使用")
+
+	require.NoError(t, repo.Update(&models.User{ID: bob.ID, TOTPRecoveryCodes: "a,b"}, "totp_recovery_codes"))
+	replaced, err := repo.ReplaceRecoveryCodes(bob.ID, "a,b", "b")
+	require.NoError(t, err)
+	assert.True(t, replaced)
+	replaced, err = repo.ReplaceRecoveryCodes(bob.ID, "a,b", "a")
+	require.NoError(t, err)
+	assert.False(t, replaced, "恢复码已变化时不替换")
+
+	require.NoError(t, repo.Delete(bob.ID))
+	_, err = repo.FindByID(bob.ID)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/pkg/database"
+	"gorm.io/gorm"
+)
+
+// 审计导出格式
+const (
+	AuditExportCSV  = "csv"
+	AuditExportJSON = "json"
+)
+
+const (
+	defaultAuditPageSize = 20
+	maxAuditPageSize     = 500
+	auditExportBatchSize = 500
+)
+
+var auditCSVHeader = []string{
+	"id", "time", "username", "role", "client_ip", "action", "cluster", "verb", "resource",
+	"namespace", "name", "method", "path", "status_code", "latency_ms", "body_hash", "target", "detail",
+}
+
+// AuditService 查询和导出审计事件
+type AuditService struct{}
+
+func NewAuditService() *AuditService {
+	return &AuditService{}
+}
+
+// Query 按条件分页查询审计事件，按时间倒序
+func (s *AuditService) Query(q *models.AuditQuery) (*models.AuditEventListResponse, error) {
+	if err := validateAuditQuery(q); err != nil {
+		return nil, err
+	}
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = defaultAuditPageSize
+	}
+	if q.PageSize > maxAuditPageSize {
+		q.PageSize = maxAuditPageSize
+	}
+
+	var total int64
+	if err := auditScope(q).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	items := []models.AuditEvent{}
+	err := auditScope(q).Order("created_at DESC, id DESC").
+		Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize).Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return &models.AuditEventListResponse{Items: items, Total: total, Page: q.Page, PageSize: q.PageSize}, nil
+}
+
+// Export 将符合条件的全部审计事件按时间顺序写入 w，分批读取避免一次性加载
+func (s *AuditService) Export(q *models.AuditQuery, format string, w io.Writer) error {
+	if format != AuditExportCSV && format != AuditExportJSON {
+		return NewValidationError("不支持的导出格式: " + format)
+	}
+	if err := validateAuditQuery(q); err != nil {
+		return err
+	}
+
+	var events []models.AuditEvent
+	if format == AuditExportCSV {
+		writer := csv.NewWriter(w)
+		if err := writer.Write(auditCSVHeader); err != nil {
+			return err
+		}
+		err := auditScope(q).Order("id").FindInBatches(&events, auditExportBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, event := range events {
+				if err := writer.Write(auditCSVRecord(&event)); err != nil {
+					return err
+				}
+			}
+			writer.Flush()
+			return writer.Error()
+		}).Error
+		if err != nil {
+			return err
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	// JSON 导出为一个数组，逐条编码
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	first := true
+	err := auditScope(q).Order("id").FindInBatches(&events, auditExportBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			if !first {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "]")
+	return err
+}
+
+func validateAuditQuery(q *models.AuditQuery) error {
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return NewValidationError("开始时间必须早于结束时间")
+	}
+	return nil
+}
+
+// auditScope 根据查询条件构造查询，空条件不过滤
+func auditScope(q *models.AuditQuery) *gorm.DB {
+	db := database.DB.Model(&models.AuditEvent{})
+	equals := map[string]string{
+		"username":  q.Username,
+		"action":    q.Action,
+		"cluster":   q.Cluster,
+		"verb":      q.Verb,
+		"resource":  q.Resource,
+		"namespace": q.Namespace,
+		"name":      q.Name,
+	}
+	for column, value := range equals {
+		if value != "" {
+			db = db.Where(column+" = ?", value)
+		}
+	}
+	if q.Status != 0 {
+		db = db.Where("status_code = ?", q.Status)
+	}
+	if q.Failed {
+		db = db.Where("status_code >= ?", 400)
+	}
+	if q.From != nil {
+		db = db.Where("created_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		db = db.Where("created_at < ?", *q.To)
+	}
+	return db
+}
+
+func auditCSVRecord(e *models.AuditEvent) []string {
+	return []string{
+		strconv.FormatUint(uint64(e.ID), 10), e.CreatedAt.UTC().Format(time.RFC3339), e.Username, e.Role, e.ClientIP,
+		e.Action, e.Cluster, e.Verb, e.Resource, e.Namespace, e.Name, e.Method, e.Path,
+		strconv.Itoa(e.StatusCode), strconv.FormatInt(e.LatencyMs, 10), e.BodyHash, e.Target, e.Detail,
+	}
+}
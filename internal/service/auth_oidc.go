@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/configs"
+	"github.com/ciliverse/cilikube/pkg/auth"
+	"github.com/ciliverse/cilikube/pkg/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDCStateTTL 从跳转到 IdP 到回调之间允许的最长时间，也是浏览器保存 state cookie 的时长
+const OIDCStateTTL = 10 * time.Minute
+
+var (
+	ErrOIDCDisabled     = errors.New("未启用 OIDC 单点登录")
+	ErrInvalidOIDCState = errors.New("登录请求无效或已过期，请重新登录")
+)
+
+// oidcLoginState 一次授权请求的 PKCE verifier 和 nonce，只保存在服务端，按 state 查找且只能使用一次
+type oidcLoginState struct {
+	verifier  string
+	nonce     string
+	expiresAt time.Time
+}
+
+// OIDCEnabled 是否启用了 OIDC 单点登录
+func (s *AuthService) OIDCEnabled() bool {
+	return configs.GlobalConfig != nil && configs.GlobalConfig.OIDC.Enabled
+}
+
+// StartOIDCLogin 生成 state、nonce 和 PKCE verifier，返回跳转到 IdP 的授权地址和 state。
+// 调用方需要把 state 保存在发起登录的浏览器中 (cookie)，回调时一并提交以证明是同一个浏览器
+func (s *AuthService) StartOIDCLogin(ctx context.Context) (string, string, error) {
+	provider, err := s.oidcProvider(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	state, err := auth.GenerateOpaqueToken(24)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := auth.GenerateOpaqueToken(24)
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	s.oidcMu.Lock()
+	now := time.Now()
+	for key, pending := range s.oidcStates {
+		if now.After(pending.expiresAt) {
+			delete(s.oidcStates, key)
+		}
+	}
+	s.oidcStates[state] = oidcLoginState{verifier: verifier, nonce: nonce, expiresAt: now.Add(OIDCStateTTL)}
+	s.oidcMu.Unlock()
+
+	return provider.AuthCodeURL(state, nonce, verifier), state, nil
+}
+
+// CompleteOIDCLogin 处理 IdP 回调：校验 state、换取并校验 ID Token、创建或更新本地用户，
+// 最后按本地的两步验证设置签发本系统的令牌或要求完成第二步
+// browserState 是发起登录时保存在浏览器中的 state，与回调参数不一致说明回调链接不是本浏览器发起的 (登录 CSRF)
+func (s *AuthService) CompleteOIDCLogin(ctx context.Context, state, browserState, code string, client models.ClientInfo) (*models.LoginResponse, error) {
+	provider, err := s.oidcProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return nil, ErrInvalidOIDCState
+	}
+
+	s.oidcMu.Lock()
+	pending, ok := s.oidcStates[state]
+	delete(s.oidcStates, state)
+	s.oidcMu.Unlock()
+	if !ok || time.Now().After(pending.expiresAt) || code == "" {
+		return nil, ErrInvalidOIDCState
+	}
+
+	claims, err := provider.Exchange(ctx, code, pending.verifier, pending.nonce)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.provisionOIDCUser(claims)
+	if err != nil {
+		return nil, err
+	}
+	// 与密码登录相同：启用了两步验证或安全策略要求绑定时只返回 mfa_token，由客户端完成第二步
+	return s.completeLogin(user, client)
+}
+
+// oidcProvider 第一次使用时才做发现，避免 IdP 不可用时影响服务启动；发现失败会在下次请求时重试
+func (s *AuthService) oidcProvider(ctx context.Context) (*oidc.Provider, error) {
+	if !s.OIDCEnabled() {
+		return nil, ErrOIDCDisabled
+	}
+	s.oidcMu.Lock()
+	defer s.oidcMu.Unlock()
+	if s.provider != nil {
+		return s.provider, nil
+	}
+	cfg := configs.GlobalConfig.OIDC
+	provider, err := oidc.Discover(ctx, oidc.Config{
+		IssuerURL:     cfg.IssuerURL,
+		ClientID:      cfg.ClientID,
+		ClientSecret:  cfg.ClientSecret,
+		RedirectURL:   cfg.RedirectURL,
+		Scopes:        cfg.Scopes,
+		UsernameClaim: cfg.UsernameClaim,
+		GroupsClaim:   cfg.GroupsClaim,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.provider = provider
+	return provider, nil
+}
+
+// provisionOIDCUser 按 sub 查找外部账号，首次登录时自动创建；每次登录都按 IdP 分组重新计算角色
+func (s *AuthService) provisionOIDCUser(claims *oidc.Claims) (*models.User, error) {
+	cfg := configs.GlobalConfig.OIDC
+	username := claims.Username
+	if username == "" && claims.Email != "" {
+		username = strings.Split(claims.Email, "@")[0]
+	}
+	if username == "" {
+		username = claims.Subject
+	}
+	email := claims.Email
+	if email == "" {
+		email = fmt.Sprintf("%s@%s", claims.Subject, oidcIssuerHost())
+	}
+	return upsertExternalUser(s.users, externalIdentity{
+		Provider:   models.AuthProviderOIDC,
+		ExternalID: claims.Subject,
+		Username:   username,
+		Email:      email,
+		Role:       mapGroupRole(cfg.GroupRoles, cfg.DefaultRole, claims.Groups),
+	})
+}
+
+func oidcIssuerHost() string {
+	if u, err := url.Parse(configs.GlobalConfig.OIDC.IssuerURL); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return "oidc.local"
+}
@@ -1,65 +1,338 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"log"
+	"sync"
 	"time"
 
+	"github.com/casbin/casbin/v2"
 	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/configs"
+	"github.com/ciliverse/cilikube/internal/repository"
 	"github.com/ciliverse/cilikube/pkg/auth"
-	"github.com/ciliverse/cilikube/pkg/database"
+	"github.com/ciliverse/cilikube/pkg/oidc"
 	"gorm.io/gorm"
 )
 
-type AuthService struct{}
+type AuthService struct {
+	// users 用户记录通过仓库读写
+	users repository.UserRepository
+	// db 保存会话、吊销列表、密码历史、API token、登录失败记录和安全策略
+	db *gorm.DB
+	// authenticators 按顺序尝试的用户名密码认证方式
+	authenticators []Authenticator
+	// enforcer 用于判断用户是否有写权限，为 nil 时不执行强制两步验证策略
+	enforcer *casbin.Enforcer
+
+	mfaMu         sync.Mutex
+	mfaChallenges map[string]*mfaChallenge
+
+	oidcMu     sync.Mutex
+	provider   *oidc.Provider
+	oidcStates map[string]oidcLoginState
+}
+
+// NewAuthService 本地账号总是可用，启用 LDAP 时在本地账号之后尝试 LDAP；e 可以为 nil。
+// 用户记录保存在 users 中，其余认证数据保存在 db 中
+func NewAuthService(users repository.UserRepository, db *gorm.DB, e *casbin.Enforcer) *AuthService {
+	authenticators := []Authenticator{NewLocalAuthenticator(users)}
+	if configs.GlobalConfig != nil && configs.GlobalConfig.LDAP.Enabled {
+		authenticators = append(authenticators, NewLDAPAuthenticator(configs.GlobalConfig.LDAP, users, db))
+	}
+	return &AuthService{
+		users:          users,
+		db:             db,
+		authenticators: authenticators,
+		enforcer:       e,
+		mfaChallenges:  map[string]*mfaChallenge{},
+		oidcStates:     map[string]oidcLoginState{},
+	}
+}
+
+// refreshTokenBytes 刷新令牌的随机字节数
+const refreshTokenBytes = 32
+
+var ErrInvalidRefreshToken = errors.New("刷新令牌无效或已过期，请重新登录")
+
+// Login 用户登录，用户名或 IP 被锁定时直接拒绝；依次尝试各认证方式，启用了两步验证时只返回 mfa_token，否则创建新的会话并签发令牌
+func (s *AuthService) Login(req *models.LoginRequest, client models.ClientInfo) (*models.LoginResponse, error) {
+	if err := s.checkLoginLock(req.Username, client.IP); err != nil {
+		return nil, err
+	}
+	user, err := s.authenticate(context.Background(), req.Username, req.Password)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			s.recordLoginFailure(req.Username, client.IP)
+		}
+		return nil, err
+	}
+	// 失败次数在真正签发会话时才清除 (见 startSession)，否则已知密码的人可以反复重新登录来重置计数并继续猜验证码
+	return s.completeLogin(user, client)
+}
+
+// authenticate 凭据错误时继续尝试下一个认证方式；其他错误 (如目录服务不可用) 在全部失败后返回
+func (s *AuthService) authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	var lastErr error
+	for _, authenticator := range s.authenticators {
+		user, err := authenticator.Authenticate(ctx, username, password)
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, ErrInvalidCredentials) {
+			log.Printf("%s 认证失败: %v", authenticator.Name(), err)
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// RefreshToken 使用刷新令牌换取新的 access token，同时轮换刷新令牌
+// 已被轮换掉的刷新令牌再次出现说明令牌可能泄露，整个会话会被吊销
+func (s *AuthService) RefreshToken(refreshToken string, client models.ClientInfo) (*models.LoginResponse, error) {
+	hash := auth.HashToken(refreshToken)
+	now := time.Now()
+
+	var session models.Session
+	err := s.db.Where("refresh_token_hash = ?", hash).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var reused models.Session
+		if s.db.Where("previous_token_hash = ? AND revoked_at IS NULL", hash).First(&reused).Error == nil {
+			log.Printf("检测到会话 %d (用户 %d) 的刷新令牌被重复使用，吊销该会话", reused.ID, reused.UserID)
+			if err := revokeSessions(s.db, "id = ?", reused.ID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.RevokedAt != nil || !session.ExpiresAt.After(now) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// 用户被禁用或删除后不能再刷新
+	user, err := s.users.FindByID(session.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrInvalidRefreshToken
+	}
+	// 安全策略开启后，未绑定两步验证的写权限用户需要重新登录完成绑定
+	if !user.TOTPEnabled {
+		required, err := s.twoFactorRequired(user)
+		if err != nil {
+			return nil, err
+		}
+		if required {
+			return nil, ErrTwoFactorRequired
+		}
+	}
 
-// Login 用户登录
-func (s *AuthService) Login(req *models.LoginRequest) (*models.LoginResponse, error) {
-	var user models.User
+	return s.rotateSession(user, session, client)
+}
 
-	// 根据用户名查找用户
-	err := database.DB.Where("username = ? AND is_active = ?", req.Username, true).First(&user).Error
+// rotateSession 以查询时的刷新令牌为条件轮换会话。并发请求使用同一个刷新令牌时都能查到会话，
+// 但只有一个能完成条件更新，其余按重复使用处理并吊销整个会话
+func (s *AuthService) rotateSession(user *models.User, session models.Session, client models.ClientInfo) (*models.LoginResponse, error) {
+	hash := session.RefreshTokenHash
+	previousAccessTokenID, previousAccessExpiresAt := session.AccessTokenID, session.AccessExpiresAt
+	rotated, err := renewRefreshToken(&session, client)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("用户名或密码错误")
+		return nil, err
+	}
+	session.PreviousTokenHash = hash
+	result := s.db.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", session.ID, hash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":  session.RefreshTokenHash,
+			"previous_token_hash": session.PreviousTokenHash,
+			"expires_at":          session.ExpiresAt,
+			"last_used_at":        session.LastUsedAt,
+			"user_agent":          session.UserAgent,
+			"client_ip":           session.ClientIP,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		log.Printf("检测到会话 %d (用户 %d) 的刷新令牌被并发重复使用，吊销该会话", session.ID, session.UserID)
+		if err := revokeSessions(s.db, "id = ?", session.ID); err != nil {
+			return nil, err
 		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// 旧的 access token 随轮换一并失效，保证每个会话同时只有一个有效的 access token
+	if err := auth.RevokeToken(s.db, previousAccessTokenID, previousAccessExpiresAt); err != nil {
 		return nil, err
 	}
+	return s.issueAccessToken(user, &session, rotated)
+}
 
-	// 验证密码
-	if !user.CheckPassword(req.Password) {
-		return nil, errors.New("用户名或密码错误")
+// issueTokens 为新会话生成刷新令牌和 access token 并保存会话
+func (s *AuthService) issueTokens(user *models.User, session *models.Session, client models.ClientInfo) (*models.LoginResponse, error) {
+	refreshToken, err := renewRefreshToken(session, client)
+	if err != nil {
+		return nil, err
+	}
+	// 先保存以获得会话 ID，再签发包含会话 ID 的 access token
+	if err := s.db.Create(session).Error; err != nil {
+		return nil, err
 	}
+	return s.issueAccessToken(user, session, refreshToken)
+}
 
-	// 更新最后登录时间
+// renewRefreshToken 生成新的刷新令牌，更新会话中的令牌哈希、有效期和设备信息，不保存
+func renewRefreshToken(session *models.Session, client models.ClientInfo) (string, error) {
+	refreshToken, err := auth.GenerateOpaqueToken(refreshTokenBytes)
+	if err != nil {
+		return "", err
+	}
 	now := time.Now()
-	user.LastLogin = &now
-	database.DB.Save(&user)
+	session.RefreshTokenHash = auth.HashToken(refreshToken)
+	session.ExpiresAt = now.Add(configs.GlobalConfig.JWT.RefreshExpireDuration)
+	session.LastUsedAt = now
+	session.UserAgent = truncate(client.UserAgent, 255)
+	session.ClientIP = client.IP
+	return refreshToken, nil
+}
 
-	// 生成JWT token
-	token, expiresAt, err := auth.GenerateToken(&user)
+// issueAccessToken 签发包含会话 ID 的 access token 并记录到会话中。
+// 会话在此期间被吊销 (如检测到刷新令牌重复使用) 时，刚签发的 token 同样吊销
+func (s *AuthService) issueAccessToken(user *models.User, session *models.Session, refreshToken string) (*models.LoginResponse, error) {
+	token, claims, err := auth.GenerateToken(user, session.ID)
 	if err != nil {
 		return nil, err
 	}
+	session.AccessTokenID = claims.ID
+	session.AccessExpiresAt = claims.ExpiresAt.Time
+	result := s.db.Model(&models.Session{}).Where("id = ? AND revoked_at IS NULL", session.ID).
+		Updates(map[string]interface{}{"access_token_id": session.AccessTokenID, "access_expires_at": session.AccessExpiresAt})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if err := auth.RevokeToken(s.db, session.AccessTokenID, session.AccessExpiresAt); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
 
 	return &models.LoginResponse{
-		Token:     token,
-		ExpiresAt: expiresAt,
-		User:      user.ToResponse(),
+		Token:            token,
+		ExpiresAt:        session.AccessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+		User:             user.ToResponse(),
 	}, nil
 }
 
+// Logout 吊销当前会话及其 access token
+func (s *AuthService) Logout(userID, sessionID uint) error {
+	return revokeSessions(s.db, "user_id = ? AND id = ?", userID, sessionID)
+}
+
+// LogoutAll 吊销用户的所有会话
+func (s *AuthService) LogoutAll(userID uint) error {
+	return revokeSessions(s.db, "user_id = ?", userID)
+}
+
+// RevokeSession 吊销用户的指定会话，会话不存在或已失效时返回错误
+func (s *AuthService) RevokeSession(userID, sessionID uint) error {
+	var count int64
+	s.db.Model(&models.Session{}).Where("user_id = ? AND id = ? AND revoked_at IS NULL AND expires_at > ?", userID, sessionID, time.Now()).Count(&count)
+	if count == 0 {
+		return errors.New("会话不存在或已失效")
+	}
+	return s.Logout(userID, sessionID)
+}
+
+// ListSessions 列出用户当前有效的会话，currentSessionID 对应的会话标记为当前会话
+func (s *AuthService) ListSessions(userID, currentSessionID uint) ([]models.SessionResponse, error) {
+	var sessions []models.Session
+	err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]models.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, models.SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			ClientIP:   session.ClientIP,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+	return responses, nil
+}
+
+// revokeSessions 吊销查询条件匹配的所有未吊销会话，并把它们当前的 access token 加入吊销列表
+func revokeSessions(db *gorm.DB, query interface{}, args ...interface{}) error {
+	var ids []uint
+	if err := db.Model(&models.Session{}).Where(query, args...).Where("revoked_at IS NULL").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	// 先标记吊销再读取当前的 access token：并发轮换在标记之前写入的 token 会在这里被读到，
+	// 之后写入的会因会话已吊销被 issueAccessToken 自行吊销
+	if err := db.Model(&models.Session{}).Where("id IN ?", ids).Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	var sessions []models.Session
+	if err := db.Where("id IN ?", ids).Find(&sessions).Error; err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if err := auth.RevokeToken(db, session.AccessTokenID, session.AccessExpiresAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func truncate(s string, limit int) string {
+	if len(s) > limit {
+		return s[:limit]
+	}
+	return s
+}
+
 // Register 用户注册
 func (s *AuthService) Register(req *models.RegisterRequest) (*models.UserResponse, error) {
+	if err := validatePassword(req.Password, req.Username); err != nil {
+		return nil, err
+	}
+
 	// 检查用户名是否已存在
-	var count int64
-	database.DB.Model(&models.User{}).Where("username = ?", req.Username).Count(&count)
-	if count > 0 {
+	exists, err := s.users.ExistsByUsername(req.Username)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
 		return nil, errors.New("用户名已存在")
 	}
 
 	// 检查邮箱是否已存在
-	database.DB.Model(&models.User{}).Where("email = ?", req.Email).Count(&count)
-	if count > 0 {
+	if exists, err = s.users.ExistsByEmail(req.Email, 0); err != nil {
+		return nil, err
+	}
+	if exists {
 		return nil, errors.New("邮箱已存在")
 	}
 
@@ -67,12 +340,12 @@ func (s *AuthService) Register(req *models.RegisterRequest) (*models.UserRespons
 	user := &models.User{
 		Username: req.Username,
 		Email:    req.Email,
-		Password: req.Password, // 密码会在BeforeCreate钩子中加密
+		Password: req.Password, // 密码由仓库在保存前加密
 		Role:     "user",
 		IsActive: true,
 	}
 
-	if err := database.DB.Create(user).Error; err != nil {
+	if err := s.users.Create(user); err != nil {
 		return nil, err
 	}
 
@@ -82,13 +355,8 @@ func (s *AuthService) Register(req *models.RegisterRequest) (*models.UserRespons
 
 // GetProfile 获取用户资料
 func (s *AuthService) GetProfile(userID uint) (*models.UserResponse, error) {
-	var user models.User
-
-	err := database.DB.First(&user, userID).Error
+	user, err := s.users.FindByID(userID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("用户不存在")
-		}
 		return nil, err
 	}
 
@@ -98,26 +366,23 @@ func (s *AuthService) GetProfile(userID uint) (*models.UserResponse, error) {
 
 // UpdateProfile 更新用户资料
 func (s *AuthService) UpdateProfile(userID uint, req *models.UpdateProfileRequest) (*models.UserResponse, error) {
-	var user models.User
-
-	err := database.DB.First(&user, userID).Error
+	user, err := s.users.FindByID(userID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("用户不存在")
-		}
 		return nil, err
 	}
 
 	// 检查邮箱是否被其他用户使用
-	var count int64
-	database.DB.Model(&models.User{}).Where("email = ? AND id != ?", req.Email, userID).Count(&count)
-	if count > 0 {
+	exists, err := s.users.ExistsByEmail(req.Email, userID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
 		return nil, errors.New("邮箱已被其他用户使用")
 	}
 
 	// 更新用户信息
 	user.Email = req.Email
-	if err := database.DB.Save(&user).Error; err != nil {
+	if err := s.users.Update(user, "email"); err != nil {
 		return nil, err
 	}
 
@@ -125,43 +390,47 @@ func (s *AuthService) UpdateProfile(userID uint, req *models.UpdateProfileReques
 	return &response, nil
 }
 
-// ChangePassword 修改密码
+// ChangePassword 修改密码，新密码需要满足密码策略且不能与最近使用过的密码相同
 func (s *AuthService) ChangePassword(userID uint, req *models.ChangePasswordRequest) error {
-	var user models.User
-
-	err := database.DB.First(&user, userID).Error
+	user, err := s.users.FindByID(userID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("用户不存在")
-		}
 		return err
 	}
 
+	if user.IsExternal() {
+		return errors.New("外部账号请在身份提供方修改密码")
+	}
+
 	// 验证旧密码
 	if !user.CheckPassword(req.OldPassword) {
 		return errors.New("旧密码错误")
 	}
+	if err := validatePassword(req.NewPassword, user.Username); err != nil {
+		return err
+	}
 
-	// 更新密码
+	if err := checkPasswordHistory(s.db, user, req.NewPassword); err != nil {
+		return err
+	}
+	// 先记录旧密码再更新，更新失败时多出的历史记录只会让检查更严格
+	if err := savePasswordHistory(s.db, user); err != nil {
+		return err
+	}
 	user.Password = req.NewPassword
 	if err := user.HashPassword(); err != nil {
 		return err
 	}
-
-	return database.DB.Save(&user).Error
+	user.MustChangePassword = false
+	if err := s.users.Update(user, "password", "must_change_password"); err != nil {
+		return err
+	}
+	// 修改密码后所有会话都需要重新登录
+	return s.LogoutAll(userID)
 }
 
 // GetUserList 获取用户列表（管理员功能）
 func (s *AuthService) GetUserList(page, pageSize int) ([]models.UserResponse, int64, error) {
-	var users []models.User
-	var total int64
-
-	// 获取总数
-	database.DB.Model(&models.User{}).Count(&total)
-
-	// 分页查询
-	offset := (page - 1) * pageSize
-	err := database.DB.Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&users).Error
+	users, total, err := s.users.List((page-1)*pageSize, pageSize)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -175,12 +444,24 @@ func (s *AuthService) GetUserList(page, pageSize int) ([]models.UserResponse, in
 	return responses, total, nil
 }
 
-// UpdateUserStatus 更新用户状态（管理员功能）
+// UpdateUserStatus 更新用户状态（管理员功能），禁用用户时吊销其所有会话
 func (s *AuthService) UpdateUserStatus(userID uint, isActive bool) error {
-	return database.DB.Model(&models.User{}).Where("id = ?", userID).Update("is_active", isActive).Error
+	if err := s.users.Update(&models.User{ID: userID, IsActive: isActive}, "is_active"); err != nil {
+		return err
+	}
+	if !isActive {
+		return s.LogoutAll(userID)
+	}
+	return nil
 }
 
-// DeleteUser 删除用户（管理员功能）
+// DeleteUser 删除用户（管理员功能），同时吊销其所有会话和 API token
 func (s *AuthService) DeleteUser(userID uint) error {
-	return database.DB.Delete(&models.User{}, userID).Error
+	if err := s.users.Delete(userID); err != nil {
+		return err
+	}
+	if err := s.db.Where("user_id = ?", userID).Delete(&models.APIToken{}).Error; err != nil {
+		return err
+	}
+	return s.LogoutAll(userID)
 }
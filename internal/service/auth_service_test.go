@@ -0,0 +1,261 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/configs"
+	"github.com/ciliverse/cilikube/internal/repository"
+	"github.com/ciliverse/cilikube/pkg/auth"
+	"github.com/ciliverse/cilikube/pkg/totp"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupAuthServiceTest 用户保存在内存仓库中，传给 AuthService 的数据库只建会话、令牌等其余认证数据的表，
+// 因此 AuthService 任何绕过仓库直接访问用户表的操作都会失败；不修改全局的 database.DB
+func setupAuthServiceTest(t *testing.T) (*AuthService, *repository.MemoryUserRepository) {
+	configs.GlobalConfig = &configs.Config{
+		JWT: configs.JWTConfig{SecretKey: "test-secret", ExpireDuration: time.Hour, RefreshExpireDuration: 24 * time.Hour, Issuer: "cilikube-test"},
+		Security: configs.SecurityConfig{
+			Login:    configs.LoginProtectionConfig{MaxAttempts: 5, IPMaxAttempts: 20, Window: time.Minute, LockDuration: time.Minute},
+			Password: configs.PasswordPolicyConfig{MinLength: 8, MinCharClasses: 2, HistorySize: 3},
+		},
+	}
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		sqlDB.Close()
+		configs.GlobalConfig = nil
+	})
+	require.NoError(t, db.AutoMigrate(&models.Session{}, &models.RevokedToken{}, &models.APIToken{},
+		&models.SecurityPolicy{}, &models.LoginAttempt{}, &models.PasswordHistory{}, &models.AuditEvent{}))
+
+	users := repository.NewMemoryUserRepository()
+	require.NoError(t, users.Create(&models.User{Username: "admin", Email: "admin@example.com", Password: "Admin-pass1", Role: "admin", IsActive: true}))
+	return NewAuthService(users, db, nil), users
+}
+
+func TestAuthService_RegisterAndLogin(t *testing.T) {
+	s, users := setupAuthServiceTest(t)
+	client := models.ClientInfo{IP: "10.0.0.1", UserAgent: "test"}
+
+	registered, err := s.Register(&models.RegisterRequest{Username: "bob", Email: "bob@example.com", Password: "Secret-pass1"})
+	require.NoError(t, err)
+	assert.Equal(t, "user", registered.Role)
+
+	_, err = s.Register(&models.RegisterRequest{Username: "bob", Email: "other@example.com", Password: "Secret-pass1"})
+	assert.EqualError(t, err, "用户名已存在")
+	_, err = s.Register(&models.RegisterRequest{Username: "carol", Email: "bob@example.com", Password: "Secret-pass1"})
+	assert.EqualError(t, err, "邮箱已存在")
+	_, err = s.Register(&models.RegisterRequest{Username: "carol", Email: "carol@example.com", Password: "short"})
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr, "密码不满足策略")
+
+	login, err := s.Login(&models.LoginRequest{Username: "bob", Password: "Secret-pass1"}, client)
+	require.NoError(t, err)
+	assert.NotEmpty(t, login.Token)
+	assert.NotEmpty(t, login.RefreshToken)
+	stored, err := users.FindByUsername("bob")
+	require.NoError(t, err)
+	assert.NotNil(t, stored.LastLogin, "登录时更新最后登录时间")
+
+	_, err = s.Login(&models.LoginRequest{Username: "bob", Password: "wrong-pass-1"}, client)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = s.Login(&models.LoginRequest{Username: "nobody", Password: "whatever-1"}, client)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	refreshed, err := s.RefreshToken(login.RefreshToken, client)
+	require.NoError(t, err)
+	assert.NotEqual(t, login.RefreshToken, refreshed.RefreshToken, "刷新时轮换刷新令牌")
+}
+
+func TestAuthService_Profile(t *testing.T) {
+	s, _ := setupAuthServiceTest(t)
+	bob, err := s.Register(&models.RegisterRequest{Username: "bob", Email: "bob@example.com", Password: "Secret-pass1"})
+	require.NoError(t, err)
+
+	profile, err := s.GetProfile(bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "bob@example.com", profile.Email)
+	_, err = s.GetProfile(999)
+	assert.ErrorIs(t, err, repository.ErrUserNotFound)
+
+	_, err = s.UpdateProfile(bob.ID, &models.UpdateProfileRequest{Email: "admin@example.com"})
+	assert.EqualError(t, err, "邮箱已被其他用户使用")
+	profile, err = s.UpdateProfile(bob.ID, &models.UpdateProfileRequest{Email: "robert@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "robert@example.com", profile.Email)
+
+	assert.EqualError(t, s.ChangePassword(bob.ID, &models.ChangePasswordRequest{OldPassword: "wrong", NewPassword: "Secret-pass2"}), "旧密码错误")
+	require.NoError(t, s.ChangePassword(bob.ID, &models.ChangePasswordRequest{OldPassword: "Secret-pass1", NewPassword: "Secret-pass2"}))
+	var validationErr *ValidationError
+	assert.ErrorAs(t, s.ChangePassword(bob.ID, &models.ChangePasswordRequest{OldPassword: "Secret-pass2", NewPassword: "Secret-pass1"}), &validationErr, "不能重复使用最近的密码")
+
+	client := models.ClientInfo{IP: "10.0.0.1"}
+	_, err = s.Login(&models.LoginRequest{Username: "bob", Password: "Secret-pass1"}, client)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = s.Login(&models.LoginRequest{Username: "bob", Password: "Secret-pass2"}, client)
+	assert.NoError(t, err)
+}
+
+func TestAuthService_AdminFlows(t *testing.T) {
+	s, _ := setupAuthServiceTest(t)
+	client := models.ClientInfo{IP: "10.0.0.1"}
+	var ids []uint
+	for _, name := range []string{"bob", "carol", "dave"} {
+		user, err := s.Register(&models.RegisterRequest{Username: name, Email: name + "@example.com", Password: "Secret-pass1"})
+		require.NoError(t, err)
+		ids = append(ids, user.ID)
+		time.Sleep(time.Millisecond)
+	}
+
+	list, total, err := s.GetUserList(1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+	require.Len(t, list, 2)
+	assert.Equal(t, "dave", list[0].Username, "最新创建的用户排在前面")
+	list, _, err = s.GetUserList(2, 2)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "admin", list[1].Username)
+
+	// 禁用后不能登录，已有的刷新令牌也失效
+	login, err := s.Login(&models.LoginRequest{Username: "bob", Password: "Secret-pass1"}, client)
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateUserStatus(ids[0], false))
+	_, err = s.Login(&models.LoginRequest{Username: "bob", Password: "Secret-pass1"}, client)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = s.RefreshToken(login.RefreshToken, client)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	require.NoError(t, s.UpdateUserStatus(ids[0], true))
+	_, err = s.Login(&models.LoginRequest{Username: "bob", Password: "Secret-pass1"}, client)
+	assert.NoError(t, err)
+
+	require.NoError(t, s.DeleteUser(ids[1]))
+	_, err = s.GetProfile(ids[1])
+	assert.ErrorIs(t, err, repository.ErrUserNotFound)
+	_, total, err = s.GetUserList(1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+}
+
+func TestAuthService_TwoFactorFailuresSurviveRelogin(t *testing.T) {
+	s, users := setupAuthServiceTest(t)
+	client := models.ClientInfo{IP: "10.0.0.2", UserAgent: "test"}
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	require.NoError(t, users.Create(&models.User{Username: "dave", Email: "dave@example.com", Password: "Secret-pass1", Role: "user", IsActive: true, TOTPEnabled: true, TOTPSecret: secret}))
+
+	// 每次重新输入正确密码都能拿到新的 mfa_token，但不会清除之前验证码错误的次数
+	locked := false
+	for cycle := 0; cycle < 3 && !locked; cycle++ {
+		login, err := s.Login(&models.LoginRequest{Username: "dave", Password: "Secret-pass1"}, client)
+		require.NoError(t, err, "第 %d 轮", cycle+1)
+		require.True(t, login.TwoFactorRequired)
+		for i := 0; i < 3; i++ {
+			_, err = s.VerifyTwoFactorLogin(&models.TwoFactorLoginRequest{MFAToken: login.MFAToken, Code: "not-a-code"}, client)
+			var lockedErr *AccountLockedError
+			if errors.As(err, &lockedErr) {
+				locked = true
+				break
+			}
+			assert.ErrorIs(t, err, ErrInvalidTOTPCode)
+		}
+	}
+	require.True(t, locked, "连续 MaxAttempts 次验证码错误后锁定用户名")
+
+	_, err = s.Login(&models.LoginRequest{Username: "dave", Password: "Secret-pass1"}, client)
+	var lockedErr *AccountLockedError
+	assert.ErrorAs(t, err, &lockedErr)
+}
+
+func TestAuthService_ConcurrentRefreshIsReuse(t *testing.T) {
+	s, users := setupAuthServiceTest(t)
+	client := models.ClientInfo{IP: "10.0.0.3", UserAgent: "test"}
+	login, err := s.Login(&models.LoginRequest{Username: "admin", Password: "Admin-pass1"}, client)
+	require.NoError(t, err)
+	user, err := users.FindByUsername("admin")
+	require.NoError(t, err)
+
+	// 模拟多个请求同时用同一个刷新令牌查到了会话，再并发轮换
+	var session models.Session
+	require.NoError(t, s.db.Where("refresh_token_hash = ?", auth.HashToken(login.RefreshToken)).First(&session).Error)
+	const workers = 4
+	results := make(chan *models.LoginResponse, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := s.rotateSession(user, session, client)
+			if err != nil {
+				assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+				return
+			}
+			results <- resp
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	// 只有一个请求完成轮换，其余视为重复使用并吊销会话，胜出请求拿到的令牌也随之失效
+	var succeeded []*models.LoginResponse
+	for resp := range results {
+		succeeded = append(succeeded, resp)
+	}
+	require.Len(t, succeeded, 1)
+	require.NoError(t, s.db.First(&session, session.ID).Error)
+	assert.NotNil(t, session.RevokedAt)
+	_, err = s.RefreshToken(succeeded[0].RefreshToken, client)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	claims, err := auth.ParseToken(succeeded[0].Token)
+	require.NoError(t, err)
+	var revoked int64
+	require.NoError(t, s.db.Model(&models.RevokedToken{}).Where("token_id = ?", claims.ID).Count(&revoked).Error)
+	assert.EqualValues(t, 1, revoked)
+}
+
+func TestAuthService_LockoutEscalatesToMax(t *testing.T) {
+	s, _ := setupAuthServiceTest(t)
+	configs.GlobalConfig.Security.Login = configs.LoginProtectionConfig{MaxAttempts: 5, Window: 15 * time.Minute, LockDuration: time.Minute, MaxLockout: time.Hour}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	loginClock = func() time.Time { return now }
+	t.Cleanup(func() { loginClock = time.Now })
+
+	// lockCycle 连续失败直到锁定，返回锁定时长，并把时钟拨到锁定结束后 idle
+	lockCycle := func(idle time.Duration) time.Duration {
+		for i := 0; i < 5; i++ {
+			require.NoError(t, s.checkLoginLock("erin", "10.0.0.4"))
+			s.recordLoginFailure("erin", "10.0.0.4")
+		}
+		var lockedErr *AccountLockedError
+		require.ErrorAs(t, s.checkLoginLock("erin", "10.0.0.4"), &lockedErr)
+		duration := lockedErr.Until.Sub(now)
+		now = lockedErr.Until.Add(idle)
+		return duration
+	}
+
+	// 锁定结束后立即再次失败，时长逐轮翻倍并停在 MaxLockout，即使单轮锁定超过统计窗口
+	var durations []time.Duration
+	for cycle := 0; cycle < 8; cycle++ {
+		durations = append(durations, lockCycle(time.Second))
+	}
+	assert.Equal(t, []time.Duration{
+		time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute,
+		16 * time.Minute, 32 * time.Minute, time.Hour, time.Hour,
+	}, durations)
+
+	// 最近一次锁定结束后安静超过 MaxLockout 才恢复为初始时长
+	now = now.Add(time.Hour)
+	assert.Equal(t, time.Minute, lockCycle(time.Second))
+}
@@ -0,0 +1,387 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/internal/repository"
+	"github.com/ciliverse/cilikube/pkg/auth"
+	"github.com/ciliverse/cilikube/pkg/totp"
+)
+
+const (
+	// mfaChallengeTTL 密码验证通过后完成第二步的最长时间
+	mfaChallengeTTL = 5 * time.Minute
+	// mfaMaxAttempts 每次登录允许提交验证码的次数，超过后需要重新输入密码
+	mfaMaxAttempts    = 5
+	recoveryCodeCount = 10
+	totpIssuer        = "CiliKube"
+)
+
+var (
+	ErrInvalidMFAToken   = errors.New("两步验证请求无效或已过期，请重新登录")
+	ErrInvalidTOTPCode   = errors.New("验证码错误")
+	ErrTwoFactorRequired = errors.New("安全策略要求启用两步验证，请重新登录并完成绑定")
+)
+
+// mfaChallenge 密码验证通过、等待第二步的登录，按 mfa_token 查找；setup 表示需要先绑定身份验证器
+type mfaChallenge struct {
+	userID    uint
+	setup     bool
+	attempts  int
+	expiresAt time.Time
+}
+
+// completeLogin 密码验证通过后决定是直接签发令牌，还是要求验证码或先绑定身份验证器
+func (s *AuthService) completeLogin(user *models.User, client models.ClientInfo) (*models.LoginResponse, error) {
+	if user.TOTPEnabled {
+		token, err := s.newMFAChallenge(user.ID, false)
+		if err != nil {
+			return nil, err
+		}
+		return &models.LoginResponse{User: user.ToResponse(), TwoFactorRequired: true, MFAToken: token}, nil
+	}
+	required, err := s.twoFactorRequired(user)
+	if err != nil {
+		return nil, err
+	}
+	if required {
+		token, err := s.newMFAChallenge(user.ID, true)
+		if err != nil {
+			return nil, err
+		}
+		return &models.LoginResponse{User: user.ToResponse(), TwoFactorSetupRequired: true, MFAToken: token}, nil
+	}
+	return s.startSession(user, client)
+}
+
+// startSession 登录的所有步骤都通过后清除用户名的失败记录，更新最后登录时间并创建新的会话
+func (s *AuthService) startSession(user *models.User, client models.ClientInfo) (*models.LoginResponse, error) {
+	s.clearLoginFailures(user.Username)
+	now := time.Now()
+	user.LastLogin = &now
+	if err := s.users.Update(user, "last_login"); err != nil {
+		log.Printf("更新用户 %s 最后登录时间失败: %v", user.Username, err)
+	}
+
+	session := &models.Session{UserID: user.ID, CreatedAt: now}
+	return s.issueTokens(user, session, client)
+}
+
+// VerifyTwoFactorLogin 登录第二步，校验验证码或恢复码后签发令牌
+func (s *AuthService) VerifyTwoFactorLogin(req *models.TwoFactorLoginRequest, client models.ClientInfo) (*models.LoginResponse, error) {
+	challenge, err := s.takeMFAChallenge(req.MFAToken, false)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.activeUser(challenge.userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLoginLock(user.Username, client.IP); err != nil {
+		return nil, err
+	}
+	// 验证码错误同样计入失败次数，避免通过反复输入密码绕过 mfaMaxAttempts 暴力猜测验证码
+	if err := s.verifySecondFactor(user, req.Code, true); err != nil {
+		if errors.Is(err, ErrInvalidTOTPCode) {
+			s.recordLoginFailure(user.Username, client.IP)
+		}
+		return nil, err
+	}
+	s.finishMFAChallenge(req.MFAToken)
+	return s.startSession(user, client)
+}
+
+// BeginTwoFactorEnrollment 安全策略要求绑定两步验证时，在登录过程中获取绑定信息
+func (s *AuthService) BeginTwoFactorEnrollment(mfaToken string) (*models.TOTPSetupResponse, error) {
+	challenge, err := s.takeMFAChallenge(mfaToken, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.BeginTOTPSetup(challenge.userID)
+}
+
+// CompleteTwoFactorEnrollment 确认验证码完成绑定，返回恢复码并签发令牌
+func (s *AuthService) CompleteTwoFactorEnrollment(req *models.TwoFactorLoginRequest, client models.ClientInfo) (*models.TwoFactorEnrollResponse, error) {
+	challenge, err := s.takeMFAChallenge(req.MFAToken, true)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := s.EnableTOTP(challenge.userID, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	s.finishMFAChallenge(req.MFAToken)
+
+	user, err := s.activeUser(challenge.userID)
+	if err != nil {
+		return nil, err
+	}
+	login, err := s.startSession(user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorEnrollResponse{RecoveryCodes: codes, Login: login}, nil
+}
+
+// BeginTOTPSetup 生成新的 TOTP 密钥，确认验证码之前不会生效
+func (s *AuthService) BeginTOTPSetup(userID uint) (*models.TOTPSetupResponse, error) {
+	user, err := s.activeUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, NewValidationError("已启用两步验证，如需更换设备请先关闭")
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = secret
+	if err := s.users.Update(user, "totp_secret"); err != nil {
+		return nil, err
+	}
+	return &models.TOTPSetupResponse{Secret: secret, ProvisioningURI: totp.ProvisioningURI(totpIssuer, user.Username, secret)}, nil
+}
+
+// EnableTOTP 校验身份验证器生成的验证码后启用两步验证，返回一次性恢复码
+func (s *AuthService) EnableTOTP(userID uint, code string) ([]string, error) {
+	user, err := s.activeUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, NewValidationError("已启用两步验证")
+	}
+	if user.TOTPSecret == "" {
+		return nil, NewValidationError("请先获取绑定信息")
+	}
+	counter, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTOTPCode
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPEnabled = true
+	user.TOTPLastCounter = counter
+	user.TOTPRecoveryCodes = hashes
+	if err := s.users.Update(user, "totp_enabled", "totp_last_counter", "totp_recovery_codes"); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP 使用验证码或恢复码关闭两步验证，安全策略要求两步验证的用户不能关闭
+func (s *AuthService) DisableTOTP(userID uint, code string) error {
+	user, err := s.activeUser(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return NewValidationError("未启用两步验证")
+	}
+	required, err := s.twoFactorRequired(user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return NewValidationError("安全策略要求您的角色启用两步验证，不能关闭")
+	}
+	if err := s.verifySecondFactor(user, code, true); err != nil {
+		return err
+	}
+	return s.clearTwoFactor(userID)
+}
+
+// RegenerateRecoveryCodes 使用验证码重新生成恢复码，旧的恢复码全部失效
+func (s *AuthService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	user, err := s.activeUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, NewValidationError("未启用两步验证")
+	}
+	if err := s.verifySecondFactor(user, code, false); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPRecoveryCodes = hashes
+	if err := s.users.Update(user, "totp_recovery_codes"); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// ResetTwoFactor 管理员为丢失设备的用户重置两步验证，用户下次登录时重新绑定
+func (s *AuthService) ResetTwoFactor(userID uint) error {
+	if _, err := s.activeUser(userID); err != nil {
+		return err
+	}
+	return s.clearTwoFactor(userID)
+}
+
+// GetSecurityPolicy 读取全局安全策略，未保存过时返回默认值
+func (s *AuthService) GetSecurityPolicy() (*models.SecurityPolicy, error) {
+	policy := &models.SecurityPolicy{}
+	if err := s.db.FirstOrInit(policy, models.SecurityPolicy{ID: 1}).Error; err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// UpdateSecurityPolicy 更新全局安全策略，只修改请求中出现的字段
+func (s *AuthService) UpdateSecurityPolicy(req *models.UpdateSecurityPolicyRequest) (*models.SecurityPolicy, error) {
+	policy, err := s.GetSecurityPolicy()
+	if err != nil {
+		return nil, err
+	}
+	if req.RequireTwoFactorForWriters != nil {
+		policy.RequireTwoFactorForWriters = *req.RequireTwoFactorForWriters
+	}
+	if err := s.db.Save(policy).Error; err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// twoFactorRequired 安全策略是否要求该用户启用两步验证
+func (s *AuthService) twoFactorRequired(user *models.User) (bool, error) {
+	if s.enforcer == nil {
+		return false, nil
+	}
+	policy, err := s.GetSecurityPolicy()
+	if err != nil {
+		return false, err
+	}
+	if !policy.RequireTwoFactorForWriters {
+		return false, nil
+	}
+	return auth.HasWriteAccess(s.enforcer, user.Role, auth.UserSubject(user.Username))
+}
+
+// verifySecondFactor 校验 TOTP 验证码，allowRecovery 为 true 时也接受恢复码
+// 验证码的时间步必须大于上一次使用的时间步，恢复码使用后即删除；两者都用条件更新避免并发请求重复使用
+func (s *AuthService) verifySecondFactor(user *models.User, code string, allowRecovery bool) error {
+	if counter, ok := totp.Validate(user.TOTPSecret, code, time.Now()); ok {
+		advanced, err := s.users.AdvanceTOTPCounter(user.ID, counter)
+		if err != nil {
+			return err
+		}
+		if !advanced {
+			return ErrInvalidTOTPCode
+		}
+		user.TOTPLastCounter = counter
+		return nil
+	}
+	if !allowRecovery || user.TOTPRecoveryCodes == "" {
+		return ErrInvalidTOTPCode
+	}
+
+	hash := auth.HashToken(normalizeRecoveryCode(code))
+	hashes := strings.Split(user.TOTPRecoveryCodes, ",")
+	remaining := make([]string, 0, len(hashes))
+	for _, h := range hashes {
+		if h != hash {
+			remaining = append(remaining, h)
+		}
+	}
+	if len(remaining) == len(hashes) {
+		return ErrInvalidTOTPCode
+	}
+	next := strings.Join(remaining, ",")
+	replaced, err := s.users.ReplaceRecoveryCodes(user.ID, user.TOTPRecoveryCodes, next)
+	if err != nil {
+		return err
+	}
+	if !replaced {
+		return ErrInvalidTOTPCode
+	}
+	user.TOTPRecoveryCodes = next
+	return nil
+}
+
+func (s *AuthService) newMFAChallenge(userID uint, setup bool) (string, error) {
+	token, err := auth.GenerateOpaqueToken(24)
+	if err != nil {
+		return "", err
+	}
+	s.mfaMu.Lock()
+	defer s.mfaMu.Unlock()
+	now := time.Now()
+	for key, pending := range s.mfaChallenges {
+		if now.After(pending.expiresAt) {
+			delete(s.mfaChallenges, key)
+		}
+	}
+	s.mfaChallenges[token] = &mfaChallenge{userID: userID, setup: setup, expiresAt: now.Add(mfaChallengeTTL)}
+	return token, nil
+}
+
+// takeMFAChallenge 查找未过期的登录第二步并计入一次尝试，尝试次数用尽后作废
+func (s *AuthService) takeMFAChallenge(token string, setup bool) (mfaChallenge, error) {
+	s.mfaMu.Lock()
+	defer s.mfaMu.Unlock()
+	challenge, ok := s.mfaChallenges[token]
+	if !ok || challenge.setup != setup {
+		return mfaChallenge{}, ErrInvalidMFAToken
+	}
+	challenge.attempts++
+	if time.Now().After(challenge.expiresAt) || challenge.attempts > mfaMaxAttempts {
+		delete(s.mfaChallenges, token)
+		return mfaChallenge{}, ErrInvalidMFAToken
+	}
+	return *challenge, nil
+}
+
+func (s *AuthService) finishMFAChallenge(token string) {
+	s.mfaMu.Lock()
+	defer s.mfaMu.Unlock()
+	delete(s.mfaChallenges, token)
+}
+
+func (s *AuthService) activeUser(userID uint) (*models.User, error) {
+	user, err := s.users.FindByID(userID)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, err
+	}
+	if user == nil || !user.IsActive {
+		return nil, errors.New("用户不存在或已被禁用")
+	}
+	return user, nil
+}
+
+func (s *AuthService) clearTwoFactor(userID uint) error {
+	return s.users.Update(&models.User{ID: userID}, "totp_enabled", "totp_secret", "totp_last_counter", "totp_recovery_codes")
+}
+
+// generateRecoveryCodes 生成恢复码明文及其逗号分隔的摘要
+func generateRecoveryCodes() ([]string, string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	buf := make([]byte, 8)
+	for i := 0; i < recoveryCodeCount; i++ {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, "", err
+		}
+		raw := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, auth.HashToken(raw))
+	}
+	return codes, strings.Join(hashes, ","), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
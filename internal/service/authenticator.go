@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/configs"
+	"github.com/ciliverse/cilikube/internal/repository"
+	"github.com/ciliverse/cilikube/pkg/auth"
+)
+
+// ErrInvalidCredentials 用户不存在或密码错误，AuthService 会继续尝试下一个认证方式
+var ErrInvalidCredentials = errors.New("用户名或密码错误")
+
+// Authenticator 用户名密码认证方式，认证成功时返回对应的本地用户 (外部账号在首次登录时创建)
+type Authenticator interface {
+	Name() string
+	Authenticate(ctx context.Context, username, password string) (*models.User, error)
+}
+
+// LocalAuthenticator 使用用户仓库中 bcrypt 哈希的密码认证本地账号
+type LocalAuthenticator struct {
+	users repository.UserRepository
+}
+
+func NewLocalAuthenticator(users repository.UserRepository) *LocalAuthenticator {
+	return &LocalAuthenticator{users: users}
+}
+
+func (a *LocalAuthenticator) Name() string { return models.AuthProviderLocal }
+
+func (a *LocalAuthenticator) Authenticate(_ context.Context, username, password string) (*models.User, error) {
+	user, err := a.users.FindByUsername(username)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	// 外部账号只能通过对应的身份源认证
+	if !user.IsActive || user.IsExternal() || !user.CheckPassword(password) {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// externalIdentity 外部身份源认证通过后得到的用户信息
+type externalIdentity struct {
+	Provider   string
+	ExternalID string
+	Username   string
+	Email      string
+	Role       string
+}
+
+// upsertExternalUser 按身份源和外部 ID 查找本地用户，首次登录时自动创建；每次登录都以身份源的角色为准
+// 用户名或邮箱已被其他账号占用时拒绝创建，避免外部账号接管同名的本地账号
+func upsertExternalUser(users repository.UserRepository, identity externalIdentity) (*models.User, error) {
+	now := time.Now()
+	user, err := users.FindByExternalID(identity.Provider, identity.ExternalID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		taken, err := users.ExistsByUsername(identity.Username)
+		if err != nil {
+			return nil, err
+		}
+		if !taken {
+			if taken, err = users.ExistsByEmail(identity.Email, 0); err != nil {
+				return nil, err
+			}
+		}
+		if taken {
+			return nil, fmt.Errorf("用户名 %s 或邮箱 %s 已被其他账号使用，请联系管理员", identity.Username, identity.Email)
+		}
+		// 外部账号不使用本地密码，填入随机值
+		password, err := auth.GenerateOpaqueToken(32)
+		if err != nil {
+			return nil, err
+		}
+		user = &models.User{
+			Username:     identity.Username,
+			Email:        identity.Email,
+			Password:     password,
+			Role:         identity.Role,
+			IsActive:     true,
+			AuthProvider: identity.Provider,
+			ExternalID:   identity.ExternalID,
+			LastLogin:    &now,
+		}
+		if err := users.Create(user); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, errors.New("用户已被禁用")
+	}
+
+	user.Role = identity.Role
+	user.LastLogin = &now
+	if identity.Email != "" {
+		user.Email = identity.Email
+	}
+	if err := users.Update(user, "role", "last_login", "email"); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// mapGroupRole 按配置顺序返回第一个匹配分组对应的角色，没有匹配时使用默认角色
+func mapGroupRole(mappings []configs.GroupRole, defaultRole string, groups []string) string {
+	for _, mapping := range mappings {
+		for _, group := range groups {
+			if group == mapping.Group {
+				return mapping.Role
+			}
+		}
+	}
+	if defaultRole != "" {
+		return defaultRole
+	}
+	return "user"
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+)
+
+// parseCertificateChain 解析 PEM 中的全部证书，忽略其他类型的块；没有证书时返回错误
+func parseCertificateChain(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析第 %d 个证书失败: %w", len(certs)+1, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("没有找到 PEM 格式的证书")
+	}
+	return certs, nil
+}
+
+// parsePrivateKey 解析 PEM 中的第一个私钥，支持 PKCS#1、PKCS#8 和 EC 格式
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("没有找到 PEM 格式的私钥")
+		}
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			return x509.ParseECPrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			switch k := key.(type) {
+			case *rsa.PrivateKey:
+				return k, nil
+			case *ecdsa.PrivateKey:
+				return k, nil
+			case ed25519.PrivateKey:
+				return k, nil
+			}
+			return nil, fmt.Errorf("不支持的私钥类型 %T", key)
+		case "ENCRYPTED PRIVATE KEY":
+			return nil, errors.New("不支持加密的私钥，请先解密")
+		}
+	}
+}
+
+// certificateMatchesKey 叶子证书的公钥是否与私钥匹配
+func certificateMatchesKey(cert *x509.Certificate, key crypto.Signer) bool {
+	public, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	return ok && public.Equal(cert.PublicKey)
+}
+
+// certificateInfo 提取证书的主题、SAN、签发者和有效期，DaysRemaining 相对 now 计算
+func certificateInfo(cert *x509.Certificate, now time.Time) models.CertificateInfo {
+	info := models.CertificateInfo{
+		Subject:        cert.Subject.String(),
+		Issuer:         cert.Issuer.String(),
+		SerialNumber:   cert.SerialNumber.Text(16),
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		NotBefore:      cert.NotBefore,
+		NotAfter:       cert.NotAfter,
+		DaysRemaining:  int(cert.NotAfter.Sub(now).Hours() / 24),
+		Expired:        !now.Before(cert.NotAfter),
+		IsCA:           cert.IsCA,
+	}
+	for _, ip := range cert.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		info.URIs = append(info.URIs, uri.String())
+	}
+	return info
+}
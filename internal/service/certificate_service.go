@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// CertificateService 汇总 TLS Secret 和 Ingress tls 引用，生成证书过期清单
+type CertificateService struct {
+	secrets   *SecretService
+	ingresses *IngressService
+}
+
+func NewCertificateService(secrets *SecretService, ingresses *IngressService) *CertificateService {
+	return &CertificateService{secrets: secrets, ingresses: ingresses}
+}
+
+// Inventory 扫描 kubernetes.io/tls 类型的 Secret 以及被 Ingress tls 引用的 Secret，解析其中的证书。
+// namespace 为空时扫描所有命名空间；被引用但不存在或不含 tls.crt 的 Secret 记录为悬空引用
+func (s *CertificateService) Inventory(namespace string, query *models.CertificateInventoryQuery) (*models.CertificateInventoryResponse, error) {
+	less, err := inventoryLess(query.SortBy, query.Order)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.TODO()
+	now := time.Now()
+
+	secretList, err := s.secrets.client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("type", string(corev1.SecretTypeTLS)).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	ingressList, err := s.ingresses.List(namespace, "", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := make(map[string]*corev1.Secret, len(secretList.Items))
+	for i := range secretList.Items {
+		secret := &secretList.Items[i]
+		if secret.Type == corev1.SecretTypeTLS {
+			secrets[secret.Namespace+"/"+secret.Name] = secret
+		}
+	}
+
+	// 收集 Ingress 引用；引用的 Secret 不一定是 TLS 类型，需要单独读取
+	references := map[string][]string{}
+	var dangling []models.DanglingTLSReference
+	missing := map[string]string{}
+	for _, ingress := range ingressList.Items {
+		for _, tls := range ingress.Spec.TLS {
+			if tls.SecretName == "" {
+				continue // 未指定 Secret 时使用 Ingress Controller 的默认证书
+			}
+			key := ingress.Namespace + "/" + tls.SecretName
+			if _, ok := secrets[key]; !ok {
+				if _, checked := missing[key]; !checked {
+					reason, err := s.loadReferencedSecret(ingress.Namespace, tls.SecretName, secrets)
+					if err != nil {
+						return nil, err
+					}
+					missing[key] = reason
+				}
+			}
+			if reason := missing[key]; reason != "" {
+				dangling = append(dangling, models.DanglingTLSReference{
+					Namespace:  ingress.Namespace,
+					Ingress:    ingress.Name,
+					SecretName: tls.SecretName,
+					Hosts:      tls.Hosts,
+					Reason:     reason,
+				})
+				continue
+			}
+			if names := references[key]; len(names) == 0 || names[len(names)-1] != ingress.Name {
+				references[key] = append(names, ingress.Name)
+			}
+		}
+	}
+
+	items := make([]models.CertificateInventoryItem, 0, len(secrets))
+	for key, secret := range secrets {
+		item := inventoryItem(secret, now)
+		item.Ingresses = references[key]
+		sort.Strings(item.Ingresses)
+		if query.ThresholdDays != nil && item.Certificate != nil && item.Certificate.DaysRemaining > *query.ThresholdDays {
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return less(&items[i], &items[j]) })
+	sort.SliceStable(dangling, func(i, j int) bool {
+		if dangling[i].Namespace != dangling[j].Namespace {
+			return dangling[i].Namespace < dangling[j].Namespace
+		}
+		return dangling[i].Ingress < dangling[j].Ingress
+	})
+	if dangling == nil {
+		dangling = []models.DanglingTLSReference{}
+	}
+
+	return &models.CertificateInventoryResponse{
+		Items:              items,
+		Total:              len(items),
+		DanglingReferences: dangling,
+		ScannedAt:          now,
+	}, nil
+}
+
+// loadReferencedSecret 读取被 Ingress 引用但不是 TLS 类型的 Secret，含 tls.crt 时加入清单，否则返回悬空原因
+func (s *CertificateService) loadReferencedSecret(namespace, name string, secrets map[string]*corev1.Secret) (string, error) {
+	secret, err := s.secrets.Get(namespace, name)
+	if k8serrors.IsNotFound(err) {
+		return "Secret 不存在", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, ok := secret.Data[corev1.TLSCertKey]; !ok {
+		return fmt.Sprintf("Secret 类型为 %s 且不包含 %s", secret.Type, corev1.TLSCertKey), nil
+	}
+	secrets[namespace+"/"+name] = secret
+	return "", nil
+}
+
+func inventoryItem(secret *corev1.Secret, now time.Time) models.CertificateInventoryItem {
+	item := models.CertificateInventoryItem{
+		Namespace:  secret.Namespace,
+		SecretName: secret.Name,
+		SecretType: string(secret.Type),
+	}
+	data, ok := secret.Data[corev1.TLSCertKey]
+	if !ok || len(data) == 0 {
+		item.Error = "缺少 " + corev1.TLSCertKey
+		return item
+	}
+	certs, err := parseCertificateChain(data)
+	if err != nil {
+		item.Error = err.Error()
+		return item
+	}
+	info := certificateInfo(certs[0], now)
+	item.Certificate = &info
+	item.ChainLength = len(certs)
+	return item
+}
+
+// inventoryLess 按剩余天数或名称排序；按剩余天数排序时无法解析的条目视为最紧急，排在升序最前面
+func inventoryLess(sortBy, order string) (func(a, b *models.CertificateInventoryItem) bool, error) {
+	var less func(a, b *models.CertificateInventoryItem) bool
+	byName := func(a, b *models.CertificateInventoryItem) bool {
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.SecretName < b.SecretName
+	}
+	switch sortBy {
+	case "", models.CertificateSortByDaysRemaining:
+		less = func(a, b *models.CertificateInventoryItem) bool {
+			switch {
+			case a.Certificate == nil || b.Certificate == nil:
+				if (a.Certificate == nil) != (b.Certificate == nil) {
+					return a.Certificate == nil
+				}
+			case !a.Certificate.NotAfter.Equal(b.Certificate.NotAfter):
+				return a.Certificate.NotAfter.Before(b.Certificate.NotAfter)
+			}
+			return byName(a, b)
+		}
+	case models.CertificateSortByName:
+		less = byName
+	default:
+		return nil, NewValidationError("不支持的排序字段: " + sortBy)
+	}
+
+	switch strings.ToLower(order) {
+	case "", "asc":
+	case "desc":
+		asc := less
+		less = func(a, b *models.CertificateInventoryItem) bool { return asc(b, a) }
+	default:
+		return nil, NewValidationError("不支持的排序方向: " + order)
+	}
+	return less, nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCertificateService_Inventory(t *testing.T) {
+	day := 24 * time.Hour
+	webCert, _ := newTestCertificate(t, "web.example.com", time.Now().Add(10*day+time.Hour), "web.example.com")
+	longCert, _ := newTestCertificate(t, "long.example.com", time.Now().Add(200*day+time.Hour))
+	legacyCert, _ := newTestCertificate(t, "legacy.example.com", time.Now().Add(5*day+time.Hour))
+	secret := func(namespace, name string, secretType corev1.SecretType, cert string) runtime.Object {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Type:       secretType,
+			Data:       map[string][]byte{corev1.TLSCertKey: []byte(cert)},
+		}
+	}
+	ingress := func(namespace, name string, secretNames ...string) runtime.Object {
+		ing := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+		for _, secretName := range secretNames {
+			ing.Spec.TLS = append(ing.Spec.TLS, networkingv1.IngressTLS{Hosts: []string{name + ".example.com"}, SecretName: secretName})
+		}
+		return ing
+	}
+	client := fake.NewSimpleClientset(
+		secret("a", "web-tls", corev1.SecretTypeTLS, webCert),
+		secret("a", "long-tls", corev1.SecretTypeTLS, longCert),
+		secret("a", "unused", corev1.SecretTypeOpaque, webCert),
+		secret("b", "broken-tls", corev1.SecretTypeTLS, "garbage"),
+		secret("b", "legacy", corev1.SecretTypeOpaque, legacyCert),
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "b", Name: "no-cert"}, Type: corev1.SecretTypeOpaque},
+		ingress("a", "web", "web-tls", "web-tls"),
+		ingress("a", "api", "web-tls", ""),
+		ingress("b", "legacy", "legacy"),
+		ingress("b", "bad", "no-cert", "gone"),
+	)
+	svc := NewCertificateService(NewSecretService(client), NewIngressService(client))
+
+	names := func(items []models.CertificateInventoryItem) []string {
+		var result []string
+		for _, item := range items {
+			result = append(result, item.Namespace+"/"+item.SecretName)
+		}
+		return result
+	}
+
+	// 默认按剩余天数升序，无法解析的排在最前
+	inventory, err := svc.Inventory("", &models.CertificateInventoryQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b/broken-tls", "b/legacy", "a/web-tls", "a/long-tls"}, names(inventory.Items))
+	assert.NotEmpty(t, inventory.Items[0].Error)
+	assert.Nil(t, inventory.Items[0].Certificate)
+	assert.Equal(t, string(corev1.SecretTypeOpaque), inventory.Items[1].SecretType)
+	assert.Equal(t, 5, inventory.Items[1].Certificate.DaysRemaining)
+	assert.Equal(t, []string{"api", "web"}, inventory.Items[2].Ingresses)
+	assert.Equal(t, "CN=web.example.com", inventory.Items[2].Certificate.Subject)
+	assert.Equal(t, []string{"web.example.com"}, inventory.Items[2].Certificate.DNSNames)
+	assert.Empty(t, inventory.Items[3].Ingresses)
+
+	require.Len(t, inventory.DanglingReferences, 2)
+	assert.Equal(t, "bad", inventory.DanglingReferences[0].Ingress)
+	assert.Equal(t, "no-cert", inventory.DanglingReferences[0].SecretName)
+	assert.Contains(t, inventory.DanglingReferences[0].Reason, corev1.TLSCertKey)
+	assert.Equal(t, "gone", inventory.DanglingReferences[1].SecretName)
+	assert.Equal(t, "Secret 不存在", inventory.DanglingReferences[1].Reason)
+
+	// 按阈值过滤、按名称倒序、限定命名空间
+	threshold := 30
+	inventory, err = svc.Inventory("", &models.CertificateInventoryQuery{ThresholdDays: &threshold, SortBy: models.CertificateSortByName, Order: "desc"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b/legacy", "b/broken-tls", "a/web-tls"}, names(inventory.Items))
+	assert.Equal(t, 3, inventory.Total)
+
+	inventory, err = svc.Inventory("a", &models.CertificateInventoryQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/web-tls", "a/long-tls"}, names(inventory.Items))
+	assert.Empty(t, inventory.DanglingReferences)
+
+	_, err = svc.Inventory("", &models.CertificateInventoryQuery{SortBy: "issuer"})
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+}
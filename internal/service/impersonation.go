@@ -0,0 +1,81 @@
+package service
+
+import (
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// 开启 Kubernetes 用户模拟时，handler 通过 WithClient 得到使用当前用户身份客户端的服务副本，
+// 服务本身只保存客户端，复制的开销可以忽略。config 只有需要直接构造请求的服务才会使用
+
+func (s *ConfigMapService) WithClient(client kubernetes.Interface, _ *rest.Config) *ConfigMapService {
+	return &ConfigMapService{client: client}
+}
+
+func (s *DaemonSetService) WithClient(client kubernetes.Interface, _ *rest.Config) *DaemonSetService {
+	return &DaemonSetService{client: client}
+}
+
+func (s *DeploymentService) WithClient(client kubernetes.Interface, _ *rest.Config) *DeploymentService {
+	return &DeploymentService{client: client}
+}
+
+func (s *EventsService) WithClient(client kubernetes.Interface, _ *rest.Config) *EventsService {
+	return &EventsService{client: client}
+}
+
+func (s *IngressService) WithClient(client kubernetes.Interface, _ *rest.Config) *IngressService {
+	return &IngressService{client: client}
+}
+
+func (s *NamespaceService) WithClient(client kubernetes.Interface, _ *rest.Config) *NamespaceService {
+	return &NamespaceService{client: client}
+}
+
+func (s *NetworkPolicyService) WithClient(client kubernetes.Interface, _ *rest.Config) *NetworkPolicyService {
+	return &NetworkPolicyService{client: client}
+}
+
+func (s *NodeService) WithClient(client kubernetes.Interface, _ *rest.Config) *NodeService {
+	return &NodeService{client: client}
+}
+
+func (s *PVService) WithClient(client kubernetes.Interface, _ *rest.Config) *PVService {
+	return &PVService{client: client}
+}
+
+func (s *PVCService) WithClient(client kubernetes.Interface, _ *rest.Config) *PVCService {
+	return &PVCService{client: client}
+}
+
+func (s *SecretService) WithClient(client kubernetes.Interface, _ *rest.Config) *SecretService {
+	return &SecretService{client: client}
+}
+
+func (s *ServiceService) WithClient(client kubernetes.Interface, _ *rest.Config) *ServiceService {
+	return &ServiceService{client: client}
+}
+
+func (s *StatefulSetService) WithClient(client kubernetes.Interface, _ *rest.Config) *StatefulSetService {
+	return &StatefulSetService{client: client}
+}
+
+func (s *SummaryService) WithClient(client kubernetes.Interface, _ *rest.Config) *SummaryService {
+	return &SummaryService{client: client}
+}
+
+func (s *PodService) WithClient(client kubernetes.Interface, config *rest.Config) *PodService {
+	return &PodService{client: client, config: config}
+}
+
+func (s *RbacService) WithClient(client kubernetes.Interface, config *rest.Config) *RbacService {
+	return &RbacService{client: client, config: config}
+}
+
+func (s *ProxyService) WithClient(_ kubernetes.Interface, config *rest.Config) *ProxyService {
+	return &ProxyService{restConfig: config}
+}
+
+func (s *CertificateService) WithClient(client kubernetes.Interface, config *rest.Config) *CertificateService {
+	return &CertificateService{secrets: s.secrets.WithClient(client, config), ingresses: s.ingresses.WithClient(client, config)}
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/configs"
+	"github.com/ciliverse/cilikube/internal/repository"
+	"github.com/go-ldap/ldap/v3"
+	"gorm.io/gorm"
+)
+
+// ldapConn LDAP 连接中用到的操作，便于测试时替换为内存实现
+type ldapConn interface {
+	Bind(username, password string) error
+	Search(request *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// LDAPAuthenticator 先用服务账号查找用户 DN，再以用户 DN bind 校验密码，并按所属分组映射角色
+type LDAPAuthenticator struct {
+	config configs.LDAPConfig
+	users  repository.UserRepository
+	db     *gorm.DB // 同步禁用账号时吊销会话
+	dial   func() (ldapConn, error)
+}
+
+func NewLDAPAuthenticator(config configs.LDAPConfig, users repository.UserRepository, db *gorm.DB) *LDAPAuthenticator {
+	a := &LDAPAuthenticator{config: config, users: users, db: db}
+	a.dial = a.dialServer
+	return a
+}
+
+func (a *LDAPAuthenticator) Name() string { return models.AuthProviderLDAP }
+
+func (a *LDAPAuthenticator) dialServer() (ldapConn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: a.config.InsecureSkipVerify}
+	conn, err := ldap.DialURL(a.config.URL, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("连接 LDAP 服务器失败: %w", err)
+	}
+	if a.config.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("LDAP StartTLS 失败: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// connect 建立连接并以服务账号 bind
+func (a *LDAPAuthenticator) connect() (ldapConn, error) {
+	conn, err := a.dial()
+	if err != nil {
+		return nil, err
+	}
+	if err := a.bindService(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (a *LDAPAuthenticator) bindService(conn ldapConn) error {
+	if a.config.BindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(a.config.BindDN, a.config.BindPassword); err != nil {
+		return fmt.Errorf("LDAP 服务账号 bind 失败: %w", err)
+	}
+	return nil
+}
+
+func (a *LDAPAuthenticator) Authenticate(_ context.Context, username, password string) (*models.User, error) {
+	// 空密码会被很多目录服务当作匿名 bind 并返回成功，必须提前拒绝
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := a.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := a.findUser(conn, username, true)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAP 用户 bind 失败: %w", err)
+	}
+
+	// 分组通常只有服务账号可读，查询前切回服务账号
+	if err := a.bindService(conn); err != nil {
+		return nil, err
+	}
+	groups, err := a.findGroups(conn, entry.DN)
+	if err != nil {
+		return nil, err
+	}
+
+	name := entry.GetAttributeValue(a.config.UsernameAttribute)
+	if name == "" {
+		name = username
+	}
+	email := entry.GetAttributeValue(a.config.EmailAttribute)
+	if email == "" {
+		email = fmt.Sprintf("%s@%s", name, models.AuthProviderLDAP)
+	}
+	return upsertExternalUser(a.users, externalIdentity{
+		Provider:   models.AuthProviderLDAP,
+		ExternalID: entry.DN,
+		Username:   name,
+		Email:      email,
+		Role:       mapGroupRole(a.config.GroupRoles, a.config.DefaultRole, groups),
+	})
+}
+
+// findUser 按用户名查找唯一的用户条目，excludeDisabled 为 true 时排除匹配 DisabledFilter 的账号
+func (a *LDAPAuthenticator) findUser(conn ldapConn, username string, excludeDisabled bool) (*ldap.Entry, error) {
+	filter := fmt.Sprintf(a.config.UserFilter, ldap.EscapeFilter(username))
+	if excludeDisabled && a.config.DisabledFilter != "" {
+		filter = fmt.Sprintf("(&%s(!%s))", filter, a.config.DisabledFilter)
+	}
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.config.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		filter, []string{"dn", a.config.UsernameAttribute, a.config.EmailAttribute}, nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("LDAP 查找用户失败: %w", err)
+	}
+	switch len(result.Entries) {
+	case 0:
+		return nil, nil
+	case 1:
+		return result.Entries[0], nil
+	default:
+		return nil, fmt.Errorf("LDAP 中存在多个用户名为 %s 的条目", username)
+	}
+}
+
+func (a *LDAPAuthenticator) findGroups(conn ldapConn, userDN string) ([]string, error) {
+	baseDN := a.config.GroupBaseDN
+	if baseDN == "" {
+		baseDN = a.config.BaseDN
+	}
+	result, err := conn.Search(ldap.NewSearchRequest(
+		baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf(a.config.GroupFilter, ldap.EscapeFilter(userDN)), []string{a.config.GroupNameAttribute}, nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("LDAP 查找分组失败: %w", err)
+	}
+	groups := make([]string, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		if name := entry.GetAttributeValue(a.config.GroupNameAttribute); name != "" {
+			groups = append(groups, name)
+		}
+	}
+	return groups, nil
+}
+
+// SyncDisabledAccounts 检查所有启用的 LDAP 账号，目录中已删除或已禁用的账号在本地禁用并吊销会话
+// 只做禁用：目录中重新启用的账号需要管理员在本地手动启用
+func (a *LDAPAuthenticator) SyncDisabledAccounts(_ context.Context) (int, error) {
+	users, err := a.users.ListActiveByProvider(models.AuthProviderLDAP)
+	if err != nil {
+		return 0, err
+	}
+	if len(users) == 0 {
+		return 0, nil
+	}
+
+	conn, err := a.connect()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	disabled := 0
+	for _, user := range users {
+		entry, err := a.findUser(conn, user.Username, true)
+		if err != nil {
+			return disabled, err
+		}
+		if entry != nil && strings.EqualFold(entry.DN, user.ExternalID) {
+			continue
+		}
+		user.IsActive = false
+		if err := a.users.Update(&user, "is_active"); err != nil {
+			return disabled, err
+		}
+		if err := revokeSessions(a.db, "user_id = ?", user.ID); err != nil {
+			return disabled, err
+		}
+		log.Printf("LDAP 账号 %s 在目录中已禁用或删除，已禁用本地账号", user.Username)
+		disabled++
+	}
+	return disabled, nil
+}
+
+// RunSync 按间隔同步禁用账号，直到 ctx 结束
+func (a *LDAPAuthenticator) RunSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := a.SyncDisabledAccounts(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("同步 LDAP 禁用账号失败: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/configs"
+	"github.com/ciliverse/cilikube/internal/repository"
+	"github.com/glebarez/sqlite"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type fakeLDAPUser struct {
+	dn       string
+	password string
+	mail     string
+	groups   []string
+	disabled bool
+}
+
+// fakeLDAP 内存目录，只识别 (uid=%s) 用户过滤器和 (member=%s) 分组过滤器
+type fakeLDAP struct {
+	users map[string]*fakeLDAPUser
+}
+
+func (f *fakeLDAP) Bind(username, password string) error {
+	if username == "cn=svc,dc=example,dc=com" && password == "svc-secret" {
+		return nil
+	}
+	for _, user := range f.users {
+		if user.dn == username && user.password == password {
+			return nil
+		}
+	}
+	return ldap.NewError(ldap.LDAPResultInvalidCredentials, nil)
+}
+
+func (f *fakeLDAP) Search(request *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	result := &ldap.SearchResult{}
+	for name, user := range f.users {
+		if strings.HasPrefix(request.BaseDN, "ou=groups") {
+			if !strings.Contains(request.Filter, ldap.EscapeFilter(user.dn)) {
+				continue
+			}
+			for _, group := range user.groups {
+				result.Entries = append(result.Entries, ldap.NewEntry("cn="+group+",ou=groups,dc=example,dc=com", map[string][]string{"cn": {group}}))
+			}
+			continue
+		}
+		if !strings.Contains(request.Filter, "(uid="+ldap.EscapeFilter(name)+")") {
+			continue
+		}
+		if user.disabled && strings.Contains(request.Filter, "(!") {
+			continue
+		}
+		result.Entries = append(result.Entries, ldap.NewEntry(user.dn, map[string][]string{"uid": {name}, "mail": {user.mail}}))
+	}
+	return result, nil
+}
+
+func (f *fakeLDAP) Close() error { return nil }
+
+func setupLDAPTest(t *testing.T) (*LDAPAuthenticator, *fakeLDAP) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Session{}, &models.RevokedToken{}))
+	require.NoError(t, db.Create(&models.User{Username: "admin", Email: "admin@example.com", Password: "admin123", Role: "admin", IsActive: true}).Error)
+
+	directory := &fakeLDAP{users: map[string]*fakeLDAPUser{
+		"alice": {dn: "uid=alice,ou=people,dc=example,dc=com", password: "alice-pw", mail: "alice@example.com", groups: []string{"developers", "k8s-admins"}},
+		"bob":   {dn: "uid=bob,ou=people,dc=example,dc=com", password: "bob-pw", mail: "bob@example.com"},
+		"admin": {dn: "uid=admin,ou=people,dc=example,dc=com", password: "ldap-admin-pw", mail: "root@example.com"},
+	}}
+	authenticator := NewLDAPAuthenticator(configs.LDAPConfig{
+		BindDN:             "cn=svc,dc=example,dc=com",
+		BindPassword:       "svc-secret",
+		BaseDN:             "ou=people,dc=example,dc=com",
+		UserFilter:         "(uid=%s)",
+		UsernameAttribute:  "uid",
+		EmailAttribute:     "mail",
+		GroupBaseDN:        "ou=groups,dc=example,dc=com",
+		GroupFilter:        "(member=%s)",
+		GroupNameAttribute: "cn",
+		DisabledFilter:     "(nsAccountLock=TRUE)",
+		GroupRoles:         []configs.GroupRole{{Group: "k8s-admins", Role: "admin"}},
+		DefaultRole:        "user",
+	}, repository.NewGormUserRepository(db), db)
+	authenticator.dial = func() (ldapConn, error) { return directory, nil }
+	return authenticator, directory
+}
+
+func TestLDAPAuthenticator_Authenticate(t *testing.T) {
+	authenticator, directory := setupLDAPTest(t)
+	ctx := context.Background()
+
+	user, err := authenticator.Authenticate(ctx, "alice", "alice-pw")
+	require.NoError(t, err)
+	assert.Equal(t, models.AuthProviderLDAP, user.AuthProvider)
+	assert.Equal(t, "uid=alice,ou=people,dc=example,dc=com", user.ExternalID)
+	assert.Equal(t, "admin", user.Role, "k8s-admins 分组映射为 admin")
+
+	user, err = authenticator.Authenticate(ctx, "bob", "bob-pw")
+	require.NoError(t, err)
+	assert.Equal(t, "user", user.Role, "没有匹配分组时使用默认角色")
+
+	_, err = authenticator.Authenticate(ctx, "alice", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = authenticator.Authenticate(ctx, "alice", "")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = authenticator.Authenticate(ctx, "nobody", "pw")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	// 目录中的同名账号不能接管本地账号
+	_, err = authenticator.Authenticate(ctx, "admin", "ldap-admin-pw")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidCredentials)
+
+	directory.users["bob"].disabled = true
+	_, err = authenticator.Authenticate(ctx, "bob", "bob-pw")
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "目录中禁用的账号不能登录")
+}
+
+func TestLDAPAuthenticator_SyncDisabledAccounts(t *testing.T) {
+	authenticator, directory := setupLDAPTest(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"alice", "bob"} {
+		_, err := authenticator.Authenticate(ctx, name, directory.users[name].password)
+		require.NoError(t, err)
+	}
+	var bob models.User
+	require.NoError(t, authenticator.db.Where("username = ?", "bob").First(&bob).Error)
+	require.NoError(t, authenticator.db.Create(&models.Session{UserID: bob.ID, RefreshTokenHash: "bob-session"}).Error)
+
+	directory.users["bob"].disabled = true
+	disabled, err := authenticator.SyncDisabledAccounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, disabled)
+
+	var users []models.User
+	require.NoError(t, authenticator.db.Order("username").Find(&users).Error)
+	active := map[string]bool{}
+	for _, user := range users {
+		active[user.Username] = user.IsActive
+	}
+	assert.Equal(t, map[string]bool{"admin": true, "alice": true, "bob": false}, active)
+
+	var session models.Session
+	require.NoError(t, authenticator.db.Where("user_id = ?", bob.ID).First(&session).Error)
+	assert.NotNil(t, session.RevokedAt, "禁用账号的会话被吊销")
+
+	// 本地禁用后即使目录中删除也不再重复处理
+	delete(directory.users, "bob")
+	disabled, err = authenticator.SyncDisabledAccounts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, disabled)
+}
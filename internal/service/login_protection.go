@@ -0,0 +1,159 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/configs"
+	"github.com/ciliverse/cilikube/pkg/audit"
+	"gorm.io/gorm"
+)
+
+// loginAttemptMu 串行化失败计数的读改写，避免并发失败请求少算次数
+var loginAttemptMu sync.Mutex
+
+// loginClock 登录保护使用的当前时间，测试中替换以模拟多轮锁定
+var loginClock = time.Now
+
+// AccountLockedError 用户名或客户端 IP 因连续登录失败被临时锁定
+type AccountLockedError struct {
+	Until time.Time
+}
+
+func (e *AccountLockedError) Error() string {
+	return fmt.Sprintf("登录失败次数过多，请在 %s 后重试", e.Until.Format("2006-01-02 15:04:05"))
+}
+
+// RetryAfter 距离解锁的剩余时间
+func (e *AccountLockedError) RetryAfter() time.Duration {
+	return time.Until(e.Until)
+}
+
+func userAttemptKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func ipAttemptKey(ip string) string {
+	return "ip:" + ip
+}
+
+// checkLoginLock 用户名或 IP 任意一个处于锁定期时拒绝登录，不再校验密码
+func (s *AuthService) checkLoginLock(username, ip string) error {
+	var attempts []models.LoginAttempt
+	err := s.db.Where("attempt_key IN ? AND locked_until > ?", []string{userAttemptKey(username), ipAttemptKey(ip)}, loginClock()).
+		Find(&attempts).Error
+	if err != nil {
+		return err
+	}
+	var until time.Time
+	for _, attempt := range attempts {
+		if attempt.LockedUntil.After(until) {
+			until = *attempt.LockedUntil
+		}
+	}
+	if until.IsZero() {
+		return nil
+	}
+	return &AccountLockedError{Until: until}
+}
+
+// recordLoginFailure 分别累计用户名和 IP 的失败次数，达到上限时锁定并记录审计事件
+// 每次锁定的时长是上一次的两倍，直到 MaxLockout；失败次数按统计窗口重新计数，
+// 而锁定次数只在最近一次锁定结束后安静 MaxLockout 才清零，否则每轮锁定都会超过窗口，翻倍永远达不到上限
+func (s *AuthService) recordLoginFailure(username, ip string) {
+	cfg := configs.GlobalConfig.Security.Login
+	limits := []struct {
+		key    string
+		target string
+		limit  int
+	}{
+		{userAttemptKey(username), username, cfg.MaxAttempts},
+		{ipAttemptKey(ip), ip, cfg.IPMaxAttempts},
+	}
+
+	loginAttemptMu.Lock()
+	defer loginAttemptMu.Unlock()
+	now := loginClock()
+	for _, l := range limits {
+		if l.limit <= 0 {
+			continue
+		}
+		attempt := models.LoginAttempt{Key: l.key}
+		if err := s.db.First(&attempt, "attempt_key = ?", l.key).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if cfg.Window > 0 && now.Sub(attempt.LastFailureAt) > cfg.Window {
+			attempt.Failures = 0
+		}
+		if attempt.LockedUntil != nil && now.Sub(*attempt.LockedUntil) > lockoutDecay(cfg) {
+			attempt.LockCount = 0
+		}
+		attempt.Failures++
+		attempt.LastFailureAt = now
+		if attempt.Failures >= l.limit {
+			duration := lockoutDuration(cfg, attempt.LockCount)
+			until := now.Add(duration)
+			attempt.LockedUntil = &until
+			attempt.LockCount++
+			attempt.Failures = 0
+			audit.Record(&models.AuditEvent{
+				Username: username,
+				ClientIP: ip,
+				Action:   models.AuditActionLoginLockout,
+				Target:   l.key,
+				Detail:   fmt.Sprintf("连续登录失败 %d 次，第 %d 次锁定 %s", l.limit, attempt.LockCount, duration),
+			})
+		}
+		s.db.Save(&attempt)
+	}
+}
+
+// lockoutDecay 锁定结束后多久没有再次锁定时，锁定时长恢复为 LockDuration
+func lockoutDecay(cfg configs.LoginProtectionConfig) time.Duration {
+	if cfg.MaxLockout > 0 {
+		return cfg.MaxLockout
+	}
+	return cfg.Window
+}
+
+func lockoutDuration(cfg configs.LoginProtectionConfig, lockCount int) time.Duration {
+	duration := cfg.LockDuration
+	if duration <= 0 {
+		duration = time.Minute
+	}
+	for i := 0; i < lockCount; i++ {
+		duration *= 2
+		if cfg.MaxLockout > 0 && duration >= cfg.MaxLockout {
+			return cfg.MaxLockout
+		}
+	}
+	return duration
+}
+
+// clearLoginFailures 登录成功后清除该用户名的失败记录；IP 的记录按时间窗口自然过期
+func (s *AuthService) clearLoginFailures(username string) {
+	s.db.Delete(&models.LoginAttempt{}, "attempt_key = ?", userAttemptKey(username))
+}
+
+// UnlockUser 管理员解除用户的登录锁定
+func (s *AuthService) UnlockUser(userID uint, operator, clientIP string) error {
+	user, err := s.users.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Delete(&models.LoginAttempt{}, "attempt_key = ?", userAttemptKey(user.Username)).Error; err != nil {
+		return err
+	}
+	audit.Record(&models.AuditEvent{
+		Username: operator,
+		ClientIP: clientIP,
+		Action:   models.AuditActionLoginUnlock,
+		Target:   userAttemptKey(user.Username),
+		Detail:   "管理员解除登录锁定",
+	})
+	return nil
+}
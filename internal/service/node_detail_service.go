@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// 详情中优先展示的标准资源，其余 (扩展资源、hugepages 等) 按名称排序追加
+var standardNodeResources = []corev1.ResourceName{
+	corev1.ResourceCPU,
+	corev1.ResourceMemory,
+	corev1.ResourceEphemeralStorage,
+}
+
+// 在 pressure 字段中汇总的节点状况
+var nodePressureConditions = []corev1.NodeConditionType{
+	corev1.NodeMemoryPressure,
+	corev1.NodeDiskPressure,
+	corev1.NodePIDPressure,
+	corev1.NodeNetworkUnavailable,
+}
+
+// GetDetail 获取Node详情：容量、可分配量、Pod 请求/限制汇总以及调度在该节点上的 Pod
+func (s *NodeService) GetDetail(name string) (*models.NodeDetailResponse, error) {
+	ctx := context.TODO()
+	node, err := s.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	podList, err := s.client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &models.NodeDetailResponse{
+		Name:           node.Name,
+		Labels:         node.Labels,
+		Annotations:    node.Annotations,
+		Unschedulable:  node.Spec.Unschedulable,
+		Ready:          string(corev1.ConditionUnknown),
+		Pressure:       make(map[string]bool, len(nodePressureConditions)),
+		Conditions:     node.Status.Conditions,
+		Taints:         node.Spec.Taints,
+		Addresses:      node.Status.Addresses,
+		KubeletVersion: node.Status.NodeInfo.KubeletVersion,
+		NodeInfo:       node.Status.NodeInfo,
+		PodCapacity:    quantityString(node.Status.Allocatable, corev1.ResourcePods),
+		Pods:           make([]models.NodePodSummary, 0, len(podList.Items)),
+		CreatedAt:      node.CreationTimestamp.Format(time.RFC3339),
+	}
+	for _, t := range nodePressureConditions {
+		detail.Pressure[string(t)] = false
+	}
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			detail.Ready = string(cond.Status)
+			continue
+		}
+		if _, ok := detail.Pressure[string(cond.Type)]; ok {
+			detail.Pressure[string(cond.Type)] = cond.Status == corev1.ConditionTrue
+		}
+	}
+
+	totalRequests, totalLimits := corev1.ResourceList{}, corev1.ResourceList{}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		reqs, limits := podRequestsAndLimits(pod)
+		detail.Pods = append(detail.Pods, models.NodePodSummary{
+			Name:           pod.Name,
+			Namespace:      pod.Namespace,
+			Phase:          string(pod.Status.Phase),
+			CPURequests:    quantityString(reqs, corev1.ResourceCPU),
+			CPULimits:      quantityString(limits, corev1.ResourceCPU),
+			MemoryRequests: quantityString(reqs, corev1.ResourceMemory),
+			MemoryLimits:   quantityString(limits, corev1.ResourceMemory),
+			CreatedAt:      pod.CreationTimestamp.Format(time.RFC3339),
+		})
+		// 已结束的 Pod 不再占用节点资源
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		detail.PodCount++
+		addResourceList(totalRequests, reqs)
+		addResourceList(totalLimits, limits)
+	}
+	sort.Slice(detail.Pods, func(i, j int) bool {
+		if detail.Pods[i].Namespace != detail.Pods[j].Namespace {
+			return detail.Pods[i].Namespace < detail.Pods[j].Namespace
+		}
+		return detail.Pods[i].Name < detail.Pods[j].Name
+	})
+
+	for _, name := range nodeResourceNames(node, totalRequests, totalLimits) {
+		allocatable := node.Status.Allocatable[name]
+		requests := totalRequests[name]
+		limits := totalLimits[name]
+		detail.Resources = append(detail.Resources, models.NodeResourceUsage{
+			Resource:        string(name),
+			Capacity:        quantityString(node.Status.Capacity, name),
+			Allocatable:     quantityString(node.Status.Allocatable, name),
+			Requests:        requests.String(),
+			Limits:          limits.String(),
+			RequestsPercent: quantityPercent(name, requests, allocatable),
+			LimitsPercent:   quantityPercent(name, limits, allocatable),
+		})
+	}
+	return detail, nil
+}
+
+// podRequestsAndLimits 计算 Pod 的有效资源请求和限制
+// 与调度器一致：普通容器与 sidecar 累加，普通 init 容器取最大值，再加上 Pod overhead
+func podRequestsAndLimits(pod *corev1.Pod) (corev1.ResourceList, corev1.ResourceList) {
+	reqs, limits := corev1.ResourceList{}, corev1.ResourceList{}
+	for _, c := range pod.Spec.Containers {
+		addResourceList(reqs, c.Resources.Requests)
+		addResourceList(limits, c.Resources.Limits)
+	}
+	initReqs, initLimits := corev1.ResourceList{}, corev1.ResourceList{}
+	for _, c := range pod.Spec.InitContainers {
+		if c.RestartPolicy != nil && *c.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			addResourceList(reqs, c.Resources.Requests)
+			addResourceList(limits, c.Resources.Limits)
+			continue
+		}
+		maxResourceList(initReqs, c.Resources.Requests)
+		maxResourceList(initLimits, c.Resources.Limits)
+	}
+	maxResourceList(reqs, initReqs)
+	maxResourceList(limits, initLimits)
+
+	if pod.Spec.Overhead != nil {
+		addResourceList(reqs, pod.Spec.Overhead)
+		for name, quantity := range pod.Spec.Overhead {
+			// 只有设置了限制的资源才需要加上 overhead
+			if value, ok := limits[name]; ok {
+				value.Add(quantity)
+				limits[name] = value
+			}
+		}
+	}
+	return reqs, limits
+}
+
+func addResourceList(list, add corev1.ResourceList) {
+	for name, quantity := range add {
+		if value, ok := list[name]; ok {
+			value.Add(quantity)
+			list[name] = value
+		} else {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+func maxResourceList(list, other corev1.ResourceList) {
+	for name, quantity := range other {
+		if value, ok := list[name]; !ok || quantity.Cmp(value) > 0 {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+// nodeResourceNames 返回需要展示的资源名称：标准资源在前，其余按名称排序
+func nodeResourceNames(node *corev1.Node, lists ...corev1.ResourceList) []corev1.ResourceName {
+	seen := map[corev1.ResourceName]bool{corev1.ResourcePods: true}
+	names := make([]corev1.ResourceName, 0, len(node.Status.Allocatable))
+	for _, name := range standardNodeResources {
+		seen[name] = true
+		names = append(names, name)
+	}
+	var extra []corev1.ResourceName
+	for _, list := range append([]corev1.ResourceList{node.Status.Capacity, node.Status.Allocatable}, lists...) {
+		for name := range list {
+			if !seen[name] {
+				seen[name] = true
+				extra = append(extra, name)
+			}
+		}
+	}
+	sort.Slice(extra, func(i, j int) bool { return extra[i] < extra[j] })
+	return append(names, extra...)
+}
+
+func quantityString(list corev1.ResourceList, name corev1.ResourceName) string {
+	if quantity, ok := list[name]; ok {
+		return quantity.String()
+	}
+	return "0"
+}
+
+// quantityPercent 计算 used 占 total 的百分比，保留两位小数
+func quantityPercent(name corev1.ResourceName, used, total resource.Quantity) float64 {
+	var u, t int64
+	if name == corev1.ResourceCPU {
+		u, t = used.MilliValue(), total.MilliValue()
+	} else {
+		u, t = used.Value(), total.Value()
+	}
+	if t == 0 {
+		return 0
+	}
+	return math.Round(float64(u)/float64(t)*10000) / 100
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// 测试节点详情中的资源汇总与状况
+func TestNodeService_GetDetail(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: "gpu", Effect: corev1.TaintEffectNoSchedule}}},
+		Status: corev1.NodeStatus{
+			Capacity: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("8Gi"),
+				corev1.ResourcePods:   resource.MustParse("110"),
+				"nvidia.com/gpu":      resource.MustParse("2"),
+			},
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("8Gi"),
+				corev1.ResourcePods:   resource.MustParse("110"),
+				"nvidia.com/gpu":      resource.MustParse("2"),
+			},
+			Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+				{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue},
+			},
+			NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.33.0"},
+		},
+	}
+	running := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "train", Namespace: "ml"},
+		Spec: corev1.PodSpec{
+			NodeName: "node-1",
+			InitContainers: []corev1.Container{{
+				Name:      "init",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("3")}},
+			}},
+			Containers: []corev1.Container{{
+				Name: "main",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceMemory: resource.MustParse("2Gi"), "nvidia.com/gpu": resource.MustParse("1")},
+					Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2"), corev1.ResourceMemory: resource.MustParse("4Gi"), "nvidia.com/gpu": resource.MustParse("1")},
+				},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	finished := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			NodeName: "node-1",
+			Containers: []corev1.Container{{
+				Name:      "main",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodSucceeded},
+	}
+
+	service := NewNodeService(fake.NewSimpleClientset(node, running, finished))
+	detail, err := service.GetDetail("node-1")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "True", detail.Ready)
+	assert.True(t, detail.Pressure["DiskPressure"])
+	assert.False(t, detail.Pressure["MemoryPressure"])
+	assert.Equal(t, "v1.33.0", detail.KubeletVersion)
+	assert.Len(t, detail.Taints, 1)
+	assert.Len(t, detail.Pods, 2)
+	assert.Equal(t, 1, detail.PodCount)
+	assert.Equal(t, "110", detail.PodCapacity)
+
+	byName := map[string]int{}
+	for i, r := range detail.Resources {
+		byName[r.Resource] = i
+	}
+	assert.Equal(t, []string{"cpu", "memory", "ephemeral-storage", "nvidia.com/gpu"}, []string{
+		detail.Resources[0].Resource, detail.Resources[1].Resource, detail.Resources[2].Resource, detail.Resources[3].Resource,
+	})
+	// init 容器请求 3 核，大于普通容器的 1 核，按最大值计算
+	cpu := detail.Resources[byName["cpu"]]
+	assert.Equal(t, "3", cpu.Requests)
+	assert.Equal(t, 75.0, cpu.RequestsPercent)
+	assert.Equal(t, 50.0, cpu.LimitsPercent)
+	gpu := detail.Resources[byName["nvidia.com/gpu"]]
+	assert.Equal(t, "1", gpu.Requests)
+	assert.Equal(t, 50.0, gpu.RequestsPercent)
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// DefaultDrainTimeout 驱逐节点的默认超时时间
+	DefaultDrainTimeout = 5 * time.Minute
+
+	// mirrorPodAnnotation 静态 Pod 在 API Server 中对应的镜像 Pod 注解
+	mirrorPodAnnotation = "kubernetes.io/config.mirror"
+)
+
+// 驱逐被 PodDisruptionBudget 拒绝后的重试间隔，测试中可调整
+var drainRetryInterval = 5 * time.Second
+
+// DrainOptions 定义驱逐节点所需的选项
+type DrainOptions struct {
+	GracePeriodSeconds *int64
+	Timeout            time.Duration
+	Force              bool // 允许驱逐不受控制器管理的 Pod
+	DeleteEmptyDirData bool // 允许驱逐使用 emptyDir 的 Pod
+}
+
+// DrainEventFunc 接收驱逐进度事件的回调，可能被多个 goroutine 并发调用
+type DrainEventFunc func(event models.NodeDrainEvent)
+
+// Drain 封锁节点并通过 Eviction API 驱逐其上的 Pod
+// DaemonSet 管理的 Pod 和镜像 Pod 会被跳过；被 PodDisruptionBudget 拒绝的驱逐会重试直到超时
+func (s *NodeService) Drain(ctx context.Context, name string, opts DrainOptions, emit DrainEventFunc) error {
+	if emit == nil {
+		emit = func(models.NodeDrainEvent) {}
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	event := func(eventType string, pod *corev1.Pod, message string) models.NodeDrainEvent {
+		e := models.NodeDrainEvent{
+			Type:      eventType,
+			Node:      name,
+			Message:   message,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}
+		if pod != nil {
+			e.Namespace, e.Pod = pod.Namespace, pod.Name
+		}
+		return e
+	}
+
+	if _, err := s.setUnschedulable(ctx, name, true); err != nil {
+		return err
+	}
+	emit(event(models.DrainEventCordoned, nil, "节点已标记为不可调度"))
+
+	podList, err := s.client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// 先检查所有 Pod，存在无法驱逐的 Pod 时不做任何驱逐 (与 kubectl drain 行为一致)
+	var toEvict []*corev1.Pod
+	var problems []string
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		skip, reason, problem := checkDrainablePod(pod, opts)
+		switch {
+		case problem != "":
+			problems = append(problems, fmt.Sprintf("%s/%s: %s", pod.Namespace, pod.Name, problem))
+			emit(event(models.DrainEventFailed, pod, problem))
+		case skip:
+			emit(event(models.DrainEventSkipped, pod, reason))
+		default:
+			toEvict = append(toEvict, pod)
+		}
+	}
+	if len(problems) > 0 {
+		return NewValidationError("以下 Pod 无法驱逐: " + strings.Join(problems, "; "))
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed []string
+	for _, pod := range toEvict {
+		wg.Add(1)
+		go func(pod *corev1.Pod) {
+			defer wg.Done()
+			emit(event(models.DrainEventEvicting, pod, ""))
+			if err := s.evictPod(ctx, pod, opts.GracePeriodSeconds, func(msg string) {
+				emit(event(models.DrainEventRetrying, pod, msg))
+			}); err != nil {
+				emit(event(models.DrainEventFailed, pod, err.Error()))
+				mu.Lock()
+				failed = append(failed, fmt.Sprintf("%s/%s: %v", pod.Namespace, pod.Name, err))
+				mu.Unlock()
+				return
+			}
+			emit(event(models.DrainEventEvicted, pod, ""))
+		}(pod)
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d 个 Pod 驱逐失败: %s", len(failed), strings.Join(failed, "; "))
+	}
+	emit(event(models.DrainEventComplete, nil, fmt.Sprintf("已驱逐 %d 个 Pod", len(toEvict))))
+	return nil
+}
+
+// checkDrainablePod 判断 Pod 是否需要跳过，或者在当前选项下无法驱逐
+func checkDrainablePod(pod *corev1.Pod, opts DrainOptions) (skip bool, reason string, problem string) {
+	if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+		return true, "镜像 Pod (静态 Pod)，跳过", ""
+	}
+	// 已结束的 Pod 可以直接删除，不受其他限制
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false, "", ""
+	}
+	controller := metav1.GetControllerOf(pod)
+	if controller != nil && controller.Kind == "DaemonSet" {
+		return true, "DaemonSet 管理的 Pod，跳过", ""
+	}
+	if controller == nil && !opts.Force {
+		return false, "", "Pod 不受控制器管理，驱逐后不会被重建 (需要 force)"
+	}
+	if !opts.DeleteEmptyDirData {
+		for _, v := range pod.Spec.Volumes {
+			if v.EmptyDir != nil {
+				return false, "", "Pod 使用 emptyDir 本地存储 (需要 deleteEmptyDirData)"
+			}
+		}
+	}
+	return false, "", ""
+}
+
+// evictPod 通过 Eviction API 驱逐 Pod，并等待 Pod 被删除
+func (s *NodeService) evictPod(ctx context.Context, pod *corev1.Pod, gracePeriod *int64, onRetry func(string)) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		DeleteOptions: &metav1.DeleteOptions{
+			GracePeriodSeconds: gracePeriod,
+		},
+	}
+	for {
+		err := s.client.CoreV1().Pods(pod.Namespace).EvictV1(ctx, eviction)
+		if err == nil || apierrors.IsNotFound(err) {
+			break
+		}
+		if !apierrors.IsTooManyRequests(err) {
+			return err
+		}
+		// 429: 驱逐违反 PodDisruptionBudget，稍后重试
+		onRetry("驱逐被 PodDisruptionBudget 拒绝，稍后重试: " + err.Error())
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("等待 PodDisruptionBudget 允许驱逐超时: %w", ctx.Err())
+		case <-time.After(drainRetryInterval):
+		}
+	}
+
+	// 等待 Pod 被删除 (或者同名 Pod 被重建为新的 UID)
+	err := wait.PollUntilContextCancel(ctx, time.Second, true, func(ctx context.Context) (bool, error) {
+		current, err := s.client.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return current.UID != pod.UID, nil
+	})
+	if wait.Interrupted(err) {
+		return fmt.Errorf("等待 Pod 删除超时")
+	}
+	return err
+}
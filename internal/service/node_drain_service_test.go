@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newDrainTestPod(name, ownerKind string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID("uid-" + name)},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	if ownerKind != "" {
+		isController := true
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: name + "-owner", Controller: &isController}}
+	}
+	return pod
+}
+
+// evictionRecorder 模拟 Eviction API：删除被驱逐的 Pod，并可让前几次驱逐返回 429
+func evictionRecorder(fakeClient *fake.Clientset, rejectFirst int) *[]string {
+	var mu sync.Mutex
+	var evicted []string
+	rejected := 0
+	fakeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		mu.Lock()
+		defer mu.Unlock()
+		if rejected < rejectFirst {
+			rejected++
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+		evicted = append(evicted, eviction.Name)
+		err := fakeClient.Tracker().Delete(schema.GroupVersionResource{Version: "v1", Resource: "pods"}, eviction.Namespace, eviction.Name)
+		return true, nil, err
+	})
+	return &evicted
+}
+
+// 测试驱逐节点：跳过 DaemonSet 和镜像 Pod，PDB 拒绝时重试
+func TestNodeService_Drain(t *testing.T) {
+	drainRetryInterval = 10 * time.Millisecond
+
+	mirror := newDrainTestPod("static-etcd", "")
+	mirror.Annotations = map[string]string{mirrorPodAnnotation: "hash"}
+	fakeClient := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		newDrainTestPod("web-1", "ReplicaSet"),
+		newDrainTestPod("fluentd", "DaemonSet"),
+		mirror,
+	)
+	evicted := evictionRecorder(fakeClient, 1)
+
+	service := NewNodeService(fakeClient)
+	var mu sync.Mutex
+	eventTypes := map[string]int{}
+	err := service.Drain(context.TODO(), "node-1", DrainOptions{Timeout: 10 * time.Second}, func(e models.NodeDrainEvent) {
+		mu.Lock()
+		eventTypes[e.Type]++
+		mu.Unlock()
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web-1"}, *evicted)
+	assert.Equal(t, 2, eventTypes[models.DrainEventSkipped])
+	assert.Equal(t, 1, eventTypes[models.DrainEventRetrying])
+	assert.Equal(t, 1, eventTypes[models.DrainEventComplete])
+
+	node, err := service.Get("node-1")
+	assert.NoError(t, err)
+	assert.True(t, node.Spec.Unschedulable)
+
+	node, err = service.Uncordon("node-1")
+	assert.NoError(t, err)
+	assert.False(t, node.Spec.Unschedulable)
+}
+
+// 测试存在无法驱逐的 Pod 时不做任何驱逐
+func TestNodeService_DrainRefusesUnmanagedPods(t *testing.T) {
+	withEmptyDir := newDrainTestPod("cache", "ReplicaSet")
+	withEmptyDir.Spec.Volumes = []corev1.Volume{{Name: "tmp", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+	fakeClient := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		newDrainTestPod("bare", ""),
+		withEmptyDir,
+	)
+	evicted := evictionRecorder(fakeClient, 0)
+
+	service := NewNodeService(fakeClient)
+	err := service.Drain(context.TODO(), "node-1", DrainOptions{}, nil)
+	_, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Empty(t, *evicted)
+
+	err = service.Drain(context.TODO(), "node-1", DrainOptions{Force: true, DeleteEmptyDirData: true, Timeout: 10 * time.Second}, nil)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"bare", "cache"}, *evicted)
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+)
+
+// ValidateNodeLabelsRequest 校验标签 key/value 语法
+func ValidateNodeLabelsRequest(req *models.NodeLabelsRequest) error {
+	if len(req.Set) == 0 && len(req.Remove) == 0 {
+		return NewValidationError("set 和 remove 不能同时为空")
+	}
+	for key, value := range req.Set {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return NewValidationError(fmt.Sprintf("无效的标签 key '%s': %s", key, strings.Join(errs, "; ")))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return NewValidationError(fmt.Sprintf("无效的标签 value '%s': %s", value, strings.Join(errs, "; ")))
+		}
+	}
+	for _, key := range req.Remove {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return NewValidationError(fmt.Sprintf("无效的标签 key '%s': %s", key, strings.Join(errs, "; ")))
+		}
+		if _, ok := req.Set[key]; ok {
+			return NewValidationError(fmt.Sprintf("标签 '%s' 不能同时出现在 set 和 remove 中", key))
+		}
+	}
+	return nil
+}
+
+// ValidateNodeTaintsRequest 校验污点 key/value/effect
+func ValidateNodeTaintsRequest(req *models.NodeTaintsRequest) error {
+	if len(req.Set) == 0 && len(req.Remove) == 0 {
+		return NewValidationError("set 和 remove 不能同时为空")
+	}
+	seen := map[string]bool{}
+	for _, taint := range req.Set {
+		if errs := validation.IsQualifiedName(taint.Key); len(errs) > 0 {
+			return NewValidationError(fmt.Sprintf("无效的污点 key '%s': %s", taint.Key, strings.Join(errs, "; ")))
+		}
+		if errs := validation.IsValidLabelValue(taint.Value); len(errs) > 0 {
+			return NewValidationError(fmt.Sprintf("无效的污点 value '%s': %s", taint.Value, strings.Join(errs, "; ")))
+		}
+		if !isValidTaintEffect(taint.Effect) {
+			return NewValidationError(fmt.Sprintf("无效的污点 effect '%s'，可选值: NoSchedule, PreferNoSchedule, NoExecute", taint.Effect))
+		}
+		id := taint.Key + ":" + string(taint.Effect)
+		if seen[id] {
+			return NewValidationError(fmt.Sprintf("污点 '%s' 重复", id))
+		}
+		seen[id] = true
+	}
+	for _, removal := range req.Remove {
+		if errs := validation.IsQualifiedName(removal.Key); len(errs) > 0 {
+			return NewValidationError(fmt.Sprintf("无效的污点 key '%s': %s", removal.Key, strings.Join(errs, "; ")))
+		}
+		if removal.Effect != "" && !isValidTaintEffect(removal.Effect) {
+			return NewValidationError(fmt.Sprintf("无效的污点 effect '%s'，可选值: NoSchedule, PreferNoSchedule, NoExecute", removal.Effect))
+		}
+	}
+	return nil
+}
+
+func isValidTaintEffect(effect corev1.TaintEffect) bool {
+	switch effect {
+	case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		return true
+	}
+	return false
+}
+
+// UpdateLabels 通过 merge patch 新增/覆盖/删除节点标签
+func (s *NodeService) UpdateLabels(name string, req *models.NodeLabelsRequest) (*corev1.Node, error) {
+	if err := ValidateNodeLabelsRequest(req); err != nil {
+		return nil, err
+	}
+	patchLabels := make(map[string]interface{}, len(req.Set)+len(req.Remove))
+	for key, value := range req.Set {
+		patchLabels[key] = value
+	}
+	for _, key := range req.Remove {
+		patchLabels[key] = nil // merge patch 中 null 表示删除
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": patchLabels},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.client.CoreV1().Nodes().Patch(context.TODO(), name, types.MergePatchType, patch, metav1.PatchOptions{})
+}
+
+// UpdateTaints 新增/更新/删除节点污点
+// 污点列表没有合并键，因此基于当前列表计算新列表，并携带 resourceVersion 打补丁，冲突时重试
+func (s *NodeService) UpdateTaints(name string, req *models.NodeTaintsRequest) (*corev1.Node, error) {
+	if err := ValidateNodeTaintsRequest(req); err != nil {
+		return nil, err
+	}
+	var updated *corev1.Node
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := s.Get(name)
+		if err != nil {
+			return err
+		}
+		taints := mergeTaints(node.Spec.Taints, req)
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{"resourceVersion": node.ResourceVersion},
+			"spec":     map[string]interface{}{"taints": taints},
+		})
+		if err != nil {
+			return err
+		}
+		updated, err = s.client.CoreV1().Nodes().Patch(context.TODO(), name, types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	})
+	return updated, err
+}
+
+// mergeTaints 按 key+effect 合并污点：先删除 remove 中的污点，再新增或更新 set 中的污点
+func mergeTaints(current []corev1.Taint, req *models.NodeTaintsRequest) []corev1.Taint {
+	result := make([]corev1.Taint, 0, len(current)+len(req.Set))
+	for _, taint := range current {
+		removed := false
+		for _, removal := range req.Remove {
+			if taint.Key == removal.Key && (removal.Effect == "" || taint.Effect == removal.Effect) {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			result = append(result, taint)
+		}
+	}
+	for _, taint := range req.Set {
+		replaced := false
+		for i := range result {
+			if result[i].Key == taint.Key && result[i].Effect == taint.Effect {
+				result[i].Value = taint.Value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result = append(result, corev1.Taint{Key: taint.Key, Value: taint.Value, Effect: taint.Effect})
+		}
+	}
+	return result
+}
+
+// ResolveNodeNames 根据名称列表或标签选择器解析批量操作的目标节点
+func (s *NodeService) ResolveNodeNames(target *models.NodeBatchTarget) ([]string, error) {
+	if len(target.Nodes) > 0 && target.Selector != "" {
+		return nil, NewValidationError("nodes 和 selector 只能指定一个")
+	}
+	if len(target.Nodes) > 0 {
+		return target.Nodes, nil
+	}
+	if target.Selector == "" {
+		return nil, NewValidationError("必须指定 nodes 或 selector")
+	}
+	if _, err := labels.Parse(target.Selector); err != nil {
+		return nil, NewValidationError("无效的标签选择器: " + err.Error())
+	}
+	nodeList, err := s.List(target.Selector, 0)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(nodeList.Items))
+	for _, node := range nodeList.Items {
+		names = append(names, node.Name)
+	}
+	return names, nil
+}
+
+// BatchUpdateLabels 批量修改多个节点的标签，单个节点失败不影响其他节点
+func (s *NodeService) BatchUpdateLabels(target *models.NodeBatchTarget, req *models.NodeLabelsRequest) ([]models.NodeBatchResult, error) {
+	if err := ValidateNodeLabelsRequest(req); err != nil {
+		return nil, err
+	}
+	return s.batchUpdate(target, func(name string) error {
+		_, err := s.UpdateLabels(name, req)
+		return err
+	})
+}
+
+// BatchUpdateTaints 批量修改多个节点的污点，单个节点失败不影响其他节点
+func (s *NodeService) BatchUpdateTaints(target *models.NodeBatchTarget, req *models.NodeTaintsRequest) ([]models.NodeBatchResult, error) {
+	if err := ValidateNodeTaintsRequest(req); err != nil {
+		return nil, err
+	}
+	return s.batchUpdate(target, func(name string) error {
+		_, err := s.UpdateTaints(name, req)
+		return err
+	})
+}
+
+func (s *NodeService) batchUpdate(target *models.NodeBatchTarget, update func(name string) error) ([]models.NodeBatchResult, error) {
+	names, err := s.ResolveNodeNames(target)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]models.NodeBatchResult, 0, len(names))
+	for _, name := range names {
+		result := models.NodeBatchResult{Node: name, Success: true}
+		if err := update(name); err != nil {
+			result.Success = false
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
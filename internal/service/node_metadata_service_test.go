@@ -0,0 +1,63 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// 测试批量修改污点：按 key+effect 更新，effect 为空时删除该 key 的所有污点
+func TestNodeService_BatchUpdateTaints(t *testing.T) {
+	newNode := func(name string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"pool": "gpu"}},
+			Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+				{Key: "gpu", Value: "a100", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "maintenance", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "maintenance", Effect: corev1.TaintEffectNoExecute},
+			}},
+		}
+	}
+	other := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cpu-1", Labels: map[string]string{"pool": "cpu"}}}
+	service := NewNodeService(fake.NewSimpleClientset(newNode("gpu-1"), newNode("gpu-2"), other))
+
+	results, err := service.BatchUpdateTaints(&models.NodeBatchTarget{Selector: "pool=gpu"}, &models.NodeTaintsRequest{
+		Set:    []corev1.Taint{{Key: "gpu", Value: "h100", Effect: corev1.TaintEffectNoSchedule}},
+		Remove: []models.NodeTaintRemoval{{Key: "maintenance"}},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	for _, r := range results {
+		assert.True(t, r.Success)
+		node, err := service.Get(r.Node)
+		assert.NoError(t, err)
+		assert.Equal(t, []corev1.Taint{{Key: "gpu", Value: "h100", Effect: corev1.TaintEffectNoSchedule}}, node.Spec.Taints)
+	}
+
+	// 无效的 effect 直接拒绝
+	_, err = service.UpdateTaints("cpu-1", &models.NodeTaintsRequest{Set: []corev1.Taint{{Key: "x", Effect: "Never"}}})
+	_, ok := err.(*ValidationError)
+	assert.True(t, ok)
+}
+
+// 测试修改标签：新增与删除，并校验标签语法
+func TestNodeService_UpdateLabels(t *testing.T) {
+	service := NewNodeService(fake.NewSimpleClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{"old": "1", "keep": "1"}},
+	}))
+
+	node, err := service.UpdateLabels("node-1", &models.NodeLabelsRequest{
+		Set:    map[string]string{"topology.kubernetes.io/zone": "zone-a"},
+		Remove: []string{"old"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"keep": "1", "topology.kubernetes.io/zone": "zone-a"}, node.Labels)
+
+	_, err = service.UpdateLabels("node-1", &models.NodeLabelsRequest{Set: map[string]string{"bad key": "v"}})
+	_, ok := err.(*ValidationError)
+	assert.True(t, ok)
+}
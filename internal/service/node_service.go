@@ -2,9 +2,11 @@ package service
 
 import (
 	"context"
+	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 )
@@ -75,3 +77,19 @@ func (s *NodeService) Watch(selector string) (watch.Interface, error) {
 		},
 	)
 }
+
+// Cordon 将Node标记为不可调度
+func (s *NodeService) Cordon(name string) (*corev1.Node, error) {
+	return s.setUnschedulable(context.TODO(), name, true)
+}
+
+// Uncordon 恢复Node的可调度状态
+func (s *NodeService) Uncordon(name string) (*corev1.Node, error) {
+	return s.setUnschedulable(context.TODO(), name, false)
+}
+
+// setUnschedulable 通过 merge patch 修改 spec.unschedulable，避免覆盖整个 Node 对象
+func (s *NodeService) setUnschedulable(ctx context.Context, name string, unschedulable bool) (*corev1.Node, error) {
+	patch := []byte(fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable))
+	return s.client.CoreV1().Nodes().Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+}
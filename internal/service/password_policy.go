@@ -0,0 +1,86 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/configs"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// validatePassword 按密码策略校验本地账号的新密码
+func validatePassword(password, username string) error {
+	cfg := configs.GlobalConfig.Security.Password
+	if utf8.RuneCountInString(password) < cfg.MinLength {
+		return NewValidationError(fmt.Sprintf("密码长度不能少于 %d 位", cfg.MinLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{upper, lower, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	if classes < cfg.MinCharClasses {
+		return NewValidationError(fmt.Sprintf("密码至少需要包含大写字母、小写字母、数字、符号中的 %d 种", cfg.MinCharClasses))
+	}
+
+	if len(username) >= 3 && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return NewValidationError("密码不能包含用户名")
+	}
+	return nil
+}
+
+// checkPasswordHistory 新密码不能与当前密码及最近 HistorySize-1 个历史密码相同
+func checkPasswordHistory(db *gorm.DB, user *models.User, password string) error {
+	if user.CheckPassword(password) {
+		return NewValidationError("新密码不能与当前密码相同")
+	}
+	limit := configs.GlobalConfig.Security.Password.HistorySize - 1
+	if limit <= 0 {
+		return nil
+	}
+	var histories []models.PasswordHistory
+	if err := db.Where("user_id = ?", user.ID).Order("id DESC").Limit(limit).Find(&histories).Error; err != nil {
+		return err
+	}
+	for _, history := range histories {
+		if bcrypt.CompareHashAndPassword([]byte(history.Hash), []byte(password)) == nil {
+			return NewValidationError(fmt.Sprintf("新密码不能与最近 %d 次使用过的密码相同", limit+1))
+		}
+	}
+	return nil
+}
+
+// savePasswordHistory 保存即将被替换的密码哈希，只保留检查所需的条数
+func savePasswordHistory(db *gorm.DB, user *models.User) error {
+	limit := configs.GlobalConfig.Security.Password.HistorySize - 1
+	if limit <= 0 {
+		return nil
+	}
+	if err := db.Create(&models.PasswordHistory{UserID: user.ID, Hash: user.Password}).Error; err != nil {
+		return err
+	}
+	var keep []uint
+	if err := db.Model(&models.PasswordHistory{}).Where("user_id = ?", user.ID).Order("id DESC").Limit(limit).Pluck("id", &keep).Error; err != nil {
+		return err
+	}
+	return db.Where("user_id = ? AND id NOT IN ?", user.ID, keep).Delete(&models.PasswordHistory{}).Error
+}
@@ -0,0 +1,308 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultCopyMaxBytes 单次拷贝允许传输的默认最大字节数 (1GiB)
+	DefaultCopyMaxBytes int64 = 1 << 30
+	// copyProgressInterval 每传输多少字节触发一次进度回调
+	copyProgressInterval int64 = 1 << 20
+)
+
+// ErrCopySizeExceeded 拷贝数据超过大小限制
+var ErrCopySizeExceeded = errors.New("拷贝数据超过大小限制")
+
+// CopyOptions 定义容器文件拷贝所需的选项
+type CopyOptions struct {
+	Namespace     string
+	PodName       string
+	ContainerName string
+	Path          string            // 容器内的绝对路径
+	MaxBytes      int64             // 最大传输字节数，<=0 时使用 DefaultCopyMaxBytes
+	Progress      func(bytes int64) // 可选：进度回调，参数为已传输的字节数
+}
+
+func (o CopyOptions) maxBytes() int64 {
+	if o.MaxBytes <= 0 {
+		return DefaultCopyMaxBytes
+	}
+	return o.MaxBytes
+}
+
+// ValidateContainerPath 校验并规范化容器内路径，只接受绝对路径且不允许指向根目录
+func ValidateContainerPath(p string) (string, error) {
+	if p == "" {
+		return "", NewValidationError("容器路径不能为空")
+	}
+	if strings.ContainsRune(p, 0) {
+		return "", NewValidationError("容器路径包含非法字符")
+	}
+	if !strings.HasPrefix(p, "/") {
+		return "", NewValidationError("容器路径必须为绝对路径: " + p)
+	}
+	for _, part := range strings.Split(p, "/") {
+		if part == ".." {
+			return "", NewValidationError("容器路径不允许包含 '..': " + p)
+		}
+	}
+	cleaned := path.Clean(p)
+	if cleaned == "/" {
+		return "", NewValidationError("不允许拷贝容器根目录")
+	}
+	return cleaned, nil
+}
+
+// CopyFromPod 通过 exec 运行 tar 将容器内的文件或目录打包输出到 w (类似 kubectl cp)
+// 返回写入 w 的字节数
+func (s *PodService) CopyFromPod(ctx context.Context, opts CopyOptions, w io.Writer) (int64, error) {
+	srcPath, err := ValidateContainerPath(opts.Path)
+	if err != nil {
+		return 0, err
+	}
+	dir, base := path.Split(srcPath)
+
+	var stderr bytes.Buffer
+	counter := newCopyCounter(opts.maxBytes(), opts.Progress)
+	stdout := &countingWriter{w: w, counter: counter}
+
+	execErr := s.ExecIntoPod(ctx, ExecOptions{
+		Namespace:     opts.Namespace,
+		PodName:       opts.PodName,
+		ContainerName: opts.ContainerName,
+		Command:       []string{"tar", "cf", "-", "-C", dir, base},
+		Stdout:        stdout,
+		Stderr:        &stderr,
+	})
+	counter.finish()
+
+	if counter.exceeded {
+		return counter.n, ErrCopySizeExceeded
+	}
+	if execErr != nil {
+		return counter.n, execFailure(execErr, &stderr)
+	}
+	return counter.n, nil
+}
+
+// CopyToPod 将数据通过 exec 运行 tar 写入容器
+// isArchive 为 true 时 src 为 tar 归档，opts.Path 为解包目标目录，归档中的每个条目都会被校验；
+// 否则 src 为单个文件内容 (长度为 size)，opts.Path 为目标文件路径。
+// 返回从 src 读取的文件内容字节数
+func (s *PodService) CopyToPod(ctx context.Context, opts CopyOptions, src io.Reader, isArchive bool, size int64) (int64, error) {
+	destPath, err := ValidateContainerPath(opts.Path)
+	if err != nil {
+		return 0, err
+	}
+	limit := opts.maxBytes()
+	if !isArchive {
+		if size < 0 {
+			return 0, NewValidationError("上传单个文件时必须提供文件大小")
+		}
+		if size > limit {
+			return 0, ErrCopySizeExceeded
+		}
+	}
+
+	targetDir := destPath
+	if !isArchive {
+		targetDir, _ = path.Split(destPath)
+	}
+
+	counter := newCopyCounter(limit, opts.Progress)
+	pr, pw := io.Pipe()
+	writeErrCh := make(chan error, 1)
+	go func() {
+		var werr error
+		if isArchive {
+			werr = copySanitizedArchive(pw, src, counter)
+		} else {
+			werr = writeSingleFileArchive(pw, path.Base(destPath), src, size, counter)
+		}
+		counter.finish()
+		// 关闭写端让容器内的 tar 读到 EOF 正常退出；出错时传递错误以中断 exec
+		pw.CloseWithError(werr)
+		writeErrCh <- werr
+	}()
+
+	var stderr bytes.Buffer
+	execErr := s.ExecIntoPod(ctx, ExecOptions{
+		Namespace:     opts.Namespace,
+		PodName:       opts.PodName,
+		ContainerName: opts.ContainerName,
+		Command:       []string{"tar", "xmf", "-", "-C", targetDir},
+		Stdin:         pr,
+		Stderr:        &stderr,
+	})
+	// exec 提前结束时确保写端 goroutine 能够退出
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+	writeErr := <-writeErrCh
+
+	if counter.exceeded || errors.Is(writeErr, ErrCopySizeExceeded) {
+		return counter.n, ErrCopySizeExceeded
+	}
+	if writeErr != nil && !errors.Is(writeErr, io.ErrClosedPipe) {
+		return counter.n, writeErr
+	}
+	if execErr != nil {
+		return counter.n, execFailure(execErr, &stderr)
+	}
+	return counter.n, nil
+}
+
+// copySanitizedArchive 逐条校验 tar 归档并写入 w，拒绝绝对路径、路径穿越和指向目标目录之外的链接
+func copySanitizedArchive(w io.Writer, src io.Reader, counter *copyCounter) error {
+	tr := tar.NewReader(src)
+	tw := tar.NewWriter(w)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return NewValidationError("无效的 tar 归档: " + err.Error())
+		}
+		if err := validateArchiveEntry(hdr); err != nil {
+			return err
+		}
+		if hdr.Size > counter.remaining() {
+			return ErrCopySizeExceeded
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(&countingWriter{w: tw, counter: counter}, tr); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// writeSingleFileArchive 将单个文件包装成只有一个条目的 tar 归档写入 w
+func writeSingleFileArchive(w io.Writer, name string, src io.Reader, size int64, counter *copyCounter) error {
+	tw := tar.NewWriter(w)
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	n, err := io.Copy(&countingWriter{w: tw, counter: counter}, io.LimitReader(src, size))
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return NewValidationError(fmt.Sprintf("文件内容长度 (%d) 与声明的大小 (%d) 不一致", n, size))
+	}
+	return tw.Close()
+}
+
+// validateArchiveEntry 校验 tar 条目的名称和链接目标
+func validateArchiveEntry(hdr *tar.Header) error {
+	if !isSafeRelativePath(hdr.Name) {
+		return NewValidationError("tar 归档包含非法路径: " + hdr.Name)
+	}
+	switch hdr.Typeflag {
+	case tar.TypeReg, tar.TypeDir:
+		return nil
+	case tar.TypeSymlink:
+		target := hdr.Linkname
+		if !path.IsAbs(target) {
+			target = path.Join(path.Dir(hdr.Name), target)
+		}
+		if path.IsAbs(hdr.Linkname) || !isSafeRelativePath(target) {
+			return NewValidationError("tar 归档中的符号链接指向目标目录之外: " + hdr.Name + " -> " + hdr.Linkname)
+		}
+		return nil
+	case tar.TypeLink:
+		if !isSafeRelativePath(hdr.Linkname) {
+			return NewValidationError("tar 归档中的硬链接指向目标目录之外: " + hdr.Name + " -> " + hdr.Linkname)
+		}
+		return nil
+	default:
+		return NewValidationError(fmt.Sprintf("tar 归档包含不支持的条目类型 (%c): %s", hdr.Typeflag, hdr.Name))
+	}
+}
+
+func isSafeRelativePath(p string) bool {
+	if p == "" || strings.ContainsRune(p, 0) || path.IsAbs(p) {
+		return false
+	}
+	cleaned := path.Clean(p)
+	return cleaned != ".." && !strings.HasPrefix(cleaned, "../")
+}
+
+// execFailure 将 exec 错误与容器 stderr 输出合并，便于定位问题
+func execFailure(err error, stderr *bytes.Buffer) error {
+	msg := strings.TrimSpace(stderr.String())
+	if msg == "" {
+		return err
+	}
+	return fmt.Errorf("%w: %s", err, msg)
+}
+
+// copyCounter 统计已传输的字节数，负责大小限制与进度回调
+type copyCounter struct {
+	n          int64
+	max        int64
+	lastReport int64
+	exceeded   bool
+	progress   func(int64)
+}
+
+func newCopyCounter(max int64, progress func(int64)) *copyCounter {
+	return &copyCounter{max: max, progress: progress}
+}
+
+func (c *copyCounter) remaining() int64 { return c.max - c.n }
+
+func (c *copyCounter) add(n int) error {
+	c.n += int64(n)
+	if c.n > c.max {
+		c.exceeded = true
+		return ErrCopySizeExceeded
+	}
+	if c.progress != nil && c.n-c.lastReport >= copyProgressInterval {
+		c.lastReport = c.n
+		c.progress(c.n)
+	}
+	return nil
+}
+
+func (c *copyCounter) finish() {
+	if c.progress != nil && c.n != c.lastReport {
+		c.lastReport = c.n
+		c.progress(c.n)
+	}
+}
+
+// countingWriter 在写入底层 Writer 前进行计数
+type countingWriter struct {
+	w       io.Writer
+	counter *copyCounter
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > cw.counter.remaining() {
+		cw.counter.exceeded = true
+		return 0, ErrCopySizeExceeded
+	}
+	n, err := cw.w.Write(p)
+	if addErr := cw.counter.add(n); addErr != nil && err == nil {
+		err = addErr
+	}
+	return n, err
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试容器路径校验
+func TestValidateContainerPath(t *testing.T) {
+	cleaned, err := ValidateContainerPath("/tmp//dumps/heap.hprof")
+	assert.NoError(t, err)
+	assert.Equal(t, "/tmp/dumps/heap.hprof", cleaned)
+
+	for _, p := range []string{"", "tmp/a", "/", "/tmp/../etc/passwd", "/tmp/a\x00b"} {
+		_, err := ValidateContainerPath(p)
+		assert.Error(t, err, p)
+	}
+}
+
+// 测试上传归档时拒绝路径穿越和越界链接
+func TestCopySanitizedArchive(t *testing.T) {
+	build := func(hdrs ...*tar.Header) *bytes.Buffer {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, hdr := range hdrs {
+			assert.NoError(t, tw.WriteHeader(hdr))
+			if hdr.Size > 0 {
+				_, err := tw.Write(bytes.Repeat([]byte("x"), int(hdr.Size)))
+				assert.NoError(t, err)
+			}
+		}
+		assert.NoError(t, tw.Close())
+		return &buf
+	}
+
+	var out bytes.Buffer
+	counter := newCopyCounter(DefaultCopyMaxBytes, nil)
+	err := copySanitizedArchive(&out, build(
+		&tar.Header{Typeflag: tar.TypeDir, Name: "conf/", Mode: 0755},
+		&tar.Header{Typeflag: tar.TypeReg, Name: "conf/app.yaml", Mode: 0644, Size: 4},
+		&tar.Header{Typeflag: tar.TypeSymlink, Name: "conf/current", Linkname: "app.yaml"},
+	), counter)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), counter.n)
+
+	bad := []*tar.Header{
+		{Typeflag: tar.TypeReg, Name: "../escape", Size: 1},
+		{Typeflag: tar.TypeReg, Name: "/etc/passwd", Size: 1},
+		{Typeflag: tar.TypeSymlink, Name: "link", Linkname: "/etc/shadow"},
+		{Typeflag: tar.TypeSymlink, Name: "a/link", Linkname: "../../etc"},
+		{Typeflag: tar.TypeChar, Name: "dev"},
+	}
+	for _, hdr := range bad {
+		err := copySanitizedArchive(&bytes.Buffer{}, build(hdr), newCopyCounter(DefaultCopyMaxBytes, nil))
+		assert.Error(t, err, hdr.Name)
+		_, ok := err.(*ValidationError)
+		assert.True(t, ok, hdr.Name)
+	}
+
+	// 超过大小限制
+	err = copySanitizedArchive(&bytes.Buffer{}, build(
+		&tar.Header{Typeflag: tar.TypeReg, Name: "big", Size: 16},
+	), newCopyCounter(8, nil))
+	assert.ErrorIs(t, err, ErrCopySizeExceeded)
+}
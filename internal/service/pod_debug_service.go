@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// DefaultDebugTimeout 等待调试容器启动的默认超时时间
+	DefaultDebugTimeout = 60 * time.Second
+	// MaxDebugTimeout 等待调试容器启动的最大超时时间
+	MaxDebugTimeout = 5 * time.Minute
+
+	debugPollInterval = time.Second
+)
+
+// DebugContainerOptions 定义创建临时调试容器所需的选项
+type DebugContainerOptions struct {
+	Name            string   // 调试容器名称，为空时自动生成
+	Image           string   // 调试镜像
+	TargetContainer string   // 可选：共享进程命名空间的目标容器
+	Command         []string // 可选：覆盖镜像的 entrypoint
+	Timeout         time.Duration
+}
+
+// CreateDebugContainer 通过 ephemeralcontainers 子资源向 Pod 添加临时调试容器，
+// 并等待其进入 Running 状态，返回容器名称
+func (s *PodService) CreateDebugContainer(ctx context.Context, namespace, podName string, opts DebugContainerOptions) (string, error) {
+	if opts.Image == "" {
+		return "", NewValidationError("调试镜像不能为空")
+	}
+
+	pod, err := s.Get(namespace, podName)
+	if err != nil {
+		return "", err
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		return "", NewValidationError(fmt.Sprintf("Pod 当前状态为 %s，只能对运行中的 Pod 添加调试容器", pod.Status.Phase))
+	}
+
+	if opts.TargetContainer != "" && !hasRegularContainer(pod, opts.TargetContainer) {
+		return "", NewValidationError(fmt.Sprintf("目标容器 '%s' 在 Pod '%s' 中未找到", opts.TargetContainer, podName))
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = "debugger-" + utilrand.String(5)
+	}
+	if containerNameInUse(pod, name) {
+		return "", NewValidationError(fmt.Sprintf("容器名称 '%s' 已被占用", name))
+	}
+
+	debugPod := pod.DeepCopy()
+	debugPod.Spec.EphemeralContainers = append(debugPod.Spec.EphemeralContainers, corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:                     name,
+			Image:                    opts.Image,
+			Command:                  opts.Command,
+			ImagePullPolicy:          corev1.PullIfNotPresent,
+			Stdin:                    true,
+			TTY:                      true,
+			TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+		},
+		TargetContainerName: opts.TargetContainer,
+	})
+
+	if _, err := s.client.CoreV1().Pods(namespace).UpdateEphemeralContainers(ctx, podName, debugPod, metav1.UpdateOptions{}); err != nil {
+		return "", err
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDebugTimeout
+	}
+	if timeout > MaxDebugTimeout {
+		timeout = MaxDebugTimeout
+	}
+	if err := s.waitForEphemeralContainer(ctx, namespace, podName, name, timeout); err != nil {
+		return name, err
+	}
+	return name, nil
+}
+
+// waitForEphemeralContainer 轮询 Pod 状态直到临时容器运行、失败或超时
+func (s *PodService) waitForEphemeralContainer(ctx context.Context, namespace, podName, container string, timeout time.Duration) error {
+	var lastState string
+	err := wait.PollUntilContextTimeout(ctx, debugPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		pod, err := s.client.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, status := range pod.Status.EphemeralContainerStatuses {
+			if status.Name != container {
+				continue
+			}
+			switch {
+			case status.State.Running != nil:
+				return true, nil
+			case status.State.Terminated != nil:
+				return false, fmt.Errorf("调试容器已退出: %s (exit code %d) %s",
+					status.State.Terminated.Reason, status.State.Terminated.ExitCode, status.State.Terminated.Message)
+			case status.State.Waiting != nil:
+				lastState = status.State.Waiting.Reason
+				if isFatalWaitingReason(status.State.Waiting.Reason) {
+					return false, fmt.Errorf("调试容器启动失败: %s %s", status.State.Waiting.Reason, status.State.Waiting.Message)
+				}
+			}
+		}
+		return false, nil
+	})
+	if wait.Interrupted(err) {
+		if lastState == "" {
+			lastState = "未知"
+		}
+		return fmt.Errorf("等待调试容器 '%s' 启动超时 (最后状态: %s)", container, lastState)
+	}
+	return err
+}
+
+func isFatalWaitingReason(reason string) bool {
+	switch reason {
+	case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "CreateContainerError", "CreateContainerConfigError", "RunContainerError":
+		return true
+	}
+	return false
+}
+
+func hasRegularContainer(pod *corev1.Pod, name string) bool {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containerNameInUse(pod *corev1.Pod, name string) bool {
+	for _, c := range append(pod.Spec.Containers, pod.Spec.InitContainers...) {
+		if c.Name == name {
+			return true
+		}
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// 测试添加临时调试容器并等待其启动
+func TestPodService_CreateDebugContainer(t *testing.T) {
+	fakeClient := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "main", Image: "gcr.io/distroless/static"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	})
+
+	// 模拟 kubelet：ephemeralcontainers 子资源更新后立即将容器状态置为 Running
+	fakeClient.PrependReactor("update", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "ephemeralcontainers" {
+			return false, nil, nil
+		}
+		pod := action.(k8stesting.UpdateAction).GetObject().(*corev1.Pod).DeepCopy()
+		for _, ec := range pod.Spec.EphemeralContainers {
+			pod.Status.EphemeralContainerStatuses = append(pod.Status.EphemeralContainerStatuses, corev1.ContainerStatus{
+				Name:  ec.Name,
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			})
+		}
+		err := fakeClient.Tracker().Update(corev1.SchemeGroupVersion.WithResource("pods"), pod, pod.Namespace)
+		return true, pod, err
+	})
+
+	service := NewPodService(fakeClient, nil)
+
+	name, err := service.CreateDebugContainer(context.TODO(), "default", "app", DebugContainerOptions{
+		Image:           "busybox:1.36",
+		TargetContainer: "main",
+		Timeout:         5 * time.Second,
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, name, "debugger-")
+
+	pod, err := service.Get("default", "app")
+	assert.NoError(t, err)
+	assert.Len(t, pod.Spec.EphemeralContainers, 1)
+	assert.Equal(t, "main", pod.Spec.EphemeralContainers[0].TargetContainerName)
+	assert.Equal(t, "busybox:1.36", pod.Spec.EphemeralContainers[0].Image)
+
+	// 目标容器不存在
+	_, err = service.CreateDebugContainer(context.TODO(), "default", "app", DebugContainerOptions{
+		Image:           "busybox:1.36",
+		TargetContainer: "missing",
+	})
+	_, ok := err.(*ValidationError)
+	assert.True(t, ok)
+
+	// 容器名称冲突
+	_, err = service.CreateDebugContainer(context.TODO(), "default", "app", DebugContainerOptions{
+		Name:  name,
+		Image: "busybox:1.36",
+	})
+	_, ok = err.(*ValidationError)
+	assert.True(t, ok)
+}
@@ -0,0 +1,576 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/util"
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/pkg/auth"
+)
+
+var (
+	ErrCasbinRoleNotFound     = errors.New("角色不存在")
+	ErrRoleAssignmentNotFound = errors.New("角色分配不存在")
+)
+
+// previewDefaultNamespace 预览时命名空间级路由默认使用的命名空间
+const previewDefaultNamespace = "default"
+
+// workloadResources 命名空间内可由团队自行管理的资源
+var workloadResources = []string{
+	"pods*", "deployments*", "daemonsets*", "statefulsets*", "services*", "ingresses*",
+	"networkpolicies*", "configmaps*", "secrets*", "pvcs*", "events*",
+}
+
+// roleTemplates 预置的权限模板
+var roleTemplates = []models.RoleTemplate{
+	{Name: "viewer", Description: "只读访问所有资源", Rules: []models.PermissionRule{
+		{Resource: "*", Verbs: []string{"get", "list", "watch"}},
+	}},
+	{Name: "editor", Description: "只读访问所有资源，并可管理工作负载、服务、配置和存储声明", Rules: append([]models.PermissionRule{
+		{Resource: "*", Verbs: []string{"get", "list", "watch"}},
+	}, fullAccessRules(workloadResources...)...)},
+	{Name: "namespace-admin", Description: "在 editor 基础上可管理命名空间内的 Role、RoleBinding 和 ServiceAccount", Rules: append([]models.PermissionRule{
+		{Resource: "*", Verbs: []string{"get", "list", "watch"}},
+	}, fullAccessRules(append(workloadResources, "rbac/roles*", "rbac/roleBindings*", "rbac/serviceAccounts*")...)...)},
+	{Name: "node-operator", Description: "只读访问所有资源，并可管理节点 (封锁、驱逐、标签、污点)", Rules: []models.PermissionRule{
+		{Resource: "*", Verbs: []string{"get", "list", "watch"}},
+		{Resource: "nodes*", Verbs: []string{"*"}},
+	}},
+	{Name: "admin", Description: "所有资源的所有权限", Rules: []models.PermissionRule{
+		{Resource: "*", Verbs: []string{"*"}},
+	}},
+}
+
+func fullAccessRules(resources ...string) []models.PermissionRule {
+	rules := make([]models.PermissionRule, 0, len(resources))
+	for _, resource := range resources {
+		rules = append(rules, models.PermissionRule{Resource: resource, Verbs: []string{"*"}})
+	}
+	return rules
+}
+
+// PolicyService 在运行时管理 Casbin 策略、自定义角色和角色分配
+type PolicyService struct {
+	enforcer *casbin.Enforcer
+	cluster  string // 当前集群名称，预览时作为默认集群
+
+	mu     sync.Mutex
+	routes []models.RoutePermission // 已注册的 API 路由，用于预览策略变更的影响
+}
+
+func NewPolicyService(enforcer *casbin.Enforcer, cluster string) *PolicyService {
+	return &PolicyService{enforcer: enforcer, cluster: cluster}
+}
+
+// SetRoutes 设置参与预览计算的路由 (只需 Method 和 Path)，在路由注册完成后调用
+func (s *PolicyService) SetRoutes(routes []models.RoutePermission) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes = routes
+}
+
+// ListPolicies 列出策略和角色继承关系，role 不为空时只返回与该角色相关的条目
+func (s *PolicyService) ListPolicies(role string) (*models.PolicyListResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	policies, groupings, err := s.loadRules()
+	if err != nil {
+		return nil, err
+	}
+	resp := &models.PolicyListResponse{Policies: []models.CasbinPolicy{}, Groupings: []models.CasbinGrouping{}}
+	for _, p := range policies {
+		if role == "" || p[0] == role {
+			resp.Policies = append(resp.Policies, toCasbinPolicy(p))
+		}
+	}
+	for _, g := range groupings {
+		if role == "" || g[0] == role || g[1] == role {
+			resp.Groupings = append(resp.Groupings, toCasbinGrouping(g))
+		}
+	}
+	return resp, nil
+}
+
+// ApplyChanges 批量删除和添加策略，全部校验通过后才会写入
+func (s *PolicyService) ApplyChanges(req *models.PolicyChangeRequest) error {
+	if err := validatePolicyChanges(req); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return applyPolicyChanges(s.enforcer, req)
+}
+
+// ListRoleTemplates 返回预置的权限模板
+func (s *PolicyService) ListRoleTemplates() []models.RoleTemplate {
+	return roleTemplates
+}
+
+// ListRoles 列出所有角色 (出现在策略中的主体以及被继承的角色) 及其策略和成员
+func (s *PolicyService) ListRoles() ([]models.CasbinRole, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	policies, groupings, err := s.loadRules()
+	if err != nil {
+		return nil, err
+	}
+	roles := map[string]*models.CasbinRole{}
+	getRole := func(name string) *models.CasbinRole {
+		if role, ok := roles[name]; ok {
+			return role
+		}
+		role := &models.CasbinRole{Name: name, BuiltIn: auth.IsBuiltInRole(name), Policies: []models.CasbinPolicy{}, Members: []models.CasbinGrouping{}}
+		roles[name] = role
+		return role
+	}
+	for _, p := range policies {
+		role := getRole(p[0])
+		role.Policies = append(role.Policies, toCasbinPolicy(p))
+	}
+	for _, g := range groupings {
+		role := getRole(g[1])
+		role.Members = append(role.Members, toCasbinGrouping(g))
+	}
+
+	result := make([]models.CasbinRole, 0, len(roles))
+	for _, role := range roles {
+		result = append(result, *role)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// CreateRole 按模板和额外规则创建自定义角色
+func (s *PolicyService) CreateRole(req *models.CreateCasbinRoleRequest) (*models.CasbinRole, error) {
+	if err := validateRoleName(req.Name); err != nil {
+		return nil, err
+	}
+	if auth.IsBuiltInRole(req.Name) {
+		return nil, NewValidationError(fmt.Sprintf("不能修改内置角色 %s", req.Name))
+	}
+
+	var rules []models.PermissionRule
+	if req.Template != "" {
+		template, ok := findRoleTemplate(req.Template)
+		if !ok {
+			return nil, NewValidationError(fmt.Sprintf("权限模板 %s 不存在", req.Template))
+		}
+		rules = append(rules, template.Rules...)
+	}
+	rules = append(rules, req.Rules...)
+	if len(rules) == 0 {
+		return nil, NewValidationError("必须指定权限模板或至少一条权限规则")
+	}
+	domains := req.Domains
+	if len(domains) == 0 {
+		domains = []string{auth.AllDomains}
+	}
+
+	change := &models.PolicyChangeRequest{}
+	for _, domain := range domains {
+		for _, rule := range rules {
+			for _, verb := range rule.Verbs {
+				change.AddPolicies = append(change.AddPolicies, models.CasbinPolicy{Role: req.Name, Domain: domain, Resource: rule.Resource, Verb: verb})
+			}
+		}
+	}
+	if err := validatePolicyChanges(change); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, err := s.enforcer.GetFilteredPolicy(0, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, NewValidationError(fmt.Sprintf("角色 %s 已存在", req.Name))
+	}
+	if err := applyPolicyChanges(s.enforcer, change); err != nil {
+		return nil, err
+	}
+	return &models.CasbinRole{Name: req.Name, Policies: change.AddPolicies, Members: []models.CasbinGrouping{}}, nil
+}
+
+// DeleteRole 删除自定义角色的所有策略以及与之相关的角色继承关系
+func (s *PolicyService) DeleteRole(name string) error {
+	if auth.IsBuiltInRole(name) {
+		return NewValidationError(fmt.Sprintf("不能删除内置角色 %s", name))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	policies, err := s.enforcer.GetFilteredPolicy(0, name)
+	if err != nil {
+		return err
+	}
+	members, err := s.enforcer.GetFilteredGroupingPolicy(1, name)
+	if err != nil {
+		return err
+	}
+	if len(policies) == 0 && len(members) == 0 {
+		return ErrCasbinRoleNotFound
+	}
+	parents, err := s.enforcer.GetFilteredGroupingPolicy(0, name)
+	if err != nil {
+		return err
+	}
+
+	// 逐条删除以便中途失败时回滚
+	req := &models.PolicyChangeRequest{}
+	for _, p := range policies {
+		req.RemovePolicies = append(req.RemovePolicies, models.CasbinPolicy{Role: p[0], Domain: p[1], Resource: p[2], Verb: p[3]})
+	}
+	for _, g := range append(members, parents...) {
+		req.RemoveGroupings = append(req.RemoveGroupings, models.CasbinGrouping{Member: g[0], Role: g[1], Domain: g[2]})
+	}
+	return applyPolicyChanges(s.enforcer, req)
+}
+
+// AssignRole 在指定集群/命名空间中为用户分配角色
+func (s *PolicyService) AssignRole(req *models.RoleAssignmentRequest) (*models.CasbinGrouping, error) {
+	grouping, err := toAssignmentGrouping(req)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !auth.IsBuiltInRole(req.Role) {
+		policies, err := s.enforcer.GetFilteredPolicy(0, req.Role)
+		if err != nil {
+			return nil, err
+		}
+		if len(policies) == 0 {
+			return nil, ErrCasbinRoleNotFound
+		}
+	}
+	if err := applyPolicyChanges(s.enforcer, &models.PolicyChangeRequest{AddGroupings: []models.CasbinGrouping{*grouping}}); err != nil {
+		return nil, err
+	}
+	return grouping, nil
+}
+
+// UnassignRole 撤销用户在指定集群/命名空间中的角色
+func (s *PolicyService) UnassignRole(req *models.RoleAssignmentRequest) error {
+	grouping, err := toAssignmentGrouping(req)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed, err := s.enforcer.RemoveGroupingPolicy(grouping.Member, grouping.Role, grouping.Domain)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrRoleAssignmentNotFound
+	}
+	return nil
+}
+
+// PreviewChanges 在内存中应用策略变更，对比变更前后角色或用户可访问的路由
+// role 和 username 同时提供时与中间件一致：任一主体允许即可访问
+func (s *PolicyService) PreviewChanges(req *models.PolicyPreviewRequest) (*models.PolicyPreviewResponse, error) {
+	if req.Role == "" && req.Username == "" {
+		return nil, NewValidationError("必须指定 role 或 username")
+	}
+	if err := validatePolicyChanges(&req.Changes); err != nil {
+		return nil, err
+	}
+	subjects := make([]string, 0, 2)
+	if req.Role != "" {
+		subjects = append(subjects, req.Role)
+	}
+	if req.Username != "" {
+		subjects = append(subjects, auth.UserSubject(req.Username))
+	}
+	cluster := req.Cluster
+	if cluster == "" {
+		cluster = s.cluster
+	}
+	namespace := req.Namespace
+	if namespace == "" {
+		namespace = previewDefaultNamespace
+	}
+
+	s.mu.Lock()
+	policies, groupings, err := s.loadRules()
+	routes := s.routes
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := auth.NewMemoryEnforcer(policies, groupings)
+	if err != nil {
+		return nil, err
+	}
+	after, err := auth.NewMemoryEnforcer(policies, groupings)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyPolicyChanges(after, &req.Changes); err != nil {
+		return nil, err
+	}
+
+	resp := &models.PolicyPreviewResponse{Subject: strings.Join(subjects, ","), Gained: []models.RoutePermission{}, Lost: []models.RoutePermission{}}
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, "/api/v1/") || strings.HasPrefix(route.Path, "/api/v1/auth/") {
+			continue
+		}
+		routeNamespace := ""
+		if strings.Contains(route.Path, ":namespace") {
+			routeNamespace = namespace
+		}
+		attrs := auth.ParseRouteAttributes(route.Method, route.Path, cluster, routeNamespace)
+		allowedBefore, err := enforceAny(before, subjects, attrs)
+		if err != nil {
+			return nil, err
+		}
+		allowedAfter, err := enforceAny(after, subjects, attrs)
+		if err != nil {
+			return nil, err
+		}
+		if allowedBefore == allowedAfter {
+			continue
+		}
+		permission := models.RoutePermission{Method: route.Method, Path: route.Path, Domain: attrs.Domain(), Resource: attrs.Resource, Verb: attrs.Verb}
+		if allowedAfter {
+			resp.Gained = append(resp.Gained, permission)
+		} else {
+			resp.Lost = append(resp.Lost, permission)
+		}
+	}
+	sortRoutePermissions(resp.Gained)
+	sortRoutePermissions(resp.Lost)
+	return resp, nil
+}
+
+func (s *PolicyService) loadRules() ([][]string, [][]string, error) {
+	policies, err := s.enforcer.GetPolicy()
+	if err != nil {
+		return nil, nil, err
+	}
+	groupings, err := s.enforcer.GetGroupingPolicy()
+	if err != nil {
+		return nil, nil, err
+	}
+	return policies, groupings, nil
+}
+
+func enforceAny(e *casbin.Enforcer, subjects []string, attrs auth.RequestAttributes) (bool, error) {
+	for _, subject := range subjects {
+		allowed, err := e.Enforce(subject, attrs.Domain(), attrs.Resource, attrs.Verb)
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+	return false, nil
+}
+
+// applyPolicyChanges 先删除再添加，已存在的策略会被跳过；调用前需已通过 validatePolicyChanges
+// Enforcer 的每次调用单独写入存储，中途失败时按相反顺序撤销已生效的变更，避免策略只应用一部分
+func applyPolicyChanges(e *casbin.Enforcer, req *models.PolicyChangeRequest) (err error) {
+	var undo []func() (bool, error)
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := len(undo) - 1; i >= 0; i-- {
+			if _, rollbackErr := undo[i](); rollbackErr != nil {
+				log.Printf("回滚策略变更失败: %v", rollbackErr)
+				err = fmt.Errorf("%w (回滚失败，策略可能不完整: %v)", err, rollbackErr)
+				return
+			}
+		}
+	}()
+
+	for _, p := range req.RemovePolicies {
+		rule := []string{p.Role, p.Domain, p.Resource, p.Verb}
+		removed, err := e.RemovePolicy(rule)
+		if err != nil {
+			return fmt.Errorf("删除策略失败: %w", err)
+		}
+		if removed {
+			undo = append(undo, func() (bool, error) { return e.AddPolicy(rule) })
+		}
+	}
+	for _, g := range req.RemoveGroupings {
+		rule := []string{g.Member, g.Role, g.Domain}
+		removed, err := e.RemoveGroupingPolicy(rule)
+		if err != nil {
+			return fmt.Errorf("删除角色继承关系失败: %w", err)
+		}
+		if removed {
+			undo = append(undo, func() (bool, error) { return e.AddGroupingPolicy(rule) })
+		}
+	}
+	for _, p := range req.AddPolicies {
+		rule := []string{p.Role, p.Domain, p.Resource, p.Verb}
+		added, err := e.AddPolicy(rule)
+		if err != nil {
+			return fmt.Errorf("添加策略失败: %w", err)
+		}
+		if added {
+			undo = append(undo, func() (bool, error) { return e.RemovePolicy(rule) })
+		}
+	}
+	for _, g := range req.AddGroupings {
+		rule := []string{g.Member, g.Role, g.Domain}
+		added, err := e.AddGroupingPolicy(rule)
+		if err != nil {
+			return fmt.Errorf("添加角色继承关系失败: %w", err)
+		}
+		if added {
+			undo = append(undo, func() (bool, error) { return e.RemoveGroupingPolicy(rule) })
+		}
+	}
+	return nil
+}
+
+func validatePolicyChanges(req *models.PolicyChangeRequest) error {
+	for _, p := range append(append([]models.CasbinPolicy{}, req.AddPolicies...), req.RemovePolicies...) {
+		if err := validateCasbinPolicy(p); err != nil {
+			return err
+		}
+	}
+	for _, g := range req.AddGroupings {
+		if err := validateCasbinGrouping(g); err != nil {
+			return err
+		}
+	}
+	for _, g := range req.RemoveGroupings {
+		if err := validateCasbinGrouping(g); err != nil {
+			return err
+		}
+		if auth.IsDefaultPolicy([]string{g.Member, g.Role, g.Domain}) {
+			return NewValidationError(fmt.Sprintf("不能删除默认角色继承关系 %s -> %s", g.Member, g.Role))
+		}
+	}
+	return nil
+}
+
+func validateCasbinPolicy(p models.CasbinPolicy) error {
+	if err := validateRoleName(p.Role); err != nil {
+		return err
+	}
+	if auth.IsBuiltInRole(p.Role) {
+		return NewValidationError(fmt.Sprintf("不能修改内置角色 %s 的策略", p.Role))
+	}
+	if err := validatePolicyDomain(p.Domain); err != nil {
+		return err
+	}
+	if p.Resource == "" || strings.ContainsAny(p.Resource, " \t") {
+		return NewValidationError(fmt.Sprintf("资源 %q 无效", p.Resource))
+	}
+	if !containsString(auth.AllVerbs, p.Verb) {
+		return NewValidationError(fmt.Sprintf("动作 %q 无效，可选值: %s", p.Verb, strings.Join(auth.AllVerbs, ", ")))
+	}
+	return nil
+}
+
+func validateCasbinGrouping(g models.CasbinGrouping) error {
+	if g.Member == "" || strings.ContainsAny(g.Member, " \t") {
+		return NewValidationError(fmt.Sprintf("成员 %q 无效", g.Member))
+	}
+	if err := validateRoleName(g.Role); err != nil {
+		return err
+	}
+	if g.Member == g.Role {
+		return NewValidationError("角色不能继承自身")
+	}
+	return validatePolicyDomain(g.Domain)
+}
+
+func validateRoleName(name string) error {
+	if name == "" || strings.ContainsAny(name, " \t,") || strings.HasPrefix(name, auth.UserSubject("")) {
+		return NewValidationError(fmt.Sprintf("角色名称 %q 无效", name))
+	}
+	// 用户表中的角色已映射到内置角色，以它为名的策略会静默授予该角色的所有用户
+	if auth.IsUserTableRole(name) {
+		return NewValidationError(fmt.Sprintf("角色名称 %q 与用户表中的角色冲突", name))
+	}
+	return nil
+}
+
+// validatePolicyDomain 域为 "集群/命名空间" 形式的 glob 模式，或 "**" 表示所有域
+func validatePolicyDomain(domain string) error {
+	if domain == auth.AllDomains {
+		return nil
+	}
+	parts := strings.Split(domain, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" || strings.ContainsAny(domain, " \t") {
+		return NewValidationError(fmt.Sprintf("域 %q 无效，应为 集群/命名空间 (支持 * 通配) 或 **", domain))
+	}
+	if _, err := util.GlobMatch(domain, domain); err != nil {
+		return NewValidationError(fmt.Sprintf("域 %q 不是合法的通配模式: %v", domain, err))
+	}
+	return nil
+}
+
+// toAssignmentGrouping 集群或命名空间为空时分别匹配所有集群或所有命名空间
+func toAssignmentGrouping(req *models.RoleAssignmentRequest) (*models.CasbinGrouping, error) {
+	if req.Username == "" || strings.ContainsAny(req.Username, " \t") {
+		return nil, NewValidationError(fmt.Sprintf("用户名 %q 无效", req.Username))
+	}
+	cluster, namespace := req.Cluster, req.Namespace
+	if cluster == "" {
+		cluster = "*"
+	}
+	if namespace == "" {
+		namespace = "*"
+	}
+	grouping := &models.CasbinGrouping{Member: auth.UserSubject(req.Username), Role: req.Role, Domain: cluster + "/" + namespace}
+	if err := validateCasbinGrouping(*grouping); err != nil {
+		return nil, err
+	}
+	return grouping, nil
+}
+
+func findRoleTemplate(name string) (models.RoleTemplate, bool) {
+	for _, template := range roleTemplates {
+		if template.Name == name {
+			return template, true
+		}
+	}
+	return models.RoleTemplate{}, false
+}
+
+func toCasbinPolicy(p []string) models.CasbinPolicy {
+	policy := models.CasbinPolicy{}
+	fields := []*string{&policy.Role, &policy.Domain, &policy.Resource, &policy.Verb}
+	for i := 0; i < len(p) && i < len(fields); i++ {
+		*fields[i] = p[i]
+	}
+	return policy
+}
+
+func toCasbinGrouping(g []string) models.CasbinGrouping {
+	grouping := models.CasbinGrouping{}
+	fields := []*string{&grouping.Member, &grouping.Role, &grouping.Domain}
+	for i := 0; i < len(g) && i < len(fields); i++ {
+		*fields[i] = g[i]
+	}
+	return grouping
+}
+
+func sortRoutePermissions(routes []models.RoutePermission) {
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+}
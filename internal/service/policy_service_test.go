@@ -0,0 +1,161 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPolicyService(t *testing.T) *PolicyService {
+	e, err := auth.NewMemoryEnforcer(
+		[][]string{{auth.RoleSuperAdmin, auth.AllDomains, "*", "*"}, {auth.RoleNormalUser, auth.AllDomains, "*", "get"}},
+		[][]string{{"admin", auth.RoleSuperAdmin, auth.AllDomains}},
+	)
+	require.NoError(t, err)
+	svc := NewPolicyService(e, "prod")
+	svc.SetRoutes([]models.RoutePermission{
+		{Method: http.MethodGet, Path: "/api/v1/namespaces/:namespace/pods"},
+		{Method: http.MethodDelete, Path: "/api/v1/namespaces/:namespace/pods/:name"},
+		{Method: http.MethodPost, Path: "/api/v1/namespaces/:namespace/pods/:name/exec"},
+		{Method: http.MethodPost, Path: "/api/v1/nodes/:name/drain"},
+		{Method: http.MethodPost, Path: "/api/v1/auth/login"},
+	})
+	return svc
+}
+
+func TestPolicyService_RolesAndAssignments(t *testing.T) {
+	svc := newTestPolicyService(t)
+
+	_, err := svc.CreateRole(&models.CreateCasbinRoleRequest{Name: "team-editor", Template: "editor"})
+	require.NoError(t, err)
+	_, err = svc.CreateRole(&models.CreateCasbinRoleRequest{Name: "team-editor", Template: "viewer"})
+	assert.IsType(t, &ValidationError{}, err, "重复创建角色")
+	_, err = svc.CreateRole(&models.CreateCasbinRoleRequest{Name: auth.RoleSuperAdmin, Template: "viewer"})
+	assert.IsType(t, &ValidationError{}, err, "不能覆盖内置角色")
+	for _, name := range []string{auth.UserRoleAdmin, auth.UserRoleUser} {
+		_, err = svc.CreateRole(&models.CreateCasbinRoleRequest{Name: name, Template: "editor"})
+		assert.IsType(t, &ValidationError{}, err, "不能使用用户表中的角色名 %s", name)
+	}
+	err = svc.ApplyChanges(&models.PolicyChangeRequest{AddPolicies: []models.CasbinPolicy{{Role: auth.UserRoleUser, Domain: auth.AllDomains, Resource: "*", Verb: "create"}}})
+	assert.IsType(t, &ValidationError{}, err, "不能直接给用户表中的角色添加策略")
+	allowed, err := svc.enforcer.Enforce(auth.UserRoleUser, "prod/team-a", "pods", "create")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	grouping, err := svc.AssignRole(&models.RoleAssignmentRequest{Username: "alice", Role: "team-editor", Cluster: "prod", Namespace: "team-a"})
+	require.NoError(t, err)
+	assert.Equal(t, models.CasbinGrouping{Member: "user:alice", Role: "team-editor", Domain: "prod/team-a"}, *grouping)
+	_, err = svc.AssignRole(&models.RoleAssignmentRequest{Username: "alice", Role: "missing"})
+	assert.ErrorIs(t, err, ErrCasbinRoleNotFound)
+
+	allowed, err = svc.enforcer.Enforce(auth.UserSubject("alice"), "prod/team-a", "pods/exec", "create")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, err = svc.enforcer.Enforce(auth.UserSubject("alice"), "prod/team-b", "pods", "list")
+	require.NoError(t, err)
+	assert.False(t, allowed, "分配范围之外的命名空间")
+
+	// 默认策略和默认角色继承关系受保护
+	err = svc.ApplyChanges(&models.PolicyChangeRequest{RemovePolicies: []models.CasbinPolicy{{Role: auth.RoleSuperAdmin, Domain: auth.AllDomains, Resource: "*", Verb: "*"}}})
+	assert.IsType(t, &ValidationError{}, err)
+	err = svc.ApplyChanges(&models.PolicyChangeRequest{RemoveGroupings: []models.CasbinGrouping{{Member: "admin", Role: auth.RoleSuperAdmin, Domain: auth.AllDomains}}})
+	assert.IsType(t, &ValidationError{}, err)
+
+	roles, err := svc.ListRoles()
+	require.NoError(t, err)
+	require.Len(t, roles, 3)
+	assert.Equal(t, "team-editor", roles[2].Name)
+	assert.Len(t, roles[2].Members, 1)
+
+	require.NoError(t, svc.UnassignRole(&models.RoleAssignmentRequest{Username: "alice", Role: "team-editor", Cluster: "prod", Namespace: "team-a"}))
+	assert.ErrorIs(t, svc.UnassignRole(&models.RoleAssignmentRequest{Username: "alice", Role: "team-editor", Cluster: "prod", Namespace: "team-a"}), ErrRoleAssignmentNotFound)
+	require.NoError(t, svc.DeleteRole("team-editor"))
+	assert.ErrorIs(t, svc.DeleteRole("team-editor"), ErrCasbinRoleNotFound)
+}
+
+func TestPolicyService_PreviewChanges(t *testing.T) {
+	svc := newTestPolicyService(t)
+	_, err := svc.CreateRole(&models.CreateCasbinRoleRequest{Name: "ops", Rules: []models.PermissionRule{{Resource: "pods*", Verbs: []string{"*"}}}})
+	require.NoError(t, err)
+
+	before, err := svc.ListPolicies("ops")
+	require.NoError(t, err)
+
+	resp, err := svc.PreviewChanges(&models.PolicyPreviewRequest{
+		Role:      "ops",
+		Namespace: "team-a",
+		Changes: models.PolicyChangeRequest{
+			RemovePolicies: []models.CasbinPolicy{{Role: "ops", Domain: auth.AllDomains, Resource: "pods*", Verb: "*"}},
+			AddPolicies: []models.CasbinPolicy{
+				{Role: "ops", Domain: auth.AllDomains, Resource: "pods", Verb: "list"},
+				{Role: "ops", Domain: "prod/_", Resource: "nodes*", Verb: "*"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []models.RoutePermission{
+		{Method: http.MethodPost, Path: "/api/v1/nodes/:name/drain", Domain: "prod/_", Resource: "nodes/drain", Verb: "create"},
+	}, resp.Gained)
+	assert.Equal(t, []models.RoutePermission{
+		{Method: http.MethodDelete, Path: "/api/v1/namespaces/:namespace/pods/:name", Domain: "prod/team-a", Resource: "pods", Verb: "delete"},
+		{Method: http.MethodPost, Path: "/api/v1/namespaces/:namespace/pods/:name/exec", Domain: "prod/team-a", Resource: "pods/exec", Verb: "create"},
+	}, resp.Lost)
+
+	// 预览不会修改实际策略
+	after, err := svc.ListPolicies("ops")
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	_, err = svc.PreviewChanges(&models.PolicyPreviewRequest{})
+	assert.IsType(t, &ValidationError{}, err)
+}
+
+// failingAdapter 模拟存储写入失败：写入 failOn 规则时返回错误，其余操作成功
+type failingAdapter struct {
+	failOn []string
+}
+
+func (a *failingAdapter) LoadPolicy(model.Model) error { return nil }
+func (a *failingAdapter) SavePolicy(model.Model) error { return nil }
+func (a *failingAdapter) AddPolicy(_, _ string, rule []string) error {
+	if slices.Equal(rule, a.failOn) {
+		return errors.New("写入失败")
+	}
+	return nil
+}
+func (a *failingAdapter) RemovePolicy(string, string, []string) error { return nil }
+func (a *failingAdapter) RemoveFilteredPolicy(string, string, int, ...string) error {
+	return nil
+}
+
+// 中途写入失败时已生效的变更被回滚，策略集合保持原样
+func TestPolicyService_ApplyChangesRollback(t *testing.T) {
+	svc := newTestPolicyService(t)
+	_, err := svc.CreateRole(&models.CreateCasbinRoleRequest{Name: "team-editor", Template: "viewer"})
+	require.NoError(t, err)
+	_, err = svc.AssignRole(&models.RoleAssignmentRequest{Username: "alice", Role: "team-editor", Cluster: "prod", Namespace: "team-a"})
+	require.NoError(t, err)
+	policiesBefore, groupingsBefore, err := svc.loadRules()
+	require.NoError(t, err)
+
+	svc.enforcer.SetAdapter(&failingAdapter{failOn: []string{"user:bob", "team-editor", "prod/team-a"}})
+	err = svc.ApplyChanges(&models.PolicyChangeRequest{
+		RemovePolicies:  []models.CasbinPolicy{{Role: "team-editor", Domain: auth.AllDomains, Resource: "*", Verb: "get"}},
+		RemoveGroupings: []models.CasbinGrouping{{Member: "user:alice", Role: "team-editor", Domain: "prod/team-a"}},
+		AddPolicies:     []models.CasbinPolicy{{Role: "team-editor", Domain: "prod/team-a", Resource: "pods", Verb: "delete"}},
+		AddGroupings:    []models.CasbinGrouping{{Member: "user:bob", Role: "team-editor", Domain: "prod/team-a"}},
+	})
+	require.Error(t, err)
+
+	policiesAfter, groupingsAfter, err := svc.loadRules()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, policiesBefore, policiesAfter)
+	assert.ElementsMatch(t, groupingsBefore, groupingsAfter)
+}
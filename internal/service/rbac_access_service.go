@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// resolveReviewSubject 将审查主体转换为 API Server 看到的用户名和组
+func resolveReviewSubject(subject *models.AccessReviewSubject) (string, []string, error) {
+	if subject.ServiceAccount != nil {
+		if subject.User != "" {
+			return "", nil, NewValidationError("user 和 serviceAccount 不能同时指定")
+		}
+		sa := subject.ServiceAccount
+		if sa.Namespace == "" || sa.Name == "" {
+			return "", nil, NewValidationError("serviceAccount 必须指定 namespace 和 name")
+		}
+		// 与 API Server 为 ServiceAccount 令牌推导的身份保持一致
+		groups := append([]string{"system:serviceaccounts", "system:serviceaccounts:" + sa.Namespace, "system:authenticated"}, subject.Groups...)
+		return "system:serviceaccount:" + sa.Namespace + ":" + sa.Name, groups, nil
+	}
+	if subject.User == "" && len(subject.Groups) == 0 {
+		return "", nil, NewValidationError("user、groups 和 serviceAccount 至少指定一个")
+	}
+	return subject.User, subject.Groups, nil
+}
+
+// ReviewAccess 通过 SubjectAccessReview 检查主体能否执行指定操作
+func (s *RbacService) ReviewAccess(req *models.AccessReviewRequest) (*models.AccessReviewResponse, error) {
+	user, groups, err := resolveReviewSubject(&req.AccessReviewSubject)
+	if err != nil {
+		return nil, err
+	}
+	spec := authorizationv1.SubjectAccessReviewSpec{User: user, Groups: groups}
+	switch {
+	case req.NonResourcePath != "" && req.Resource != "":
+		return nil, NewValidationError("resource 和 nonResourcePath 不能同时指定")
+	case req.NonResourcePath != "":
+		spec.NonResourceAttributes = &authorizationv1.NonResourceAttributes{Path: req.NonResourcePath, Verb: req.Verb}
+	case req.Resource != "":
+		spec.ResourceAttributes = &authorizationv1.ResourceAttributes{
+			Namespace:   req.Namespace,
+			Verb:        req.Verb,
+			Group:       req.Group,
+			Resource:    req.Resource,
+			Subresource: req.Subresource,
+			Name:        req.Name,
+		}
+	default:
+		return nil, NewValidationError("必须指定 resource 或 nonResourcePath")
+	}
+
+	review, err := s.client.AuthorizationV1().SubjectAccessReviews().Create(context.TODO(),
+		&authorizationv1.SubjectAccessReview{Spec: spec}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &models.AccessReviewResponse{
+		User:            user,
+		Groups:          groups,
+		Allowed:         review.Status.Allowed,
+		Denied:          review.Status.Denied,
+		Reason:          review.Status.Reason,
+		EvaluationError: review.Status.EvaluationError,
+	}, nil
+}
+
+// ReviewRules 列出主体在命名空间中的全部权限
+// SelfSubjectRulesReview 只能查询调用者自身，因此以模拟 (impersonate) 主体身份的客户端发起请求，
+// 要求 Dashboard 使用的凭据具有 impersonate 权限
+func (s *RbacService) ReviewRules(req *models.RulesReviewRequest) (*models.RulesReviewResponse, error) {
+	user, groups, err := resolveReviewSubject(&req.AccessReviewSubject)
+	if err != nil {
+		return nil, err
+	}
+	if user == "" {
+		return nil, NewValidationError("查询权限列表需要指定 user 或 serviceAccount")
+	}
+	client, err := s.impersonatingClient(user, groups)
+	if err != nil {
+		return nil, err
+	}
+	review, err := client.AuthorizationV1().SelfSubjectRulesReviews().Create(context.TODO(),
+		&authorizationv1.SelfSubjectRulesReview{Spec: authorizationv1.SelfSubjectRulesReviewSpec{Namespace: req.Namespace}},
+		metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &models.RulesReviewResponse{
+		User:             user,
+		Groups:           groups,
+		Namespace:        req.Namespace,
+		ResourceRules:    review.Status.ResourceRules,
+		NonResourceRules: review.Status.NonResourceRules,
+		Incomplete:       review.Status.Incomplete,
+		EvaluationError:  review.Status.EvaluationError,
+	}, nil
+}
+
+// impersonatingClient 基于当前 rest.Config 构造模拟指定用户和组的客户端
+func (s *RbacService) impersonatingClient(user string, groups []string) (kubernetes.Interface, error) {
+	if s.config == nil {
+		return nil, errors.New("未配置 Kubernetes rest.Config，无法模拟用户身份")
+	}
+	config := rest.CopyConfig(s.config)
+	config.Impersonate = rest.ImpersonationConfig{UserName: user, Groups: groups}
+	return kubernetes.NewForConfig(config)
+}
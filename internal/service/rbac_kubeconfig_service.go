@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// DefaultTokenExpirationSeconds ServiceAccount 令牌的默认有效期
+	DefaultTokenExpirationSeconds int64 = 3600
+	// MinTokenExpirationSeconds TokenRequest API 允许的最短有效期
+	MinTokenExpirationSeconds int64 = 600
+
+	defaultKubeconfigClusterName = "kubernetes"
+)
+
+// GenerateServiceAccountKubeconfig 通过 TokenRequest API 为 ServiceAccount 签发绑定令牌，
+// 并生成指向当前集群 API Server 的 kubeconfig
+func (s *RbacService) GenerateServiceAccountKubeconfig(namespace, name string, req *models.ServiceAccountKubeconfigRequest) (*models.ServiceAccountKubeconfigResponse, error) {
+	if s.config == nil || s.config.Host == "" {
+		return nil, errors.New("未配置 Kubernetes rest.Config，无法确定 API Server 地址")
+	}
+	expiration := req.ExpirationSeconds
+	if expiration == 0 {
+		expiration = DefaultTokenExpirationSeconds
+	}
+	if expiration < MinTokenExpirationSeconds {
+		return nil, NewValidationError(fmt.Sprintf("expirationSeconds 不能小于 %d", MinTokenExpirationSeconds))
+	}
+	clusterName := req.ClusterName
+	if clusterName == "" {
+		clusterName = defaultKubeconfigClusterName
+	}
+
+	ctx := context.TODO()
+	if _, err := s.client.CoreV1().ServiceAccounts(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+		return nil, err
+	}
+	tokenRequest, err := s.client.CoreV1().ServiceAccounts(namespace).CreateToken(ctx, name, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences:         req.Audiences,
+			ExpirationSeconds: &expiration,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	cluster := clientcmdapi.NewCluster()
+	cluster.Server = s.config.Host
+	cluster.TLSServerName = s.config.TLSClientConfig.ServerName
+	cluster.InsecureSkipTLSVerify = s.config.TLSClientConfig.Insecure
+	if !cluster.InsecureSkipTLSVerify {
+		caData := s.config.TLSClientConfig.CAData
+		if len(caData) == 0 && s.config.TLSClientConfig.CAFile != "" {
+			if caData, err = os.ReadFile(s.config.TLSClientConfig.CAFile); err != nil {
+				return nil, fmt.Errorf("读取集群 CA 证书失败: %w", err)
+			}
+		}
+		cluster.CertificateAuthorityData = caData
+	}
+
+	userName := fmt.Sprintf("%s-%s", namespace, name)
+	contextName := fmt.Sprintf("%s@%s", userName, clusterName)
+	authInfo := clientcmdapi.NewAuthInfo()
+	authInfo.Token = tokenRequest.Status.Token
+	kubeContext := clientcmdapi.NewContext()
+	kubeContext.Cluster = clusterName
+	kubeContext.AuthInfo = userName
+	kubeContext.Namespace = namespace
+
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters[clusterName] = cluster
+	kubeconfig.AuthInfos[userName] = authInfo
+	kubeconfig.Contexts[contextName] = kubeContext
+	kubeconfig.CurrentContext = contextName
+
+	content, err := clientcmd.Write(*kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("生成 kubeconfig 失败: %w", err)
+	}
+	return &models.ServiceAccountKubeconfigResponse{
+		Namespace:      namespace,
+		ServiceAccount: name,
+		Server:         cluster.Server,
+		ExpiresAt:      tokenRequest.Status.ExpirationTimestamp.Format(time.RFC3339),
+		Kubeconfig:     string(content),
+	}, nil
+}
@@ -0,0 +1,370 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// 权限矩阵中表示 "所有命名空间" 的占位符
+const allNamespaces = "*"
+
+// rbacSnapshot 一次性加载集群中所有角色和绑定，避免逐个绑定请求 API Server
+type rbacSnapshot struct {
+	roles               map[string]*rbacv1.Role // key: namespace/name
+	clusterRoles        map[string]*rbacv1.ClusterRole
+	roleBindings        []rbacv1.RoleBinding
+	clusterRoleBindings []rbacv1.ClusterRoleBinding
+	clusterRoleRules    map[string][]rbacv1.PolicyRule // 展开聚合后的 ClusterRole 规则缓存
+}
+
+func (s *RbacService) loadSnapshot(ctx context.Context) (*rbacSnapshot, error) {
+	roleList, err := s.client.RbacV1().Roles(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	clusterRoleList, err := s.client.RbacV1().ClusterRoles().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	roleBindingList, err := s.client.RbacV1().RoleBindings(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	clusterRoleBindingList, err := s.client.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &rbacSnapshot{
+		roles:               make(map[string]*rbacv1.Role, len(roleList.Items)),
+		clusterRoles:        make(map[string]*rbacv1.ClusterRole, len(clusterRoleList.Items)),
+		roleBindings:        roleBindingList.Items,
+		clusterRoleBindings: clusterRoleBindingList.Items,
+		clusterRoleRules:    map[string][]rbacv1.PolicyRule{},
+	}
+	for i := range roleList.Items {
+		role := &roleList.Items[i]
+		snap.roles[role.Namespace+"/"+role.Name] = role
+	}
+	for i := range clusterRoleList.Items {
+		clusterRole := &clusterRoleList.Items[i]
+		snap.clusterRoles[clusterRole.Name] = clusterRole
+	}
+	return snap, nil
+}
+
+// rulesFor 返回绑定引用的角色规则，引用不存在的角色时返回空
+func (snap *rbacSnapshot) rulesFor(roleRef rbacv1.RoleRef, bindingNamespace string) []rbacv1.PolicyRule {
+	if roleRef.Kind == "Role" {
+		if role, ok := snap.roles[bindingNamespace+"/"+roleRef.Name]; ok {
+			return role.Rules
+		}
+		return nil
+	}
+	return snap.aggregatedRules(roleRef.Name, map[string]bool{})
+}
+
+// aggregatedRules 返回 ClusterRole 的规则，并递归合并 aggregationRule 选中的 ClusterRole
+// 聚合控制器通常已将规则写回 ClusterRole，这里重新计算以覆盖控制器尚未同步的情况，重复规则在构建矩阵时去重
+func (snap *rbacSnapshot) aggregatedRules(name string, visiting map[string]bool) []rbacv1.PolicyRule {
+	if rules, ok := snap.clusterRoleRules[name]; ok {
+		return rules
+	}
+	clusterRole, ok := snap.clusterRoles[name]
+	if !ok || visiting[name] {
+		return nil
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	rules := append([]rbacv1.PolicyRule{}, clusterRole.Rules...)
+	if clusterRole.AggregationRule != nil {
+		for i := range clusterRole.AggregationRule.ClusterRoleSelectors {
+			selector, err := metav1.LabelSelectorAsSelector(&clusterRole.AggregationRule.ClusterRoleSelectors[i])
+			if err != nil {
+				continue
+			}
+			for otherName, other := range snap.clusterRoles {
+				if otherName != name && selector.Matches(labels.Set(other.Labels)) {
+					rules = append(rules, snap.aggregatedRules(otherName, visiting)...)
+				}
+			}
+		}
+	}
+	snap.clusterRoleRules[name] = rules
+	return rules
+}
+
+// subjectMatcher 判断绑定中的主体是否指向被查询的主体 (直接匹配或通过所属的组)
+type subjectMatcher struct {
+	kind, name, namespace string
+	userName              string // ServiceAccount 对应的用户名
+	groups                map[string]bool
+}
+
+func newSubjectMatcher(subject *models.PermissionSubject) (*subjectMatcher, error) {
+	m := &subjectMatcher{kind: subject.Kind, name: subject.Name, namespace: subject.Namespace, groups: map[string]bool{}}
+	for _, group := range subject.Groups {
+		m.groups[group] = true
+	}
+	switch subject.Kind {
+	case rbacv1.ServiceAccountKind:
+		if subject.Namespace == "" {
+			return nil, NewValidationError("ServiceAccount 必须指定 namespace")
+		}
+		m.userName = "system:serviceaccount:" + subject.Namespace + ":" + subject.Name
+		m.groups["system:serviceaccounts"] = true
+		m.groups["system:serviceaccounts:"+subject.Namespace] = true
+		m.groups["system:authenticated"] = true
+	case rbacv1.UserKind:
+		m.userName = subject.Name
+		m.groups["system:authenticated"] = true
+	case rbacv1.GroupKind:
+		m.groups[subject.Name] = true
+	default:
+		return nil, NewValidationError("kind 必须为 User、Group 或 ServiceAccount")
+	}
+	return m, nil
+}
+
+func (m *subjectMatcher) matches(subjects []rbacv1.Subject, bindingNamespace string) bool {
+	for _, subject := range subjects {
+		switch subject.Kind {
+		case rbacv1.UserKind:
+			if m.userName != "" && subject.Name == m.userName {
+				return true
+			}
+		case rbacv1.GroupKind:
+			if m.groups[subject.Name] {
+				return true
+			}
+		case rbacv1.ServiceAccountKind:
+			namespace := subject.Namespace
+			if namespace == "" {
+				namespace = bindingNamespace
+			}
+			if m.kind == rbacv1.ServiceAccountKind && subject.Name == m.name && namespace == m.namespace {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m *subjectMatcher) sortedGroups() []string {
+	groups := make([]string, 0, len(m.groups))
+	for group := range m.groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// matrixBuilder 按 (命名空间, API 组, 资源, 资源名) 汇总动作和授权来源
+type matrixBuilder struct {
+	rows        map[string]*models.PermissionMatrixRow
+	nonResource map[string]*models.NonResourcePermission
+}
+
+func (b *matrixBuilder) add(namespace string, rules []rbacv1.PolicyRule, source string) {
+	for _, rule := range rules {
+		if len(rule.NonResourceURLs) > 0 {
+			// 非资源 URL 只在 ClusterRoleBinding 中生效
+			if namespace != allNamespaces {
+				continue
+			}
+			for _, url := range rule.NonResourceURLs {
+				entry, ok := b.nonResource[url]
+				if !ok {
+					entry = &models.NonResourcePermission{URL: url}
+					b.nonResource[url] = entry
+				}
+				entry.Verbs = appendUnique(entry.Verbs, rule.Verbs...)
+				entry.Sources = appendUnique(entry.Sources, source)
+			}
+			continue
+		}
+		resourceNames := append([]string{}, rule.ResourceNames...)
+		sort.Strings(resourceNames)
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				key := strings.Join([]string{namespace, group, resource, strings.Join(resourceNames, ",")}, "|")
+				row, ok := b.rows[key]
+				if !ok {
+					row = &models.PermissionMatrixRow{Namespace: namespace, APIGroup: group, Resource: resource}
+					if len(resourceNames) > 0 {
+						row.ResourceNames = resourceNames
+					}
+					b.rows[key] = row
+				}
+				row.Verbs = appendUnique(row.Verbs, rule.Verbs...)
+				row.Sources = appendUnique(row.Sources, source)
+			}
+		}
+	}
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		if !containsString(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// GetPermissionMatrix 解析主体在所有命名空间中的绑定，汇总为 动作 × 资源 × 命名空间 的权限矩阵
+func (s *RbacService) GetPermissionMatrix(subject *models.PermissionSubject) (*models.PermissionMatrixResponse, error) {
+	matcher, err := newSubjectMatcher(subject)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := s.loadSnapshot(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.PermissionMatrixResponse{
+		Subject:  *subject,
+		Groups:   matcher.sortedGroups(),
+		Bindings: []models.PermissionBindingRef{},
+		Rows:     []models.PermissionMatrixRow{},
+	}
+	builder := &matrixBuilder{rows: map[string]*models.PermissionMatrixRow{}, nonResource: map[string]*models.NonResourcePermission{}}
+	for _, binding := range snap.clusterRoleBindings {
+		if !matcher.matches(binding.Subjects, "") {
+			continue
+		}
+		ref := models.PermissionBindingRef{Kind: "ClusterRoleBinding", Name: binding.Name, RoleKind: binding.RoleRef.Kind, RoleName: binding.RoleRef.Name}
+		result.Bindings = append(result.Bindings, ref)
+		builder.add(allNamespaces, snap.rulesFor(binding.RoleRef, ""), "ClusterRoleBinding/"+binding.Name)
+	}
+	for _, binding := range snap.roleBindings {
+		if !matcher.matches(binding.Subjects, binding.Namespace) {
+			continue
+		}
+		ref := models.PermissionBindingRef{Kind: "RoleBinding", Name: binding.Name, Namespace: binding.Namespace, RoleKind: binding.RoleRef.Kind, RoleName: binding.RoleRef.Name}
+		result.Bindings = append(result.Bindings, ref)
+		builder.add(binding.Namespace, snap.rulesFor(binding.RoleRef, binding.Namespace), "RoleBinding/"+binding.Namespace+"/"+binding.Name)
+	}
+
+	for _, row := range builder.rows {
+		sort.Strings(row.Verbs)
+		result.Rows = append(result.Rows, *row)
+	}
+	sort.Slice(result.Rows, func(i, j int) bool {
+		a, b := result.Rows[i], result.Rows[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.APIGroup != b.APIGroup {
+			return a.APIGroup < b.APIGroup
+		}
+		if a.Resource != b.Resource {
+			return a.Resource < b.Resource
+		}
+		return strings.Join(a.ResourceNames, ",") < strings.Join(b.ResourceNames, ",")
+	})
+	for _, entry := range builder.nonResource {
+		sort.Strings(entry.Verbs)
+		result.NonResourceURLs = append(result.NonResourceURLs, *entry)
+	}
+	sort.Slice(result.NonResourceURLs, func(i, j int) bool { return result.NonResourceURLs[i].URL < result.NonResourceURLs[j].URL })
+	return result, nil
+}
+
+// WhoCan 反向查询能对资源执行指定动作的主体
+// 只根据 RBAC 绑定计算，不包含 Webhook 等其他授权模块授予的权限
+func (s *RbacService) WhoCan(req *models.WhoCanRequest) (*models.WhoCanResponse, error) {
+	snap, err := s.loadSnapshot(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	result := &models.WhoCanResponse{
+		Verb:      req.Verb,
+		Group:     req.Group,
+		Resource:  req.Resource,
+		Namespace: req.Namespace,
+		Name:      req.Name,
+		Subjects:  []models.WhoCanEntry{},
+	}
+	addSubjects := func(subjects []rbacv1.Subject, ref models.PermissionBindingRef) {
+		for _, subject := range subjects {
+			if subject.Kind == rbacv1.ServiceAccountKind && subject.Namespace == "" {
+				subject.Namespace = ref.Namespace
+			}
+			result.Subjects = append(result.Subjects, models.WhoCanEntry{Subject: subject, Binding: ref})
+		}
+	}
+
+	for _, binding := range snap.clusterRoleBindings {
+		if rulesAllow(snap.rulesFor(binding.RoleRef, ""), req) {
+			addSubjects(binding.Subjects, models.PermissionBindingRef{Kind: "ClusterRoleBinding", Name: binding.Name, RoleKind: binding.RoleRef.Kind, RoleName: binding.RoleRef.Name})
+		}
+	}
+	// RoleBinding 只在自身命名空间内生效
+	if req.Namespace != "" {
+		for _, binding := range snap.roleBindings {
+			if binding.Namespace == req.Namespace && rulesAllow(snap.rulesFor(binding.RoleRef, binding.Namespace), req) {
+				addSubjects(binding.Subjects, models.PermissionBindingRef{Kind: "RoleBinding", Name: binding.Name, Namespace: binding.Namespace, RoleKind: binding.RoleRef.Kind, RoleName: binding.RoleRef.Name})
+			}
+		}
+	}
+	return result, nil
+}
+
+// rulesAllow 与 API Server 的 RBAC 规则匹配逻辑一致：支持 "*" 通配和 "*/subresource" 形式
+func rulesAllow(rules []rbacv1.PolicyRule, req *models.WhoCanRequest) bool {
+	for _, rule := range rules {
+		if !containsOrWildcard(rule.Verbs, req.Verb) || !containsOrWildcard(rule.APIGroups, req.Group) {
+			continue
+		}
+		if !resourceMatches(rule.Resources, req.Resource) {
+			continue
+		}
+		if len(rule.ResourceNames) > 0 && (req.Name == "" || !containsString(rule.ResourceNames, req.Name)) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func containsOrWildcard(list []string, value string) bool {
+	for _, item := range list {
+		if item == rbacv1.VerbAll || item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func resourceMatches(ruleResources []string, resource string) bool {
+	for _, ruleResource := range ruleResources {
+		if ruleResource == rbacv1.ResourceAll || ruleResource == resource {
+			return true
+		}
+		// "*/scale" 匹配所有资源的 scale 子资源
+		if strings.HasPrefix(ruleResource, "*/") {
+			if idx := strings.Index(resource, "/"); idx >= 0 && resource[idx:] == ruleResource[1:] {
+				return true
+			}
+		}
+	}
+	return false
+}
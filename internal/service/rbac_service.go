@@ -2,16 +2,24 @@ package service
 
 import (
 	"context"
+	"fmt"
+
 	"github.com/ciliverse/cilikube/api/v1/models"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
 type RbacService struct {
 	client kubernetes.Interface
+	config *rest.Config // 访问审查时构造模拟用户身份的客户端
 }
 
-func NewRbacService(client kubernetes.Interface) *RbacService { return &RbacService{client: client} }
+func NewRbacService(client kubernetes.Interface, config *rest.Config) *RbacService {
+	return &RbacService{client: client, config: config}
+}
 
 // Roles
 func (s *RbacService) ListRoles(namespace string) ([]*models.RoleResponse, error) {
@@ -118,3 +126,294 @@ func (s *RbacService) GetServiceAccounts(namespace string, name string) (*models
 	}
 	return models.ToServiceAccountsResponse(serviceAccount), nil
 }
+
+// CreateRole 在指定命名空间创建 Role
+func (s *RbacService) CreateRole(namespace string, req *models.CreateRoleRequest) (*models.RoleResponse, error) {
+	if err := checkRequestNamespace(namespace, req.Namespace); err != nil {
+		return nil, err
+	}
+	if err := validatePolicyRules(req.Rules, false); err != nil {
+		return nil, err
+	}
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: req.Name, Namespace: namespace, Labels: req.Labels, Annotations: req.Annotations},
+		Rules:      req.Rules,
+	}
+	created, err := s.client.RbacV1().Roles(namespace).Create(context.TODO(), role, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return models.ToRoleResponse(created), nil
+}
+
+// UpdateRole 更新 Role 的规则、标签和注解
+func (s *RbacService) UpdateRole(namespace, name string, req *models.UpdateRoleRequest) (*models.RoleResponse, error) {
+	if err := validatePolicyRules(req.Rules, false); err != nil {
+		return nil, err
+	}
+	role, err := s.client.RbacV1().Roles(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	role.Rules = req.Rules
+	role.Labels = req.Labels
+	role.Annotations = req.Annotations
+	updated, err := s.client.RbacV1().Roles(namespace).Update(context.TODO(), role, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return models.ToRoleResponse(updated), nil
+}
+
+func (s *RbacService) DeleteRole(namespace, name string) error {
+	return s.client.RbacV1().Roles(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+}
+
+// CreateRoleBinding 在指定命名空间创建 RoleBinding
+func (s *RbacService) CreateRoleBinding(namespace string, req *models.CreateRoleBindingRequest) (*models.RoleBindingResponse, error) {
+	if err := checkRequestNamespace(namespace, req.Namespace); err != nil {
+		return nil, err
+	}
+	roleRef, subjects, err := normalizeBinding(req.RoleRef, req.Subjects, namespace)
+	if err != nil {
+		return nil, err
+	}
+	roleBinding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: req.Name, Namespace: namespace, Labels: req.Labels, Annotations: req.Annotations},
+		RoleRef:    roleRef,
+		Subjects:   subjects,
+	}
+	created, err := s.client.RbacV1().RoleBindings(namespace).Create(context.TODO(), roleBinding, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return models.ToRoleBindingResponse(created), nil
+}
+
+// UpdateRoleBinding 更新 RoleBinding 的主体、标签和注解，roleRef 创建后不可修改
+func (s *RbacService) UpdateRoleBinding(namespace, name string, req *models.UpdateRoleBindingRequest) (*models.RoleBindingResponse, error) {
+	roleRef, subjects, err := normalizeBinding(req.RoleRef, req.Subjects, namespace)
+	if err != nil {
+		return nil, err
+	}
+	roleBinding, err := s.client.RbacV1().RoleBindings(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if roleBinding.RoleRef != roleRef {
+		return nil, NewValidationError("roleRef 创建后不可修改，请删除后重新创建")
+	}
+	roleBinding.Subjects = subjects
+	roleBinding.Labels = req.Labels
+	roleBinding.Annotations = req.Annotations
+	updated, err := s.client.RbacV1().RoleBindings(namespace).Update(context.TODO(), roleBinding, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return models.ToRoleBindingResponse(updated), nil
+}
+
+func (s *RbacService) DeleteRoleBinding(namespace, name string) error {
+	return s.client.RbacV1().RoleBindings(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+}
+
+// CreateClusterRole 创建 ClusterRole
+func (s *RbacService) CreateClusterRole(req *models.CreateClusterRoleRequest) (*models.ClusterRoleResponse, error) {
+	if err := validatePolicyRules(req.Rules, true); err != nil {
+		return nil, err
+	}
+	clusterRole := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: req.Name, Labels: req.Labels, Annotations: req.Annotations},
+		Rules:      req.Rules,
+	}
+	created, err := s.client.RbacV1().ClusterRoles().Create(context.TODO(), clusterRole, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return models.ToClusterRoleResponse(created), nil
+}
+
+// UpdateClusterRole 更新 ClusterRole 的规则、标签和注解
+// 聚合 ClusterRole 的规则由控制器维护，这里保留其 aggregationRule 不变
+func (s *RbacService) UpdateClusterRole(name string, req *models.UpdateClusterRoleRequest) (*models.ClusterRoleResponse, error) {
+	if err := validatePolicyRules(req.Rules, true); err != nil {
+		return nil, err
+	}
+	clusterRole, err := s.client.RbacV1().ClusterRoles().Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	clusterRole.Rules = req.Rules
+	clusterRole.Labels = req.Labels
+	clusterRole.Annotations = req.Annotations
+	updated, err := s.client.RbacV1().ClusterRoles().Update(context.TODO(), clusterRole, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return models.ToClusterRoleResponse(updated), nil
+}
+
+func (s *RbacService) DeleteClusterRole(name string) error {
+	return s.client.RbacV1().ClusterRoles().Delete(context.TODO(), name, metav1.DeleteOptions{})
+}
+
+// CreateClusterRoleBinding 创建 ClusterRoleBinding，只能引用 ClusterRole
+func (s *RbacService) CreateClusterRoleBinding(req *models.CreateClusterRoleBindingRequest) (*models.ClusterRoleBindingsResponse, error) {
+	roleRef, subjects, err := normalizeBinding(req.RoleRef, req.Subjects, "")
+	if err != nil {
+		return nil, err
+	}
+	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: req.Name, Labels: req.Labels, Annotations: req.Annotations},
+		RoleRef:    roleRef,
+		Subjects:   subjects,
+	}
+	created, err := s.client.RbacV1().ClusterRoleBindings().Create(context.TODO(), clusterRoleBinding, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return models.ToClusterRoleBindingsResponse(created), nil
+}
+
+// UpdateClusterRoleBinding 更新 ClusterRoleBinding 的主体、标签和注解，roleRef 创建后不可修改
+func (s *RbacService) UpdateClusterRoleBinding(name string, req *models.UpdateClusterRoleBindingRequest) (*models.ClusterRoleBindingsResponse, error) {
+	roleRef, subjects, err := normalizeBinding(req.RoleRef, req.Subjects, "")
+	if err != nil {
+		return nil, err
+	}
+	clusterRoleBinding, err := s.client.RbacV1().ClusterRoleBindings().Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if clusterRoleBinding.RoleRef != roleRef {
+		return nil, NewValidationError("roleRef 创建后不可修改，请删除后重新创建")
+	}
+	clusterRoleBinding.Subjects = subjects
+	clusterRoleBinding.Labels = req.Labels
+	clusterRoleBinding.Annotations = req.Annotations
+	updated, err := s.client.RbacV1().ClusterRoleBindings().Update(context.TODO(), clusterRoleBinding, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return models.ToClusterRoleBindingsResponse(updated), nil
+}
+
+func (s *RbacService) DeleteClusterRoleBinding(name string) error {
+	return s.client.RbacV1().ClusterRoleBindings().Delete(context.TODO(), name, metav1.DeleteOptions{})
+}
+
+// CreateServiceAccount 在指定命名空间创建 ServiceAccount
+func (s *RbacService) CreateServiceAccount(namespace string, req *models.CreateServiceAccountRequest) (*models.ServiceAccountsResponse, error) {
+	if err := checkRequestNamespace(namespace, req.Namespace); err != nil {
+		return nil, err
+	}
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: req.Name, Namespace: namespace, Labels: req.Labels, Annotations: req.Annotations},
+	}
+	created, err := s.client.CoreV1().ServiceAccounts(namespace).Create(context.TODO(), serviceAccount, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return models.ToServiceAccountsResponse(created), nil
+}
+
+// UpdateServiceAccount 更新 ServiceAccount 的标签和注解
+func (s *RbacService) UpdateServiceAccount(namespace, name string, req *models.UpdateServiceAccountRequest) (*models.ServiceAccountsResponse, error) {
+	serviceAccount, err := s.client.CoreV1().ServiceAccounts(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	serviceAccount.Labels = req.Labels
+	serviceAccount.Annotations = req.Annotations
+	updated, err := s.client.CoreV1().ServiceAccounts(namespace).Update(context.TODO(), serviceAccount, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return models.ToServiceAccountsResponse(updated), nil
+}
+
+func (s *RbacService) DeleteServiceAccount(namespace, name string) error {
+	return s.client.CoreV1().ServiceAccounts(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+}
+
+// checkRequestNamespace 请求体中的 namespace 可省略，填写时必须与路径参数一致
+func checkRequestNamespace(pathNamespace, bodyNamespace string) error {
+	if bodyNamespace != "" && bodyNamespace != pathNamespace {
+		return NewValidationError("请求体中的 namespace 与路径参数不一致")
+	}
+	return nil
+}
+
+// validatePolicyRules 校验规则：必须包含 verbs；nonResourceURLs 只能用于 ClusterRole，且不能与 resources 混用
+func validatePolicyRules(rules []rbacv1.PolicyRule, clusterScoped bool) error {
+	for i, rule := range rules {
+		if len(rule.Verbs) == 0 {
+			return NewValidationError(fmt.Sprintf("rules[%d]: verbs 不能为空", i))
+		}
+		if len(rule.NonResourceURLs) > 0 {
+			if !clusterScoped {
+				return NewValidationError(fmt.Sprintf("rules[%d]: nonResourceURLs 只能用于 ClusterRole", i))
+			}
+			if len(rule.Resources) > 0 || len(rule.APIGroups) > 0 || len(rule.ResourceNames) > 0 {
+				return NewValidationError(fmt.Sprintf("rules[%d]: nonResourceURLs 不能与 apiGroups/resources/resourceNames 同时使用", i))
+			}
+			continue
+		}
+		if len(rule.Resources) == 0 {
+			return NewValidationError(fmt.Sprintf("rules[%d]: resources 不能为空", i))
+		}
+		if len(rule.APIGroups) == 0 {
+			return NewValidationError(fmt.Sprintf("rules[%d]: apiGroups 不能为空 (核心 API 组使用 \"\")", i))
+		}
+	}
+	return nil
+}
+
+// normalizeBinding 校验并补全绑定的 roleRef 和 subjects
+// bindingNamespace 为空表示 ClusterRoleBinding：只能引用 ClusterRole，ServiceAccount 主体必须显式指定命名空间
+func normalizeBinding(roleRef rbacv1.RoleRef, subjects []rbacv1.Subject, bindingNamespace string) (rbacv1.RoleRef, []rbacv1.Subject, error) {
+	if roleRef.APIGroup == "" {
+		roleRef.APIGroup = rbacv1.GroupName
+	}
+	if roleRef.APIGroup != rbacv1.GroupName {
+		return roleRef, nil, NewValidationError("roleRef.apiGroup 必须为 " + rbacv1.GroupName)
+	}
+	switch roleRef.Kind {
+	case "ClusterRole":
+	case "Role":
+		if bindingNamespace == "" {
+			return roleRef, nil, NewValidationError("ClusterRoleBinding 只能引用 ClusterRole")
+		}
+	default:
+		return roleRef, nil, NewValidationError("roleRef.kind 必须为 Role 或 ClusterRole")
+	}
+	if roleRef.Name == "" {
+		return roleRef, nil, NewValidationError("roleRef.name 不能为空")
+	}
+
+	normalized := make([]rbacv1.Subject, 0, len(subjects))
+	for i, subject := range subjects {
+		if subject.Name == "" {
+			return roleRef, nil, NewValidationError(fmt.Sprintf("subjects[%d]: name 不能为空", i))
+		}
+		switch subject.Kind {
+		case rbacv1.UserKind, rbacv1.GroupKind:
+			if subject.APIGroup == "" {
+				subject.APIGroup = rbacv1.GroupName
+			}
+			subject.Namespace = ""
+		case rbacv1.ServiceAccountKind:
+			subject.APIGroup = ""
+			if subject.Namespace == "" {
+				subject.Namespace = bindingNamespace
+			}
+			if subject.Namespace == "" {
+				return roleRef, nil, NewValidationError(fmt.Sprintf("subjects[%d]: ServiceAccount 必须指定 namespace", i))
+			}
+		default:
+			return roleRef, nil, NewValidationError(fmt.Sprintf("subjects[%d]: kind 必须为 User、Group 或 ServiceAccount", i))
+		}
+		normalized = append(normalized, subject)
+	}
+	return roleRef, normalized, nil
+}
@@ -4,11 +4,18 @@ import (
 	"context"
 	"testing"
 
+	"github.com/ciliverse/cilikube/api/v1/models"
 	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 // 使用 fake 客户端的测试示例
@@ -36,7 +43,7 @@ func TestRbacService_ListRoles(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 创建服务
-	service := NewRbacService(fakeClient)
+	service := NewRbacService(fakeClient, nil)
 
 	// 测试 ListRoles
 	roles, err := service.ListRoles("default")
@@ -71,7 +78,7 @@ func TestRbacService_GetRole(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 创建服务
-	service := NewRbacService(fakeClient)
+	service := NewRbacService(fakeClient, nil)
 
 	// 测试 GetRole
 	role, err := service.GetRole("default", "test-role")
@@ -110,7 +117,7 @@ func TestRbacService_ListRoleBindings(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 创建服务
-	service := NewRbacService(fakeClient)
+	service := NewRbacService(fakeClient, nil)
 
 	// 测试 ListRoleBindings
 	roleBindings, err := service.ListRoleBindings("default")
@@ -151,7 +158,7 @@ func TestRbacService_GetRoleBinding(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 创建服务
-	service := NewRbacService(fakeClient)
+	service := NewRbacService(fakeClient, nil)
 
 	// 测试 GetRoleBinding
 	roleBinding, err := service.GetRoleBinding("default", "test-rolebinding")
@@ -185,7 +192,7 @@ func TestRbacService_ListClusterRoles(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 创建服务
-	service := NewRbacService(fakeClient)
+	service := NewRbacService(fakeClient, nil)
 
 	// 测试 ListClusterRoles
 	clusterRoles, err := service.ListClusterRoles()
@@ -218,7 +225,7 @@ func TestRbacService_GetClusterRole(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 创建服务
-	service := NewRbacService(fakeClient)
+	service := NewRbacService(fakeClient, nil)
 
 	// 测试 GetClusterRole
 	clusterRole, err := service.GetClusterRole("test-clusterrole")
@@ -256,7 +263,7 @@ func TestRbacService_ListClusterRoleBindings(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 创建服务
-	service := NewRbacService(fakeClient)
+	service := NewRbacService(fakeClient, nil)
 
 	// 测试 ListClusterRoleBindings
 	clusterRoleBindings, err := service.ListClusterRoleBindings()
@@ -295,7 +302,7 @@ func TestRbacService_GetClusterRoleBinding(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 创建服务
-	service := NewRbacService(fakeClient)
+	service := NewRbacService(fakeClient, nil)
 
 	// 测试 GetClusterRoleBinding
 	clusterRoleBinding, err := service.GetClusterRoleBinding("test-clusterrolebinding")
@@ -323,7 +330,7 @@ func TestRbacService_ListServiceAccounts(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 创建服务
-	service := NewRbacService(fakeClient)
+	service := NewRbacService(fakeClient, nil)
 
 	// 测试 ListServiceAccounts
 	serviceAccounts, err := service.ListServiceAccounts("default")
@@ -351,7 +358,7 @@ func TestRbacService_GetServiceAccounts(t *testing.T) {
 	assert.NoError(t, err)
 
 	// 创建服务
-	service := NewRbacService(fakeClient)
+	service := NewRbacService(fakeClient, nil)
 
 	// 测试 GetServiceAccounts
 	serviceAccount, err := service.GetServiceAccounts("default", "test-sa")
@@ -360,3 +367,166 @@ func TestRbacService_GetServiceAccounts(t *testing.T) {
 	assert.Equal(t, "test-sa", serviceAccount.Name)
 	assert.Equal(t, "default", serviceAccount.Namespace)
 }
+
+// 测试 CreateRoleBinding 方法：补全 ServiceAccount 主体的命名空间，并拒绝修改 roleRef
+func TestRbacService_CreateRoleBinding(t *testing.T) {
+	// 创建一个假的 Kubernetes 客户端
+	fakeClient := fake.NewSimpleClientset()
+
+	// 创建服务
+	service := NewRbacService(fakeClient, nil)
+
+	roleRef := rbacv1.RoleRef{Kind: "Role", Name: "pod-reader"}
+	roleBinding, err := service.CreateRoleBinding("default", &models.CreateRoleBindingRequest{
+		Name:     "read-pods",
+		RoleRef:  roleRef,
+		Subjects: []rbacv1.Subject{{Kind: "ServiceAccount", Name: "ci"}, {Kind: "User", Name: "alice"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, rbacv1.GroupName, roleBinding.RoleRef.APIGroup)
+	assert.Equal(t, "default", roleBinding.Subjects[0].Namespace)
+	assert.Equal(t, rbacv1.GroupName, roleBinding.Subjects[1].APIGroup)
+
+	// roleRef 创建后不可修改
+	_, err = service.UpdateRoleBinding("default", "read-pods", &models.UpdateRoleBindingRequest{
+		RoleRef:  rbacv1.RoleRef{Kind: "ClusterRole", Name: "admin"},
+		Subjects: []rbacv1.Subject{{Kind: "User", Name: "alice"}},
+	})
+	_, ok := err.(*ValidationError)
+	assert.True(t, ok)
+
+	// ClusterRoleBinding 不能引用 Role
+	_, err = service.CreateClusterRoleBinding(&models.CreateClusterRoleBindingRequest{
+		Name:     "bad",
+		RoleRef:  roleRef,
+		Subjects: []rbacv1.Subject{{Kind: "User", Name: "alice"}},
+	})
+	_, ok = err.(*ValidationError)
+	assert.True(t, ok)
+}
+
+// 测试 ReviewAccess 方法：ServiceAccount 主体转换为对应的用户名和组
+func TestRbacService_ReviewAccess(t *testing.T) {
+	// 创建一个假的 Kubernetes 客户端，模拟授权结果
+	fakeClient := fake.NewSimpleClientset()
+	var reviewed *authorizationv1.SubjectAccessReview
+	fakeClient.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		reviewed = action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		result := reviewed.DeepCopy()
+		result.Status.Allowed = reviewed.Spec.ResourceAttributes.Verb == "get"
+		return true, result, nil
+	})
+
+	// 创建服务
+	service := NewRbacService(fakeClient, nil)
+
+	result, err := service.ReviewAccess(&models.AccessReviewRequest{
+		AccessReviewSubject: models.AccessReviewSubject{ServiceAccount: &models.ServiceAccountRef{Namespace: "ci", Name: "deployer"}},
+		Verb:                "get",
+		Namespace:           "default",
+		Resource:            "pods",
+	})
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, "system:serviceaccount:ci:deployer", reviewed.Spec.User)
+	assert.Contains(t, reviewed.Spec.Groups, "system:serviceaccounts:ci")
+
+	_, err = service.ReviewAccess(&models.AccessReviewRequest{Verb: "get", Resource: "pods"})
+	_, ok := err.(*ValidationError)
+	assert.True(t, ok)
+}
+
+// 测试 GenerateServiceAccountKubeconfig 方法：通过 TokenRequest 签发令牌并写入 kubeconfig
+func TestRbacService_GenerateServiceAccountKubeconfig(t *testing.T) {
+	// 创建一个假的 Kubernetes 客户端，模拟 TokenRequest API
+	fakeClient := fake.NewSimpleClientset(&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "ci", Namespace: "default"}})
+	var requested *authenticationv1.TokenRequest
+	fakeClient.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		requested = action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenRequest)
+		result := requested.DeepCopy()
+		result.Status.Token = "issued-token"
+		result.Status.ExpirationTimestamp = metav1.Now()
+		return true, result, nil
+	})
+
+	// 创建服务
+	config := &rest.Config{Host: "https://10.0.0.1:6443", TLSClientConfig: rest.TLSClientConfig{CAData: []byte("ca-data")}}
+	service := NewRbacService(fakeClient, config)
+
+	result, err := service.GenerateServiceAccountKubeconfig("default", "ci", &models.ServiceAccountKubeconfigRequest{Audiences: []string{"ci"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ci"}, requested.Spec.Audiences)
+	assert.Equal(t, DefaultTokenExpirationSeconds, *requested.Spec.ExpirationSeconds)
+
+	kubeconfig, err := clientcmd.Load([]byte(result.Kubeconfig))
+	assert.NoError(t, err)
+	current := kubeconfig.Contexts[kubeconfig.CurrentContext]
+	assert.Equal(t, "default", current.Namespace)
+	assert.Equal(t, "https://10.0.0.1:6443", kubeconfig.Clusters[current.Cluster].Server)
+	assert.Equal(t, []byte("ca-data"), kubeconfig.Clusters[current.Cluster].CertificateAuthorityData)
+	assert.Equal(t, "issued-token", kubeconfig.AuthInfos[current.AuthInfo].Token)
+
+	// 有效期过短
+	_, err = service.GenerateServiceAccountKubeconfig("default", "ci", &models.ServiceAccountKubeconfigRequest{ExpirationSeconds: 60})
+	_, ok := err.(*ValidationError)
+	assert.True(t, ok)
+}
+
+// 测试 GetPermissionMatrix 和 WhoCan 方法：展开聚合 ClusterRole，并按绑定范围计算命名空间
+func TestRbacService_PermissionMatrix(t *testing.T) {
+	// 创建一个假的 Kubernetes 客户端：aggregate-view 聚合了带 rbac.example.com/aggregate-to-view 标签的 ClusterRole
+	fakeClient := fake.NewSimpleClientset(
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "aggregate-view"},
+			AggregationRule: &rbacv1.AggregationRule{ClusterRoleSelectors: []metav1.LabelSelector{
+				{MatchLabels: map[string]string{"rbac.example.com/aggregate-to-view": "true"}},
+			}},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-viewer", Labels: map[string]string{"rbac.example.com/aggregate-to-view": "true"}},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods", "pods/log"}, Verbs: []string{"get", "list"}}},
+		},
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "deployer", Namespace: "apps"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"*"}}},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "ci-view"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "aggregate-view"},
+			Subjects:   []rbacv1.Subject{{Kind: "Group", Name: "system:serviceaccounts:ci"}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "ci-deploy", Namespace: "apps"},
+			RoleRef:    rbacv1.RoleRef{Kind: "Role", Name: "deployer"},
+			Subjects:   []rbacv1.Subject{{Kind: "ServiceAccount", Name: "deployer", Namespace: "ci"}},
+		},
+	)
+
+	// 创建服务
+	service := NewRbacService(fakeClient, nil)
+
+	matrix, err := service.GetPermissionMatrix(&models.PermissionSubject{Kind: "ServiceAccount", Name: "deployer", Namespace: "ci"})
+	assert.NoError(t, err)
+	assert.Len(t, matrix.Bindings, 2)
+	assert.Len(t, matrix.Rows, 3)
+	assert.Equal(t, "*", matrix.Rows[0].Namespace)
+	assert.Equal(t, "pods", matrix.Rows[0].Resource)
+	assert.Equal(t, []string{"get", "list"}, matrix.Rows[0].Verbs)
+	assert.Equal(t, "apps", matrix.Rows[2].Namespace)
+	assert.Equal(t, []string{"*"}, matrix.Rows[2].Verbs)
+
+	// 在 apps 命名空间删除 Deployment：只有 RoleBinding 授权
+	whoCan, err := service.WhoCan(&models.WhoCanRequest{Verb: "delete", Group: "apps", Resource: "deployments", Namespace: "apps"})
+	assert.NoError(t, err)
+	assert.Len(t, whoCan.Subjects, 1)
+	assert.Equal(t, "deployer", whoCan.Subjects[0].Subject.Name)
+
+	// 集群级请求不受 RoleBinding 影响
+	whoCan, err = service.WhoCan(&models.WhoCanRequest{Verb: "get", Resource: "pods/log"})
+	assert.NoError(t, err)
+	assert.Len(t, whoCan.Subjects, 1)
+	assert.Equal(t, "ClusterRoleBinding", whoCan.Subjects[0].Binding.Kind)
+}
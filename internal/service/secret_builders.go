@@ -0,0 +1,100 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// dockerConfigJSON .dockerconfigjson 的内容格式，与 kubectl create secret docker-registry 生成的一致
+type dockerConfigJSON struct {
+	Auths map[string]dockerConfigEntry `json:"auths"`
+}
+
+type dockerConfigEntry struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email,omitempty"`
+	Auth     string `json:"auth"`
+}
+
+// CreateDockerConfigSecret 根据镜像仓库地址和凭据创建 kubernetes.io/dockerconfigjson 类型的 Secret
+func (s *SecretService) CreateDockerConfigSecret(namespace string, req *models.DockerConfigSecretRequest) (*corev1.Secret, error) {
+	server := strings.TrimSpace(req.Server)
+	if server == "" || strings.ContainsAny(server, " \t\n") {
+		return nil, NewValidationError("无效的镜像仓库地址")
+	}
+	config, err := json.Marshal(dockerConfigJSON{Auths: map[string]dockerConfigEntry{
+		server: {
+			Username: req.Username,
+			Password: req.Password,
+			Email:    req.Email,
+			Auth:     base64.StdEncoding.EncodeToString([]byte(req.Username + ":" + req.Password)),
+		},
+	}})
+	if err != nil {
+		return nil, err
+	}
+	return s.Create(namespace, newTypedSecret(req.Name, req.Labels, corev1.SecretTypeDockerConfigJson, map[string][]byte{
+		corev1.DockerConfigJsonKey: config,
+	}))
+}
+
+// CreateTLSSecret 校验证书和私钥后创建 kubernetes.io/tls 类型的 Secret，返回解析出的证书链信息。
+// 证书必须能解析且叶子证书 (第一个) 与私钥匹配；已过期的证书允许保存，由调用方根据 Expired 提示
+func (s *SecretService) CreateTLSSecret(namespace string, req *models.TLSSecretRequest) (*corev1.Secret, []models.CertificateInfo, error) {
+	certs, err := parseCertificateChain([]byte(req.Cert))
+	if err != nil {
+		return nil, nil, NewValidationError("证书无效: " + err.Error())
+	}
+	key, err := parsePrivateKey([]byte(req.Key))
+	if err != nil {
+		return nil, nil, NewValidationError("私钥无效: " + err.Error())
+	}
+	if !certificateMatchesKey(certs[0], key) {
+		return nil, nil, NewValidationError("私钥与证书不匹配")
+	}
+	data := map[string][]byte{
+		corev1.TLSCertKey:       []byte(req.Cert),
+		corev1.TLSPrivateKeyKey: []byte(req.Key),
+	}
+	if strings.TrimSpace(req.CA) != "" {
+		if _, err := parseCertificateChain([]byte(req.CA)); err != nil {
+			return nil, nil, NewValidationError("CA 证书无效: " + err.Error())
+		}
+		data[corev1.ServiceAccountRootCAKey] = []byte(req.CA)
+	}
+
+	secret, err := s.Create(namespace, newTypedSecret(req.Name, req.Labels, corev1.SecretTypeTLS, data))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	infos := make([]models.CertificateInfo, 0, len(certs))
+	for _, cert := range certs {
+		infos = append(infos, certificateInfo(cert, now))
+	}
+	return secret, infos, nil
+}
+
+// CreateBasicAuthSecret 创建 kubernetes.io/basic-auth 类型的 Secret
+func (s *SecretService) CreateBasicAuthSecret(namespace string, req *models.BasicAuthSecretRequest) (*corev1.Secret, error) {
+	return s.Create(namespace, newTypedSecret(req.Name, req.Labels, corev1.SecretTypeBasicAuth, map[string][]byte{
+		corev1.BasicAuthUsernameKey: []byte(req.Username),
+		corev1.BasicAuthPasswordKey: []byte(req.Password),
+	}))
+}
+
+func newTypedSecret(name string, labels map[string]string, secretType corev1.SecretType, data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Type:       secretType,
+		Data:       data,
+	}
+}
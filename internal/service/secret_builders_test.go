@@ -0,0 +1,103 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newTestCertificate 生成自签名证书和 PKCS#8 私钥，均为 PEM 格式
+func newTestCertificate(t *testing.T, commonName string, notAfter time.Time, dnsNames ...string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		IPAddresses:  []net.IP{net.ParseIP("10.0.0.1")},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestSecretService_CreateTLSSecret(t *testing.T) {
+	svc := NewSecretService(fake.NewSimpleClientset())
+	notAfter := time.Now().Add(30*24*time.Hour + time.Hour)
+	cert, key := newTestCertificate(t, "web.example.com", notAfter, "web.example.com", "www.example.com")
+
+	secret, certs, err := svc.CreateTLSSecret("default", &models.TLSSecretRequest{Name: "web-tls", Cert: cert, Key: key})
+	require.NoError(t, err)
+	assert.Equal(t, corev1.SecretTypeTLS, secret.Type)
+	assert.Equal(t, []byte(cert), secret.Data[corev1.TLSCertKey])
+	assert.Equal(t, []byte(key), secret.Data[corev1.TLSPrivateKeyKey])
+	require.Len(t, certs, 1)
+	assert.Equal(t, "CN=web.example.com", certs[0].Subject)
+	assert.Equal(t, []string{"web.example.com", "www.example.com"}, certs[0].DNSNames)
+	assert.Equal(t, []string{"10.0.0.1"}, certs[0].IPAddresses)
+	assert.Equal(t, 30, certs[0].DaysRemaining)
+	assert.False(t, certs[0].Expired)
+
+	// 私钥与证书不匹配、PEM 无效时拒绝创建
+	_, otherKey := newTestCertificate(t, "other", notAfter)
+	_, _, err = svc.CreateTLSSecret("default", &models.TLSSecretRequest{Name: "mismatch", Cert: cert, Key: otherKey})
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Contains(t, err.Error(), "不匹配")
+	_, _, err = svc.CreateTLSSecret("default", &models.TLSSecretRequest{Name: "garbage", Cert: "not a pem", Key: key})
+	assert.ErrorAs(t, err, &validationErr)
+	_, _, err = svc.CreateTLSSecret("default", &models.TLSSecretRequest{Name: "bad-ca", Cert: cert, Key: key, CA: "nope"})
+	assert.ErrorAs(t, err, &validationErr)
+
+	// 过期证书允许保存，但标记为已过期
+	expiredCert, expiredKey := newTestCertificate(t, "old.example.com", time.Now().Add(-48*time.Hour))
+	_, certs, err = svc.CreateTLSSecret("default", &models.TLSSecretRequest{Name: "old-tls", Cert: expiredCert, Key: expiredKey, CA: expiredCert})
+	require.NoError(t, err)
+	assert.True(t, certs[0].Expired)
+	assert.Equal(t, -2, certs[0].DaysRemaining)
+}
+
+func TestSecretService_CreateDockerConfigAndBasicAuthSecrets(t *testing.T) {
+	svc := NewSecretService(fake.NewSimpleClientset())
+
+	secret, err := svc.CreateDockerConfigSecret("default", &models.DockerConfigSecretRequest{
+		Name: "registry", Server: "registry.example.com", Username: "bot", Password: "pa:ss", Labels: map[string]string{"team": "a"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, corev1.SecretTypeDockerConfigJson, secret.Type)
+	assert.Equal(t, "a", secret.Labels["team"])
+	var config dockerConfigJSON
+	require.NoError(t, json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config))
+	entry := config.Auths["registry.example.com"]
+	assert.Equal(t, "bot", entry.Username)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("bot:pa:ss")), entry.Auth)
+
+	_, err = svc.CreateDockerConfigSecret("default", &models.DockerConfigSecretRequest{Name: "bad", Server: "a b", Username: "u", Password: "p"})
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+
+	secret, err = svc.CreateBasicAuthSecret("default", &models.BasicAuthSecretRequest{Name: "git", Username: "git", Password: "token"})
+	require.NoError(t, err)
+	assert.Equal(t, corev1.SecretTypeBasicAuth, secret.Type)
+	assert.Equal(t, map[string][]byte{"username": []byte("git"), "password": []byte("token")}, secret.Data)
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/yaml"
+)
+
+// MaxSecretSize Kubernetes 限制单个 Secret 的数据总量为 1MiB
+const MaxSecretSize = 1 << 20
+
+var (
+	ErrSecretKeyNotFound = errors.New("Secret 中不存在该键")
+	ErrSecretKeyExists   = errors.New("Secret 中已存在同名的键")
+)
+
+// RevealKey 返回单个键解码后的值
+func (s *SecretService) RevealKey(namespace, name, key string) ([]byte, error) {
+	secret, err := s.Get(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return nil, ErrSecretKeyNotFound
+	}
+	return value, nil
+}
+
+// GetYAML 返回值被遮盖的 Secret YAML，去掉 managedFields 等内部字段，last-applied-configuration 注解同样遮盖
+func (s *SecretService) GetYAML(namespace, name string) ([]byte, error) {
+	secret, err := s.Get(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	secret = secret.DeepCopy()
+	secret.ManagedFields = nil
+	secret.Annotations = models.MaskSecretAnnotations(secret.Annotations)
+	if secret.APIVersion == "" {
+		secret.APIVersion = "v1"
+		secret.Kind = "Secret"
+	}
+	masked := models.MaskSecretValues(secret.Data)
+	secret.Data = nil
+	secret.StringData = nil
+
+	// data 的值在 corev1.Secret 中是字节，先转成通用结构再填入占位符
+	raw, err := json.Marshal(secret)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return nil, err
+	}
+	if len(masked) > 0 {
+		object["data"] = masked
+	}
+	return yaml.Marshal(object)
+}
+
+// SetKey 新增或替换单个键的值，其余键保持不变
+func (s *SecretService) SetKey(namespace, name, key string, value []byte) (*corev1.Secret, error) {
+	if err := validateSecretKey(key); err != nil {
+		return nil, err
+	}
+	return s.updateData(namespace, name, func(data map[string][]byte) error {
+		data[key] = value
+		return nil
+	})
+}
+
+// RenameKey 重命名单个键，值保持不变；新键已存在且不允许覆盖时返回 ErrSecretKeyExists
+func (s *SecretService) RenameKey(namespace, name, key, newKey string, overwrite bool) (*corev1.Secret, error) {
+	if err := validateSecretKey(newKey); err != nil {
+		return nil, err
+	}
+	if key == newKey {
+		return nil, NewValidationError("新键名与原键名相同")
+	}
+	return s.updateData(namespace, name, func(data map[string][]byte) error {
+		value, ok := data[key]
+		if !ok {
+			return ErrSecretKeyNotFound
+		}
+		if _, exists := data[newKey]; exists && !overwrite {
+			return ErrSecretKeyExists
+		}
+		data[newKey] = value
+		delete(data, key)
+		return nil
+	})
+}
+
+// RemoveKey 删除单个键
+func (s *SecretService) RemoveKey(namespace, name, key string) (*corev1.Secret, error) {
+	return s.updateData(namespace, name, func(data map[string][]byte) error {
+		if _, ok := data[key]; !ok {
+			return ErrSecretKeyNotFound
+		}
+		delete(data, key)
+		return nil
+	})
+}
+
+// updateData 读取最新的 Secret，修改 data 后携带 resourceVersion 更新，冲突时重试，
+// 避免客户端回传整个 Secret 时覆盖其他人同时做的修改
+func (s *SecretService) updateData(namespace, name string, mutate func(data map[string][]byte) error) (*corev1.Secret, error) {
+	var updated *corev1.Secret
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := s.Get(namespace, name)
+		if err != nil {
+			return err
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		if err := mutate(secret.Data); err != nil {
+			return err
+		}
+		if size := secretDataSize(secret.Data); size > MaxSecretSize {
+			return NewValidationError(fmt.Sprintf("Secret 数据总大小 %d 字节超过上限 %d 字节", size, MaxSecretSize))
+		}
+		updated, err = s.client.CoreV1().Secrets(namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+		return err
+	})
+	return updated, err
+}
+
+func validateSecretKey(key string) error {
+	if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+		return NewValidationError(fmt.Sprintf("无效的键名 %q: %s", key, strings.Join(errs, "; ")))
+	}
+	return nil
+}
+
+func secretDataSize(data map[string][]byte) int {
+	size := 0
+	for key, value := range data {
+		size += len(key) + len(value)
+	}
+	return size
+}
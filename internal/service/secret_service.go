@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 
+	"github.com/ciliverse/cilikube/api/v1/models"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -60,6 +61,18 @@ func (s *SecretService) Update(namespace string, secret *corev1.Secret) (*corev1
 	if secret.Name == "" {
 		return nil, NewValidationError("Secret name required for update")
 	}
+	// 详情和 YAML 中的 last-applied-configuration 注解是遮盖过的，原样提交回来时保留集群中的原值
+	if secret.Annotations[corev1.LastAppliedConfigAnnotation] == models.SecretMaskedValue {
+		current, err := s.Get(namespace, secret.Name)
+		if err != nil {
+			return nil, err
+		}
+		if original, ok := current.Annotations[corev1.LastAppliedConfigAnnotation]; ok {
+			secret.Annotations[corev1.LastAppliedConfigAnnotation] = original
+		} else {
+			delete(secret.Annotations, corev1.LastAppliedConfigAnnotation)
+		}
+	}
 	// Fetch existing for ResourceVersion recommended
 	return s.client.CoreV1().Secrets(namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
 }
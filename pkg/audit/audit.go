@@ -0,0 +1,24 @@
+// Package audit 记录需要长期保存的安全和操作审计事件
+package audit
+
+import (
+	"log"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/pkg/database"
+)
+
+// Record 保存审计事件；未启用数据库或写入失败时只输出日志，不影响业务流程
+func Record(event *models.AuditEvent) {
+	if event.Action == models.AuditActionAPIRequest {
+		log.Printf("审计: %s 用户=%s IP=%s %s %s %s/%s 状态=%d", event.Action, event.Username, event.ClientIP, event.Verb, event.Resource, event.Namespace, event.Name, event.StatusCode)
+	} else {
+		log.Printf("审计: %s 用户=%s IP=%s 对象=%s %s", event.Action, event.Username, event.ClientIP, event.Target, event.Detail)
+	}
+	if database.DB == nil {
+		return
+	}
+	if err := database.DB.Create(event).Error; err != nil {
+		log.Printf("保存审计事件失败: %v", err)
+	}
+}
@@ -2,19 +2,78 @@
 package auth
 
 import (
+	_ "embed"
 	"fmt"
 	"log"
 	"net/http"
 	"path/filepath" // 引入 path/filepath
+	"strings"
 
 	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/casbin/casbin/v2/util"
 	gormadapter "github.com/casbin/gorm-adapter/v3"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// casbinModel 编译进二进制，避免依赖进程工作目录查找 model.conf
+//
+//go:embed model.conf
+var casbinModel string
+
+// 内置的 Casbin 角色，用户表中的角色通过 g 规则映射到这些角色上
+const (
+	RoleSuperAdmin = "super_admin"
+	RoleNormalUser = "normal_user"
+
+	// 用户表中的角色，InitCasbin 将它们分别映射到 RoleSuperAdmin 和 RoleNormalUser
+	UserRoleAdmin = "admin"
+	UserRoleUser  = "user"
+
+	// AllDomains 匹配所有集群和命名空间的域
+	AllDomains = "**"
+
+	// userSubjectPrefix 直接分配给单个用户的角色继承关系使用的主体前缀，避免与角色名冲突
+	userSubjectPrefix = "user:"
+)
+
+// readOnlyVerbs 只读角色拥有的动作
+var readOnlyVerbs = []string{"get", "list", "watch"}
+
+// AllVerbs 策略中可用的全部动作，"*" 表示任意动作；reveal 用于查看 Secret 明文，proxy 用于 API Server 透传代理，均可单独授予
+var AllVerbs = []string{"get", "list", "watch", "create", "update", "patch", "delete", "reveal", "proxy", "*"}
+
+// UserSubject 返回用户在角色继承关系中的主体名称
+func UserSubject(username string) string {
+	return userSubjectPrefix + username
+}
+
+// IsBuiltInRole 判断是否为内置角色，内置角色及其默认策略不允许通过接口修改
+func IsBuiltInRole(role string) bool {
+	return role == RoleSuperAdmin || role == RoleNormalUser
+}
+
+// IsUserTableRole 判断是否为用户表中的角色；以它为名的 Casbin 角色会被该角色的所有用户直接继承
+func IsUserTableRole(role string) bool {
+	return role == UserRoleAdmin || role == UserRoleUser
+}
+
+// IsDefaultPolicy 判断是否为 InitCasbin 写入的默认策略或角色继承关系
+func IsDefaultPolicy(rule []string) bool {
+	switch len(rule) {
+	case 3:
+		return rule[2] == AllDomains && ((rule[0] == UserRoleAdmin && rule[1] == RoleSuperAdmin) || (rule[0] == UserRoleUser && rule[1] == RoleNormalUser))
+	case 4:
+		return IsBuiltInRole(rule[0]) && rule[1] == AllDomains
+	}
+	return false
+}
+
 type CasbinBuilder struct {
 	IgnorePaths []string
+	Cluster     string // 路由中没有 :cluster 参数时使用的集群名称
 }
 
 func NewCasbinBuilder() *CasbinBuilder {
@@ -27,7 +86,14 @@ func (r *CasbinBuilder) IgnorePath(path string) *CasbinBuilder {
 	return r
 }
 
+// WithCluster 设置当前集群名称，作为权限域的集群部分
+func (r *CasbinBuilder) WithCluster(cluster string) *CasbinBuilder {
+	r.Cluster = cluster
+	return r
+}
+
 // CasbinMiddleware 返回一个 Gin 中间件处理函数
+// 从路由参数中解析资源、动作、命名空间和集群，按 (角色, 集群/命名空间, 资源, 动作) 校验权限
 func (r *CasbinBuilder) CasbinMiddleware(e *casbin.Enforcer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		reqPath := c.Request.URL.Path
@@ -42,60 +108,200 @@ func (r *CasbinBuilder) CasbinMiddleware(e *casbin.Enforcer) gin.HandlerFunc {
 		}
 
 		// 从上下文中获取角色 (由 JWT 中间件设置)
-		roleVal, exist := c.Get("role")
+		roleVal, exist := c.Get(ContextKeyUserRole)
 		if !exist {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "无法获取用户角色信息，请先登录"})
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"code": http.StatusUnauthorized, "message": "无法获取用户角色信息，请先登录"})
 			return
 		}
 
 		role, ok := roleVal.(string)
 		if !ok || role == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "用户角色信息格式不正确"})
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"code": http.StatusUnauthorized, "message": "用户角色信息格式不正确"})
 			return
 		}
 
-		obj := reqPath
-		act := c.Request.Method
+		attrs := ParseRequestAttributes(c, r.Cluster)
+		dom, obj, act := attrs.Domain(), attrs.Resource, attrs.Verb
 
-		log.Printf("权限验证 - 角色: %v, 路径: %v, 方法: %v", role, obj, act)
+		log.Printf("权限验证 - 角色: %s, 域: %s, 资源: %s, 动作: %s", role, dom, obj, act)
 
-		// 使用 Casbin Enforcer 验证权限
-		allowed, err := e.Enforce(role, obj, act)
+		// 使用 Casbin Enforcer 验证权限：先按用户表中的角色，再按直接分配给该用户的角色
+		allowed, err := e.Enforce(role, dom, obj, act)
+		if err == nil && !allowed {
+			if username := c.GetString(ContextKeyUsername); username != "" {
+				allowed, err = e.Enforce(UserSubject(username), dom, obj, act)
+			}
+		}
+		// 只读 API token 只能执行只读动作，例如 exec 虽然使用 GET 升级为 WebSocket，但动作是 create
+		if allowed && isReadOnlyToken(c) && !containsString(readOnlyVerbs, act) {
+			allowed = false
+		}
+		// 限定了角色的 API token 还必须被其中至少一个角色允许
+		if roles := c.GetStringSlice(ContextKeyTokenRoles); err == nil && allowed && len(roles) > 0 {
+			allowed = false
+			for _, tokenRole := range roles {
+				if allowed, err = e.Enforce(tokenRole, dom, obj, act); err != nil || allowed {
+					break
+				}
+			}
+		}
 		if err != nil {
 			log.Printf("Casbin Enforce 错误: %v", err)
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "权限检查时发生内部错误"})
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"code": http.StatusInternalServerError, "message": "权限检查时发生内部错误"})
 			return
 		}
 
 		if allowed {
-			log.Printf("权限验证通过 - 角色: %s, 路径: %s, 方法: %s", role, obj, act)
+			log.Printf("权限验证通过 - 角色: %s, 域: %s, 资源: %s, 动作: %s", role, dom, obj, act)
 			c.Next()
 		} else {
-			log.Printf("权限验证失败 - 角色: %s 无权访问 %s %s", role, act, obj)
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "您没有权限执行此操作"}) // 使用 403 Forbidden
+			log.Printf("权限验证失败 - 角色: %s 无权在 %s 中对 %s 执行 %s", role, dom, obj, act)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"code": http.StatusForbidden, "message": "您没有权限执行此操作"}) // 使用 403 Forbidden
+		}
+	}
+}
+
+// ReachableRoles 返回主体 (角色名或 UserSubject) 本身及其在任意域中直接或间接继承的全部角色
+func ReachableRoles(e *casbin.Enforcer, subjects ...string) (map[string]bool, error) {
+	groupings, err := e.GetGroupingPolicy()
+	if err != nil {
+		return nil, err
+	}
+	reachable := map[string]bool{}
+	queue := append([]string{}, subjects...)
+	for len(queue) > 0 {
+		subject := queue[0]
+		queue = queue[1:]
+		if reachable[subject] {
+			continue
+		}
+		reachable[subject] = true
+		for _, grouping := range groupings {
+			if len(grouping) >= 2 && grouping[0] == subject {
+				queue = append(queue, grouping[1])
+			}
+		}
+	}
+	return reachable, nil
+}
+
+// HasWriteAccess 判断主体 (角色名或 UserSubject) 及其继承的角色在任意域中是否拥有只读以外的动作
+// 不区分域，只要在某个命名空间中可写就视为有写权限
+func HasWriteAccess(e *casbin.Enforcer, subjects ...string) (bool, error) {
+	reachable, err := ReachableRoles(e, subjects...)
+	if err != nil {
+		return false, err
+	}
+
+	policies, err := e.GetPolicy()
+	if err != nil {
+		return false, err
+	}
+	for _, policy := range policies {
+		if len(policy) >= 4 && reachable[policy[0]] && !containsString(readOnlyVerbs, policy[3]) {
+			return true, nil
 		}
 	}
+	return false, nil
 }
 
 // addPolicyIfNotExists 辅助函数，检查策略是否存在，不存在则添加
-func addPolicyIfNotExists(e *casbin.Enforcer, sub, obj, act string) {
-	has, err := e.HasPolicy(sub, obj, act)
+func addPolicyIfNotExists(e *casbin.Enforcer, sub, dom, obj, act string) {
+	has, err := e.HasPolicy(sub, dom, obj, act)
 	if err != nil {
-		log.Fatalf("检查策略是否存在时出错 (%s, %s, %s): %v", sub, obj, act, err)
+		log.Fatalf("检查策略是否存在时出错 (%s, %s, %s, %s): %v", sub, dom, obj, act, err)
 	}
-	if !has {
-		added, err := e.AddPolicy(sub, obj, act)
-		if err != nil {
-			log.Fatalf("添加策略失败 (%s, %s, %s): %v", sub, obj, act, err)
+	if has {
+		log.Printf("策略已存在，跳过添加: %s, %s, %s, %s", sub, dom, obj, act)
+		return
+	}
+	if _, err := e.AddPolicy(sub, dom, obj, act); err != nil {
+		log.Fatalf("添加策略失败 (%s, %s, %s, %s): %v", sub, dom, obj, act, err)
+	}
+	log.Printf("成功添加默认策略: %s, %s, %s, %s", sub, dom, obj, act)
+}
+
+// addGroupingPolicyIfNotExists 辅助函数，检查角色继承关系是否存在，不存在则添加
+func addGroupingPolicyIfNotExists(e *casbin.Enforcer, member, role, dom string) {
+	has, err := e.HasGroupingPolicy(member, role, dom)
+	if err != nil {
+		log.Fatalf("检查角色继承关系是否存在时出错 (%s, %s, %s): %v", member, role, dom, err)
+	}
+	if has {
+		log.Printf("角色继承关系已存在，跳过添加: %s -> %s (%s)", member, role, dom)
+		return
+	}
+	if _, err := e.AddGroupingPolicy(member, role, dom); err != nil {
+		log.Fatalf("添加角色继承关系失败 (%s, %s, %s): %v", member, role, dom, err)
+	}
+	log.Printf("成功添加默认角色继承关系: %s -> %s (%s)", member, role, dom)
+}
+
+// removeLegacyPolicies 清理旧模型 (按 URL 路径匹配、角色继承不带域) 遗留在数据库中的策略
+func removeLegacyPolicies(e *casbin.Enforcer) {
+	policies, _ := e.GetPolicy()
+	for _, policy := range policies {
+		if len(policy) > 1 && strings.HasPrefix(policy[1], "/api/") {
+			if _, err := e.RemovePolicy(policy); err != nil {
+				log.Printf("删除旧版策略失败 %v: %v", policy, err)
+			} else {
+				log.Printf("已删除旧版 URL 策略: %v", policy)
+			}
 		}
-		if added {
-			log.Printf("成功添加默认策略: %s, %s, %s", sub, obj, act)
-		} else {
-			log.Printf("策略已存在，未添加: %s, %s, %s", sub, obj, act)
+	}
+	groupings, _ := e.GetGroupingPolicy()
+	for _, grouping := range groupings {
+		if len(grouping) < 3 || grouping[2] == "" {
+			if _, err := e.RemoveGroupingPolicy(grouping); err != nil {
+				log.Printf("删除旧版角色继承关系失败 %v: %v", grouping, err)
+			} else {
+				log.Printf("已删除不带域的旧版角色继承关系: %v", grouping)
+			}
 		}
+	}
+}
+
+// newEnforcer 使用内置模型创建 Enforcer，adapter 为 nil 时策略只保存在内存中
+func newEnforcer(adapter persist.Adapter) (*casbin.Enforcer, error) {
+	m, err := model.NewModelFromString(casbinModel)
+	if err != nil {
+		return nil, fmt.Errorf("解析 Casbin 模型失败: %w", err)
+	}
+	var e *casbin.Enforcer
+	if adapter != nil {
+		e, err = casbin.NewEnforcer(m, adapter)
 	} else {
-		log.Printf("策略已存在，跳过添加: %s, %s, %s", sub, obj, act)
+		e, err = casbin.NewEnforcer(m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("创建 Casbin Enforcer 失败: %w", err)
 	}
+
+	// 角色继承关系中的域支持 glob 通配，例如 "*/team-a" 表示所有集群中的 team-a 命名空间
+	e.AddNamedDomainMatchingFunc("g", "globMatch", func(dom, pattern string) bool {
+		matched, _ := util.GlobMatch(dom, pattern)
+		return matched
+	})
+	return e, nil
+}
+
+// NewMemoryEnforcer 创建只在内存中保存给定策略的 Enforcer，用于在保存前预览策略变更的效果
+func NewMemoryEnforcer(policies, groupings [][]string) (*casbin.Enforcer, error) {
+	e, err := newEnforcer(nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(policies) > 0 {
+		if _, err := e.AddPolicies(policies); err != nil {
+			return nil, fmt.Errorf("加载策略失败: %w", err)
+		}
+	}
+	if len(groupings) > 0 {
+		if _, err := e.AddGroupingPolicies(groupings); err != nil {
+			return nil, fmt.Errorf("加载角色继承关系失败: %w", err)
+		}
+	}
+	return e, nil
 }
 
 // InitCasbin 初始化 RBAC 权限控制
@@ -111,10 +317,9 @@ func InitCasbin(db *gorm.DB) (*casbin.Enforcer, error) {
 	}
 
 	log.Println("初始化 Casbin Enforcer...")
-	// 确保 model.conf 路径正确
-	e, err := casbin.NewEnforcer("./pkg/auth/model.conf", adapter)
+	e, err := newEnforcer(adapter)
 	if err != nil {
-		return nil, fmt.Errorf("创建 Casbin Enforcer 失败: %w", err)
+		return nil, err
 	}
 
 	// 启用日志记录 (可选, 但调试时有用)
@@ -131,12 +336,16 @@ func InitCasbin(db *gorm.DB) (*casbin.Enforcer, error) {
 
 	log.Println("添加或验证默认策略...")
 	// 添加默认权限 (检查是否存在)
-	addPolicyIfNotExists(e, "super_admin", "/api/v1/*", "*")   // 管理员拥有所有 v1 接口的所有权限
-	addPolicyIfNotExists(e, "normal_user", "/api/v1/*", "GET") // 普通用户只有 GET 权限
+	removeLegacyPolicies(e)
+	// 策略格式: 角色, 集群/命名空间, 资源, 动作；域使用 glob 匹配 ("**" 表示全部)，资源使用 keyMatch ("pods*" 包含其子资源)
+	addPolicyIfNotExists(e, RoleSuperAdmin, AllDomains, "*", "*") // 管理员拥有所有资源的所有权限
+	for _, verb := range readOnlyVerbs {
+		addPolicyIfNotExists(e, RoleNormalUser, AllDomains, "*", verb) // 普通用户只有只读权限
+	}
 
-	// 你可能还需要添加用户到角色的映射 (g 规则)
-	// 例如: e.AddGroupingPolicy("admin", "super_admin")
-	// 这通常在用户创建或角色分配时处理，但可以添加默认的。
+	// 用户表中的角色 (JWT 中的 role) 在所有域中映射到内置角色
+	addGroupingPolicyIfNotExists(e, UserRoleAdmin, RoleSuperAdmin, AllDomains)
+	addGroupingPolicyIfNotExists(e, UserRoleUser, RoleNormalUser, AllDomains)
 
 	// 保存所有可能的新增策略 (如果 AutoSave 不够可靠或需要批量添加)
 	// if err := e.SavePolicy(); err != nil {
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/ciliverse/cilikube/pkg/k8s"
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKeyKubeClient 以当前用户身份访问集群的客户端 (*k8s.Client)，只在开启用户模拟时写入
+const ContextKeyKubeClient = "kube_client"
+
+// KubernetesImpersonationMiddleware 为已认证的请求准备模拟当前用户身份的 Kubernetes 客户端，
+// 必须放在 JWTAuthMiddleware 之后。用户角色映射为组；API 令牌限定了角色但不包含用户角色时不映射角色组
+func KubernetesImpersonationMiddleware(impersonator *k8s.Impersonator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, username, role, ok := GetCurrentUser(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		// 组只来自用户表中的真实角色；令牌限定了角色时取两者的交集，令牌不能为用户增加组
+		roles := []string{role}
+		if tokenRoles := c.GetStringSlice(ContextKeyTokenRoles); len(tokenRoles) > 0 && !containsString(tokenRoles, role) {
+			roles = nil
+		}
+		client, err := impersonator.ClientFor(username, roles)
+		if err != nil {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": err.Error(),
+			})
+			c.Abort()
+			return
+		}
+		c.Set(ContextKeyKubeClient, client)
+		c.Next()
+	}
+}
+
+// ImpersonatedClient 返回中间件为当前请求准备的客户端，未开启用户模拟时返回 false
+func ImpersonatedClient(c *gin.Context) (*k8s.Client, bool) {
+	value, exists := c.Get(ContextKeyKubeClient)
+	if !exists {
+		return nil, false
+	}
+	client, ok := value.(*k8s.Client)
+	return client, ok
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -11,24 +12,45 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// 认证中间件写入 gin.Context 的键，CasbinMiddleware 和 handler 统一通过这些键读取当前用户
+const (
+	ContextKeyUserID   = "user_id"
+	ContextKeyUsername = "username"
+	ContextKeyUserRole = "user_role"
+	// ContextKeySessionID 当前 access token 所属的登录会话
+	ContextKeySessionID = "session_id"
+)
+
 type JWTClaims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	UserID    uint   `json:"user_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	SessionID uint   `json:"sid,omitempty"`
+	// PasswordChangeRequired 签发时用户必须先修改密码，只能访问 passwordChangeAllowedRoutes
+	PasswordChangeRequired bool `json:"pcr,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成JWT token
-func GenerateToken(user *models.User) (string, time.Time, error) {
-	expirationTime := time.Now().Add(configs.GlobalConfig.JWT.ExpireDuration)
+// GenerateToken 为指定会话生成短期有效的 JWT access token，claims.ID (jti) 用于吊销
+func GenerateToken(user *models.User, sessionID uint) (string, *JWTClaims, error) {
+	tokenID, err := GenerateOpaqueToken(16)
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	expirationTime := now.Add(configs.GlobalConfig.JWT.ExpireDuration)
 
 	claims := &JWTClaims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Role:     user.Role,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Role:      user.Role,
+		SessionID: sessionID,
+
+		PasswordChangeRequired: user.MustChangePassword,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Issuer:    configs.GlobalConfig.JWT.Issuer,
 			Subject:   user.Username,
 		},
@@ -36,8 +58,10 @@ func GenerateToken(user *models.User) (string, time.Time, error) {
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString([]byte(configs.GlobalConfig.JWT.SecretKey))
-
-	return tokenString, expirationTime, err
+	if err != nil {
+		return "", nil, err
+	}
+	return tokenString, claims, nil
 }
 
 // ParseToken 解析JWT token
@@ -61,8 +85,8 @@ func ParseToken(tokenString string) (*JWTClaims, error) {
 func JWTAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从header中获取token
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		tokenString, ok := extractToken(c)
+		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
 				"message": "Authorization header is required",
@@ -70,12 +94,7 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
-
-		// 检查Bearer前缀
-		tokenString := ""
-		if strings.HasPrefix(authHeader, "Bearer ") {
-			tokenString = authHeader[7:] // 去掉"Bearer "前缀
-		} else {
+		if tokenString == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
 				"message": "Invalid authorization header format",
@@ -84,6 +103,37 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 个人访问令牌与会话 JWT 使用相同的 Authorization 头
+		if IsAPIToken(tokenString) {
+			token, user, err := AuthenticateAPIToken(tokenString, c.ClientIP())
+			if err != nil {
+				status := http.StatusUnauthorized
+				if !errors.Is(err, ErrInvalidAPIToken) {
+					status = http.StatusInternalServerError
+				}
+				c.JSON(status, gin.H{
+					"code":    status,
+					"message": err.Error(),
+				})
+				c.Abort()
+				return
+			}
+			setAPITokenContext(c, token, user)
+			if user.MustChangePassword && abortPasswordChangeRequired(c) {
+				return
+			}
+			if isReadOnlyToken(c) && !isSafeMethod(c.Request.Method) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"code":    403,
+					"message": "只读 API token 不能执行写操作",
+				})
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
 		// 解析token
 		claims, err := ParseToken(tokenString)
 		if err != nil {
@@ -105,19 +155,57 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 检查token是否已被吊销 (登出、登出所有会话、禁用用户)
+		if revoked, err := checkRevoked(claims); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"code":    500,
+				"message": "检查 token 状态失败: " + err.Error(),
+			})
+			c.Abort()
+			return
+		} else if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "Token has been revoked",
+			})
+			c.Abort()
+			return
+		}
+
 		// 将用户信息存储到上下文中
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("user_role", claims.Role)
+		setUserContext(c, claims)
+		if claims.PasswordChangeRequired && abortPasswordChangeRequired(c) {
+			return
+		}
 
 		c.Next()
 	}
 }
 
+// passwordChangeAllowedRoutes 必须修改密码的用户仍可访问的接口
+var passwordChangeAllowedRoutes = map[string]bool{
+	"/api/v1/auth/change-password": true,
+	"/api/v1/auth/profile":         true,
+	"/api/v1/auth/logout":          true,
+}
+
+// abortPasswordChangeRequired 必须修改密码的用户访问其他接口时返回 403，返回值表示是否已中止请求
+func abortPasswordChangeRequired(c *gin.Context) bool {
+	if passwordChangeAllowedRoutes[c.FullPath()] {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"code":    403,
+		"message": "请先修改初始密码",
+	})
+	c.Abort()
+	return true
+}
+
 // AdminRequiredMiddleware 管理员权限中间件
 func AdminRequiredMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("user_role")
+		role, exists := c.Get(ContextKeyUserRole)
 		if !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"code":    401,
@@ -127,7 +215,8 @@ func AdminRequiredMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		if role != "admin" {
+		// 限定了角色的 API token 只有包含超级管理员角色时才能访问管理接口
+		if roles := c.GetStringSlice(ContextKeyTokenRoles); role != "admin" || (len(roles) > 0 && !containsString(roles, RoleSuperAdmin)) {
 			c.JSON(http.StatusForbidden, gin.H{
 				"code":    403,
 				"message": "Admin privileges required",
@@ -157,6 +246,14 @@ func OptionalAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		if IsAPIToken(tokenString) {
+			if token, user, err := AuthenticateAPIToken(tokenString, c.ClientIP()); err == nil {
+				setAPITokenContext(c, token, user)
+			}
+			c.Next()
+			return
+		}
+
 		claims, err := ParseToken(tokenString)
 		if err != nil {
 			c.Next()
@@ -167,11 +264,13 @@ func OptionalAuthMiddleware() gin.HandlerFunc {
 			c.Next()
 			return
 		}
+		if revoked, err := checkRevoked(claims); err != nil || revoked {
+			c.Next()
+			return
+		}
 
 		// 设置用户信息到上下文
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("user_role", claims.Role)
+		setUserContext(c, claims)
 
 		c.Next()
 	}
@@ -179,9 +278,9 @@ func OptionalAuthMiddleware() gin.HandlerFunc {
 
 // GetCurrentUser 从上下文中获取当前用户信息
 func GetCurrentUser(c *gin.Context) (uint, string, string, bool) {
-	userID, exists1 := c.Get("user_id")
-	username, exists2 := c.Get("username")
-	role, exists3 := c.Get("user_role")
+	userID, exists1 := c.Get(ContextKeyUserID)
+	username, exists2 := c.Get(ContextKeyUsername)
+	role, exists3 := c.Get(ContextKeyUserRole)
 
 	if !exists1 || !exists2 || !exists3 {
 		return 0, "", "", false
@@ -189,3 +288,50 @@ func GetCurrentUser(c *gin.Context) (uint, string, string, bool) {
 
 	return userID.(uint), username.(string), role.(string), true
 }
+
+// setUserContext 将 token 中的用户信息写入上下文
+func setUserContext(c *gin.Context, claims *JWTClaims) {
+	c.Set(ContextKeyUserID, claims.UserID)
+	c.Set(ContextKeyUsername, claims.Username)
+	c.Set(ContextKeyUserRole, claims.Role)
+	c.Set(ContextKeySessionID, claims.SessionID)
+}
+
+// checkRevoked 没有 jti 的旧版 token 无法吊销，一律视为失效
+func checkRevoked(claims *JWTClaims) (bool, error) {
+	if claims.ID == "" {
+		return true, nil
+	}
+	return IsTokenRevoked(claims.ID)
+}
+
+// extractToken 从 Authorization: Bearer 头中读取 token，第二个返回值表示请求是否携带了凭据
+// 浏览器的 WebSocket 和 EventSource 无法设置请求头，这两类请求允许通过 token 查询参数传递，
+// 访问日志由 RequestLogger 脱敏
+func extractToken(c *gin.Context) (string, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		if isStreamingRequest(c) && c.Query(streamTokenQueryParam) != "" {
+			return c.Query(streamTokenQueryParam), true
+		}
+		return "", false
+	}
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return "", true
+	}
+	return strings.TrimPrefix(authHeader, "Bearer "), true
+}
+
+func isStreamingRequest(c *gin.Context) bool {
+	return strings.EqualFold(c.GetHeader("Upgrade"), "websocket") ||
+		strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
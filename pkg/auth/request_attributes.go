@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ClusterScopeNamespace 集群级资源在权限域中使用的命名空间占位符 (不是合法的命名空间名称)
+	ClusterScopeNamespace = "_"
+	// DefaultCluster 未配置当前集群名称时使用的集群名
+	DefaultCluster = "default"
+
+	apiPrefix = "/api/v1/"
+)
+
+// subresourceVerbs 需要覆盖默认动作的子资源，与 Kubernetes 对 pods/exec 的授权语义保持一致。
+// 文件上传下载通过 exec 运行 tar，下载也能读取容器内挂载的 Secret 和 ServiceAccount 令牌，按 exec 授权。
+// 查看 Secret 明文使用单独的 reveal 动作，只读角色 (get/list/watch) 不会因此获得权限，
+// 该动作不是只读动作，每次查看都会被审计中间件记录。
+// API Server 透传代理可以读取任意资源 (包括 Secret 明文) 并建立 exec 连接，使用单独的 proxy 动作，
+// 只有显式授予该动作或拥有 * 动作的角色才能访问
+var subresourceVerbs = map[string]string{
+	"exec":   "create",
+	"files":  "create",
+	"reveal": "reveal",
+	"proxy":  "proxy",
+}
+
+// RequestAttributes 从路由中解析出的授权属性
+type RequestAttributes struct {
+	Cluster   string
+	Namespace string // 集群级资源为 ClusterScopeNamespace
+	Resource  string // 如 pods、pods/logs、nodes/drain、rbac/roles
+	Verb      string // get、list、watch、create、update、patch、delete、reveal、proxy
+}
+
+// Domain 返回 Casbin 模型中的域：集群/命名空间
+func (a RequestAttributes) Domain() string {
+	return Domain(a.Cluster, a.Namespace)
+}
+
+// Domain 拼接 Casbin 模型中的域，namespace 为空表示集群级资源
+func Domain(cluster, namespace string) string {
+	if namespace == "" {
+		namespace = ClusterScopeNamespace
+	}
+	return cluster + "/" + namespace
+}
+
+// ParseRequestAttributes 根据匹配到的路由模板 (如 /api/v1/namespaces/:namespace/pods/:name/logs) 解析资源、动作和命名空间
+func ParseRequestAttributes(c *gin.Context, defaultCluster string) RequestAttributes {
+	cluster := c.Param("cluster")
+	if cluster == "" {
+		cluster = defaultCluster
+	}
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	return ParseRouteAttributes(c.Request.Method, route, cluster, c.Param("namespace"))
+}
+
+// ParseRouteAttributes 根据请求方法和路由模板解析授权属性，namespace 为空表示集群级资源
+// 资源由路由中的静态段组成，路径参数不参与；yaml 段只是表示形式，不视为子资源
+func ParseRouteAttributes(method, route, cluster, namespace string) RequestAttributes {
+	attrs := RequestAttributes{Cluster: cluster, Namespace: namespace}
+	if attrs.Cluster == "" {
+		attrs.Cluster = DefaultCluster
+	}
+	if attrs.Namespace == "" {
+		attrs.Namespace = ClusterScopeNamespace
+	}
+
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(route, apiPrefix), "/"), "/")
+
+	var parts []string
+	watch, named := false, false
+	for i := 0; i < len(segments); i++ {
+		segment := segments[i]
+		switch {
+		case segment == "":
+			continue
+		case segment == "namespaces" && i+1 < len(segments) && segments[i+1] == ":namespace" && i+2 < len(segments):
+			// 命名空间前缀只决定域，不属于资源名
+			i++
+		case segment == "watch":
+			watch = true
+		case strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*"):
+			named = true
+		case segment == "yaml":
+			continue
+		default:
+			parts = append(parts, segment)
+		}
+	}
+	attrs.Resource = strings.Join(parts, "/")
+
+	switch {
+	case watch:
+		attrs.Verb = "watch"
+	case method == http.MethodGet || method == http.MethodHead:
+		if named {
+			attrs.Verb = "get"
+		} else {
+			attrs.Verb = "list"
+		}
+	case method == http.MethodPost:
+		attrs.Verb = "create"
+	case method == http.MethodPut:
+		attrs.Verb = "update"
+	case method == http.MethodPatch:
+		attrs.Verb = "patch"
+	case method == http.MethodDelete:
+		attrs.Verb = "delete"
+	default:
+		attrs.Verb = strings.ToLower(method)
+	}
+	if len(parts) > 0 {
+		if verb, ok := subresourceVerbs[parts[len(parts)-1]]; ok {
+			attrs.Verb = verb
+		}
+	}
+	return attrs
+}
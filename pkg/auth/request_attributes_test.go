@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试从路由模板解析资源、动作和权限域
+func TestParseRequestAttributes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		method, route, path string
+		want                RequestAttributes
+	}{
+		{http.MethodGet, "/api/v1/namespaces/:namespace/pods", "/api/v1/namespaces/team-a/pods",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "pods", Verb: "list"}},
+		{http.MethodGet, "/api/v1/namespaces/:namespace/pods/:name/logs", "/api/v1/namespaces/team-a/pods/web/logs",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "pods/logs", Verb: "get"}},
+		{http.MethodGet, "/api/v1/namespaces/:namespace/pods/:name/exec", "/api/v1/namespaces/team-a/pods/web/exec",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "pods/exec", Verb: "create"}},
+		{http.MethodGet, "/api/v1/namespaces/:namespace/pods/:name/files", "/api/v1/namespaces/team-a/pods/web/files",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "pods/files", Verb: "create"}},
+		{http.MethodPost, "/api/v1/namespaces/:namespace/pods/:name/files", "/api/v1/namespaces/team-a/pods/web/files",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "pods/files", Verb: "create"}},
+		{http.MethodGet, "/api/v1/namespaces/:namespace/secrets/:name/keys/:key/reveal", "/api/v1/namespaces/team-a/secrets/db/keys/password/reveal",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "secrets/keys/reveal", Verb: "reveal"}},
+		{http.MethodGet, "/api/v1/proxy/*act", "/api/v1/proxy/api/v1/namespaces/team-a/secrets/db",
+			RequestAttributes{Cluster: "prod", Namespace: ClusterScopeNamespace, Resource: "proxy", Verb: "proxy"}},
+		{http.MethodPost, "/api/v1/proxy/*act", "/api/v1/proxy/api/v1/namespaces/team-a/pods/web/exec",
+			RequestAttributes{Cluster: "prod", Namespace: ClusterScopeNamespace, Resource: "proxy", Verb: "proxy"}},
+		{http.MethodPut, "/api/v1/namespaces/:namespace/pods/:name/yaml", "/api/v1/namespaces/team-a/pods/web/yaml",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "pods", Verb: "update"}},
+		{http.MethodGet, "/api/v1/namespaces/:namespace/watch/pods", "/api/v1/namespaces/team-a/watch/pods",
+			RequestAttributes{Cluster: "prod", Namespace: "team-a", Resource: "pods", Verb: "watch"}},
+		{http.MethodPost, "/api/v1/nodes/:name/drain", "/api/v1/nodes/node-1/drain",
+			RequestAttributes{Cluster: "prod", Namespace: ClusterScopeNamespace, Resource: "nodes/drain", Verb: "create"}},
+		{http.MethodDelete, "/api/v1/rbac/clusterRoles/:name", "/api/v1/rbac/clusterRoles/view",
+			RequestAttributes{Cluster: "prod", Namespace: ClusterScopeNamespace, Resource: "rbac/clusterRoles", Verb: "delete"}},
+	}
+	for _, tt := range tests {
+		router := gin.New()
+		var got RequestAttributes
+		router.Handle(tt.method, tt.route, func(c *gin.Context) {
+			got = ParseRequestAttributes(c, "prod")
+		})
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+		assert.Equal(t, tt.want, got, tt.route)
+	}
+	assert.Equal(t, "prod/_", Domain("prod", ""))
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamTokenQueryParam WebSocket 和 EventSource 请求携带 access token 的查询参数
+const streamTokenQueryParam = "token"
+
+// RequestLogger 与 gin.Logger 输出格式相同的访问日志，查询参数中的 token 替换为占位符，
+// 避免流式请求的 access token 写入标准输出和日志存储
+func RequestLogger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		if param.Latency > time.Minute {
+			param.Latency = param.Latency.Truncate(time.Second)
+		}
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			param.StatusCode,
+			param.Latency,
+			param.ClientIP,
+			param.Method,
+			RedactQueryToken(param.Path),
+			param.ErrorMessage,
+		)
+	})
+}
+
+// RedactQueryToken 把路径中 token 查询参数的值替换为 REDACTED，其余部分保持不变
+func RedactQueryToken(path string) string {
+	i := strings.IndexByte(path, '?')
+	if i < 0 {
+		return path
+	}
+	query, err := url.ParseQuery(path[i+1:])
+	if err != nil {
+		// 无法解析时整体去掉查询串，宁可丢信息也不输出令牌
+		return path[:i] + "?REDACTED"
+	}
+	if !query.Has(streamTokenQueryParam) {
+		return path
+	}
+	for j := range query[streamTokenQueryParam] {
+		query[streamTokenQueryParam][j] = "REDACTED"
+	}
+	return path[:i+1] + query.Encode()
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactQueryToken(t *testing.T) {
+	tests := []struct{ path, want string }{
+		{"/api/v1/nodes", "/api/v1/nodes"},
+		{"/api/v1/namespaces/a/pods/web/exec?container=app", "/api/v1/namespaces/a/pods/web/exec?container=app"},
+		{"/api/v1/namespaces/a/pods/web/exec?token=eyJhbGc.x.y&container=app", "/api/v1/namespaces/a/pods/web/exec?container=app&token=REDACTED"},
+		{"/api/v1/nodes/n1/drain?token=a&token=b", "/api/v1/nodes/n1/drain?token=REDACTED&token=REDACTED"},
+		{"/api/v1/nodes?token=%zz", "/api/v1/nodes?REDACTED"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, RedactQueryToken(tt.path), tt.path)
+	}
+}
+
+func TestRequestLoggerRedactsToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var out bytes.Buffer
+	original := gin.DefaultWriter
+	gin.DefaultWriter = &out
+	defer func() { gin.DefaultWriter = original }()
+
+	router := gin.New()
+	router.Use(RequestLogger())
+	router.GET("/stream", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/stream?token=secret-jwt", nil))
+
+	assert.Contains(t, out.String(), `"/stream?token=REDACTED"`)
+	assert.NotContains(t, out.String(), "secret-jwt")
+}
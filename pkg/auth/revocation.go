@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/ciliverse/cilikube/pkg/database"
+	"gorm.io/gorm"
+)
+
+// GenerateOpaqueToken 生成 n 字节随机数编码后的令牌，用于刷新令牌和 token ID
+func GenerateOpaqueToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成随机令牌失败: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken 返回令牌的 SHA-256 十六进制摘要，数据库中只保存摘要
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RevokeToken 将 access token 加入 db 中的吊销列表，直到其自然过期；顺带清理已过期的条目。db 为 nil 时不记录
+func RevokeToken(db *gorm.DB, tokenID string, expiresAt time.Time) error {
+	if db == nil || tokenID == "" || !expiresAt.After(time.Now()) {
+		return nil
+	}
+	db.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{})
+	return db.Save(&models.RevokedToken{TokenID: tokenID, ExpiresAt: expiresAt}).Error
+}
+
+// IsTokenRevoked 判断 access token 是否在吊销列表中，未启用数据库时没有吊销列表
+func IsTokenRevoked(tokenID string) (bool, error) {
+	if database.DB == nil {
+		return false, nil
+	}
+	var count int64
+	if err := database.DB.Model(&models.RevokedToken{}).Where("token_id = ?", tokenID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
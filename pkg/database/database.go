@@ -7,7 +7,9 @@ import (
 
 	"github.com/ciliverse/cilikube/api/v1/models"
 	"github.com/ciliverse/cilikube/configs"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -21,9 +23,10 @@ func InitDatabase() error {
 		return nil
 	}
 
-	var err error
-
-	dsn := configs.GlobalConfig.GetDSN()
+	dialector, err := Dialector(configs.GlobalConfig)
+	if err != nil {
+		return err
+	}
 
 	// 配置GORM
 	gormConfig := &gorm.Config{
@@ -36,7 +39,7 @@ func InitDatabase() error {
 	// }
 
 	// 连接数据库
-	DB, err = gorm.Open(mysql.Open(dsn), gormConfig)
+	DB, err = gorm.Open(dialector, gormConfig)
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %v", err)
 	}
@@ -48,59 +51,72 @@ func InitDatabase() error {
 	}
 
 	// 设置连接池参数
-	sqlDB.SetMaxIdleConns(10)           // 设置空闲连接池中连接的最大数量
-	sqlDB.SetMaxOpenConns(100)          // 设置打开数据库连接的最大数量
-	sqlDB.SetConnMaxLifetime(time.Hour) // 设置了连接可复用的最大时间
+	if configs.GlobalConfig.Database.Type == configs.DatabaseTypeSQLite {
+		// SQLite 同一时间只允许一个写入者，单连接避免 database is locked
+		sqlDB.SetMaxOpenConns(1)
+	} else {
+		sqlDB.SetMaxIdleConns(10)           // 设置空闲连接池中连接的最大数量
+		sqlDB.SetMaxOpenConns(100)          // 设置打开数据库连接的最大数量
+		sqlDB.SetConnMaxLifetime(time.Hour) // 设置了连接可复用的最大时间
+	}
 
 	// 测试连接
 	if err := sqlDB.Ping(); err != nil {
 		return fmt.Errorf("failed to ping database: %v", err)
 	}
 
-	log.Println("Database connected successfully")
+	log.Printf("Database connected successfully (%s)", configs.GlobalConfig.Database.Type)
 	return nil
 }
 
-// AutoMigrate 自动迁移数据库表
-func AutoMigrate() error {
-	// 首先检查数据库是否启用并且 DB 实例已成功创建
-	if !configs.GlobalConfig.Database.Enabled || DB == nil {
-		log.Println("数据库未启用或未初始化，跳过迁移。")
-		return nil // 不启用或未初始化，不算错误，直接返回
-	}
-
-	log.Println("开始数据库自动迁移...") // 添加日志
-	err := DB.AutoMigrate(
-		&models.User{},
-	)
-	if err != nil {
-		return fmt.Errorf("failed to migrate database: %v", err)
+// Dialector 根据配置中的数据库类型选择 GORM 驱动，未设置类型时按 MySQL 处理以兼容旧配置
+func Dialector(cfg *configs.Config) (gorm.Dialector, error) {
+	dsn := cfg.GetDSN()
+	switch cfg.Database.Type {
+	case configs.DatabaseTypeMySQL, "":
+		return mysql.Open(dsn), nil
+	case configs.DatabaseTypePostgres:
+		return postgres.Open(dsn), nil
+	case configs.DatabaseTypeSQLite:
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported database type %q (supported: mysql, postgres, sqlite)", cfg.Database.Type)
 	}
-
-	log.Println("Database migration completed")
-	return nil
 }
 
+// defaultAdminPassword 默认管理员的初始密码，只用于首次登录
+const defaultAdminPassword = "admin123"
+
 // CreateDefaultAdmin 创建默认管理员账户
 func CreateDefaultAdmin() error {
 	var count int64
 	DB.Model(&models.User{}).Count(&count)
 
-	// 如果没有用户，创建默认管理员
+	// 如果没有用户，创建默认管理员，首次登录后必须修改密码
 	if count == 0 {
 		admin := &models.User{
-			Username: "admin",
-			Email:    "admin@cilikube.com",
-			Password: "admin123", // 这个密码会在BeforeCreate钩子中被加密
-			Role:     "admin",
-			IsActive: true,
+			Username:           "admin",
+			Email:              "admin@cilikube.com",
+			Password:           defaultAdminPassword, // 这个密码会在BeforeCreate钩子中被加密
+			Role:               "admin",
+			IsActive:           true,
+			MustChangePassword: true,
 		}
 
 		if err := DB.Create(admin).Error; err != nil {
 			return fmt.Errorf("failed to create default admin: %v", err)
 		}
 
-		log.Println("Default admin user created: username=admin, password=admin123")
+		log.Println("Default admin user created: username=admin, password=admin123 (首次登录后必须修改密码)")
+		return nil
+	}
+
+	// 旧版本创建的默认管理员仍在使用初始密码时，同样要求修改
+	var admin models.User
+	if err := DB.Where("username = ? AND auth_provider = ?", "admin", models.AuthProviderLocal).First(&admin).Error; err == nil &&
+		!admin.MustChangePassword && admin.CheckPassword(defaultAdminPassword) {
+		log.Println("警告: 默认管理员仍在使用初始密码，下次登录后必须修改密码")
+		return DB.Model(&admin).Update("must_change_password", true).Error
 	}
 
 	return nil
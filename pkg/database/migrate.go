@@ -0,0 +1,227 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/ciliverse/cilikube/configs"
+	"gorm.io/gorm"
+)
+
+// ErrSchemaTooNew 数据库已经被更新版本的程序迁移过，当前程序可能无法正确读写
+var ErrSchemaTooNew = errors.New("数据库结构版本高于当前程序支持的版本，请升级程序或先用新版本执行 migrate down")
+
+// Migration 一个有序的表结构变更，Version 必须唯一且递增
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration 已执行的迁移记录
+type SchemaMigration struct {
+	Version   int       `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `json:"name" gorm:"size:100"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus 迁移的执行状态
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Unknown   bool // 数据库中有记录但当前程序中不存在，说明数据库被更新的版本迁移过
+}
+
+// LatestVersion 当前程序包含的最新迁移版本
+func LatestVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// Migrate 启动时调用：数据库结构比程序新时拒绝启动，否则执行所有未执行的迁移
+func Migrate() error {
+	if !configs.GlobalConfig.Database.Enabled || DB == nil {
+		log.Println("数据库未启用或未初始化，跳过迁移。")
+		return nil
+	}
+	if err := CheckSchemaVersion(DB); err != nil {
+		return err
+	}
+	return MigrateTo(DB, LatestVersion())
+}
+
+// CheckSchemaVersion 检查数据库中是否存在当前程序不认识的迁移
+func CheckSchemaVersion(db *gorm.DB) error {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	known := migrationIndex()
+	for version := range applied {
+		if _, ok := known[version]; !ok || version > LatestVersion() {
+			return fmt.Errorf("%w (数据库版本 %d，程序最新版本 %d)", ErrSchemaTooNew, version, LatestVersion())
+		}
+	}
+	return nil
+}
+
+// CurrentVersion 已执行的最大迁移版本，没有执行过任何迁移时为 0
+func CurrentVersion(db *gorm.DB) (int, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+	current := 0
+	for version := range applied {
+		if version > current {
+			current = version
+		}
+	}
+	return current, nil
+}
+
+// MigrateTo 迁移到指定版本：执行所有不超过 target 且未执行的迁移，回滚所有超过 target 的已执行迁移
+func MigrateTo(db *gorm.DB, target int) error {
+	if target < 0 || target > LatestVersion() {
+		return fmt.Errorf("目标版本 %d 超出范围 [0, %d]", target, LatestVersion())
+	}
+	if err := CheckSchemaVersion(db); err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; ok && m.Version > target {
+			if err := runDown(db, m); err != nil {
+				return err
+			}
+		}
+	}
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok && m.Version <= target {
+			if err := runUp(db, m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Rollback 按版本从高到低回滚最近执行的 steps 个迁移
+func Rollback(db *gorm.DB, steps int) error {
+	if steps <= 0 {
+		return nil
+	}
+	if err := CheckSchemaVersion(db); err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if err := runDown(db, m); err != nil {
+			return err
+		}
+		steps--
+	}
+	return nil
+}
+
+// MigrationStatuses 返回所有迁移的执行状态，按版本排序
+func MigrationStatuses(db *gorm.DB) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []MigrationStatus
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if record, ok := applied[m.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = record.AppliedAt
+			delete(applied, m.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range applied {
+		statuses = append(statuses, MigrationStatus{Version: record.Version, Name: record.Name, Applied: true, AppliedAt: record.AppliedAt, Unknown: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+func appliedMigrations(db *gorm.DB) (map[int]SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("创建迁移记录表失败: %w", err)
+	}
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("读取迁移记录失败: %w", err)
+	}
+	applied := make(map[int]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+func migrationIndex() map[int]Migration {
+	index := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		index[m.Version] = m
+	}
+	return index
+}
+
+// runUp 和 runDown 在事务中执行迁移并更新记录；MySQL 的 DDL 会隐式提交，迁移本身应尽量可重复执行
+func runUp(db *gorm.DB, m Migration) error {
+	log.Printf("执行数据库迁移 %d_%s", m.Version, m.Name)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := m.Up(tx); err != nil {
+			return err
+		}
+		return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("迁移 %d_%s 失败: %w", m.Version, m.Name, err)
+	}
+	return nil
+}
+
+func runDown(db *gorm.DB, m Migration) error {
+	if m.Down == nil {
+		return fmt.Errorf("迁移 %d_%s 不支持回滚", m.Version, m.Name)
+	}
+	log.Printf("回滚数据库迁移 %d_%s", m.Version, m.Name)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := m.Down(tx); err != nil {
+			return err
+		}
+		return tx.Delete(&SchemaMigration{}, "version = ?", m.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("回滚迁移 %d_%s 失败: %w", m.Version, m.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ciliverse/cilikube/api/v1/models"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// 迁移中定义当时的表结构
+type widgetV1 struct {
+	ID    uint `gorm:"primaryKey"`
+	Title string
+}
+
+func (widgetV1) TableName() string { return "widgets" }
+
+type widgetV2 struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+	Slug string
+}
+
+func (widgetV2) TableName() string { return "widgets" }
+
+func useMigrations(t *testing.T, list []Migration) {
+	previous := migrations
+	migrations = list
+	t.Cleanup(func() { migrations = previous })
+}
+
+var testMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_widgets",
+		Up:      func(tx *gorm.DB) error { return tx.Migrator().CreateTable(&widgetV1{}) },
+		Down:    func(tx *gorm.DB) error { return tx.Migrator().DropTable("widgets") },
+	},
+	{
+		Version: 2,
+		Name:    "rename_title_add_slug",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().RenameColumn(&widgetV1{}, "title", "name"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().AddColumn(&widgetV2{}, "Slug"); err != nil {
+				return err
+			}
+			return tx.Exec("UPDATE widgets SET slug = LOWER(name)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&widgetV2{}, "Slug"); err != nil {
+				return err
+			}
+			return tx.Migrator().RenameColumn(&widgetV2{}, "name", "title")
+		},
+	},
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	db := openTestDB(t)
+	useMigrations(t, testMigrations[:1])
+
+	require.NoError(t, MigrateTo(db, LatestVersion()))
+	require.NoError(t, db.Create(&widgetV1{Title: "Hello"}).Error)
+
+	// 新版本程序追加的迁移：改列名并回填数据
+	useMigrations(t, testMigrations)
+	statuses, err := MigrationStatuses(db)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[1].Applied)
+
+	require.NoError(t, MigrateTo(db, LatestVersion()))
+	var widget widgetV2
+	require.NoError(t, db.First(&widget).Error)
+	assert.Equal(t, "Hello", widget.Name)
+	assert.Equal(t, "hello", widget.Slug)
+	version, err := CurrentVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	// 再次执行不会重复迁移
+	require.NoError(t, MigrateTo(db, LatestVersion()))
+
+	require.NoError(t, Rollback(db, 1))
+	var restored widgetV1
+	require.NoError(t, db.First(&restored).Error)
+	assert.Equal(t, "Hello", restored.Title)
+
+	require.NoError(t, MigrateTo(db, 0))
+	assert.False(t, db.Migrator().HasTable("widgets"))
+	version, err = CurrentVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	db := openTestDB(t)
+	useMigrations(t, testMigrations)
+	require.NoError(t, MigrateTo(db, LatestVersion()))
+
+	// 旧版本程序只认识第一个迁移
+	useMigrations(t, testMigrations[:1])
+	err := CheckSchemaVersion(db)
+	assert.True(t, errors.Is(err, ErrSchemaTooNew), err)
+	assert.True(t, errors.Is(MigrateTo(db, 1), ErrSchemaTooNew))
+	assert.True(t, errors.Is(Rollback(db, 1), ErrSchemaTooNew))
+
+	statuses, err := MigrationStatuses(db)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[1].Unknown)
+}
+
+func TestBaselineMigration(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, MigrateTo(db, LatestVersion()))
+	for _, model := range baselineModels() {
+		assert.True(t, db.Migrator().HasTable(model))
+	}
+	require.NoError(t, MigrateTo(db, 0))
+	for _, model := range baselineModels() {
+		assert.False(t, db.Migrator().HasTable(model))
+	}
+}
+
+// 修改 models 后忘记追加迁移时失败：迁移到最新版本后，当前模型的每个字段都必须有对应的列
+func TestMigrationsCoverModels(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, MigrateTo(db, LatestVersion()))
+	for _, model := range []interface{}{
+		&models.User{}, &models.Session{}, &models.RevokedToken{}, &models.APIToken{},
+		&models.SecurityPolicy{}, &models.LoginAttempt{}, &models.PasswordHistory{}, &models.AuditEvent{},
+	} {
+		stmt := &gorm.Statement{DB: db}
+		require.NoError(t, stmt.Parse(model))
+		require.True(t, db.Migrator().HasTable(model), stmt.Schema.Table)
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			assert.True(t, db.Migrator().HasColumn(model, field.DBName), "%s.%s 没有对应的迁移", stmt.Schema.Table, field.DBName)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrations 按版本升序排列的全部迁移。
+// 已发布的迁移不能修改，表结构变更 (加列、改名、回填数据) 都要追加新的迁移。
+// 迁移中只能使用在迁移内定义的表结构快照 (或原始 DDL)，不能引用 models 中的结构体：
+// models 会随版本变化，引用它会让同一个版本号在新旧数据库上建出不同的表。
+// 因此每次修改 models 中对应表的字段，都必须同时追加一个新版本的迁移
+var migrations = []Migration{
+	{
+		// 基线：引入版本化迁移前由 AutoMigrate 维护的全部表，结构冻结在下面的 v1 快照中。
+		// 已有数据库执行时只会补齐缺少的列和索引
+		Version: 1,
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(baselineModels()...)
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(baselineModels()...)
+		},
+	},
+}
+
+func baselineModels() []interface{} {
+	return []interface{}{
+		&userV1{},
+		&sessionV1{},
+		&revokedTokenV1{},
+		&apiTokenV1{},
+		&securityPolicyV1{},
+		&loginAttemptV1{},
+		&passwordHistoryV1{},
+		&auditEventV1{},
+	}
+}
+
+// 以下是版本 1 的表结构快照，不能修改
+
+type userV1 struct {
+	ID                 uint   `gorm:"primaryKey"`
+	Username           string `gorm:"uniqueIndex;not null;size:50"`
+	Email              string `gorm:"uniqueIndex;not null;size:100"`
+	Password           string `gorm:"not null"`
+	Role               string `gorm:"default:user;size:20"`
+	IsActive           bool   `gorm:"default:true"`
+	AuthProvider       string `gorm:"default:local;size:20"`
+	ExternalID         string `gorm:"size:255;index"`
+	TOTPSecret         string `gorm:"size:64"`
+	TOTPEnabled        bool   `gorm:"default:false"`
+	TOTPLastCounter    int64
+	TOTPRecoveryCodes  string `gorm:"type:text"`
+	LastLogin          *time.Time
+	MustChangePassword bool `gorm:"default:false"`
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	DeletedAt          gorm.DeletedAt `gorm:"index"`
+}
+
+func (userV1) TableName() string { return "users" }
+
+type sessionV1 struct {
+	ID                uint   `gorm:"primaryKey"`
+	UserID            uint   `gorm:"index;not null"`
+	RefreshTokenHash  string `gorm:"uniqueIndex;size:64;not null"`
+	PreviousTokenHash string `gorm:"index;size:64"`
+	AccessTokenID     string `gorm:"size:64"`
+	AccessExpiresAt   time.Time
+	UserAgent         string    `gorm:"size:255"`
+	ClientIP          string    `gorm:"size:64"`
+	ExpiresAt         time.Time `gorm:"index"`
+	RevokedAt         *time.Time
+	LastUsedAt        time.Time
+	CreatedAt         time.Time
+}
+
+func (sessionV1) TableName() string { return "user_sessions" }
+
+type revokedTokenV1 struct {
+	TokenID   string    `gorm:"primaryKey;size:64"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+func (revokedTokenV1) TableName() string { return "revoked_tokens" }
+
+type apiTokenV1 struct {
+	ID         uint      `gorm:"primaryKey"`
+	UserID     uint      `gorm:"index;not null"`
+	Name       string    `gorm:"size:100;not null"`
+	Prefix     string    `gorm:"size:16"`
+	TokenHash  string    `gorm:"uniqueIndex;size:64;not null"`
+	Scope      string    `gorm:"size:20;not null"`
+	Roles      string    `gorm:"size:255"`
+	ExpiresAt  time.Time `gorm:"index"`
+	LastUsedAt *time.Time
+	LastUsedIP string `gorm:"size:64"`
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+func (apiTokenV1) TableName() string { return "api_tokens" }
+
+type securityPolicyV1 struct {
+	ID                         uint `gorm:"primaryKey"`
+	RequireTwoFactorForWriters bool
+	UpdatedAt                  time.Time
+}
+
+func (securityPolicyV1) TableName() string { return "security_policies" }
+
+type loginAttemptV1 struct {
+	Key           string `gorm:"column:attempt_key;primaryKey;size:191"`
+	Failures      int
+	LockCount     int
+	LockedUntil   *time.Time
+	LastFailureAt time.Time
+}
+
+func (loginAttemptV1) TableName() string { return "login_attempts" }
+
+type passwordHistoryV1 struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index;not null"`
+	Hash      string `gorm:"size:255;not null"`
+	CreatedAt time.Time
+}
+
+func (passwordHistoryV1) TableName() string { return "password_histories" }
+
+type auditEventV1 struct {
+	ID         uint      `gorm:"primaryKey"`
+	CreatedAt  time.Time `gorm:"index"`
+	Username   string    `gorm:"size:50;index"`
+	Role       string    `gorm:"size:50"`
+	ClientIP   string    `gorm:"size:64"`
+	Action     string    `gorm:"size:64;index"`
+	Target     string    `gorm:"size:255"`
+	Detail     string    `gorm:"type:text"`
+	Cluster    string    `gorm:"size:100;index"`
+	Verb       string    `gorm:"size:20;index"`
+	Resource   string    `gorm:"size:100;index"`
+	Namespace  string    `gorm:"size:100;index"`
+	Name       string    `gorm:"size:255"`
+	Method     string    `gorm:"size:10"`
+	Path       string    `gorm:"size:500"`
+	BodyHash   string    `gorm:"size:64"`
+	StatusCode int       `gorm:"index"`
+	LatencyMs  int64
+}
+
+func (auditEventV1) TableName() string { return "audit_events" }
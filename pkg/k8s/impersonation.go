@@ -0,0 +1,110 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ciliverse/cilikube/configs"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// maxImpersonatedClients 缓存的模拟身份客户端数量上限，超过后清空重建
+const maxImpersonatedClients = 256
+
+// Impersonator 基于服务自身的 rest.Config 为每个用户构造模拟身份的客户端。
+// 同一用户和组合的客户端会被缓存，它们共享底层连接
+type Impersonator struct {
+	base   *rest.Config
+	config configs.ImpersonationConfig
+
+	mu      sync.Mutex
+	clients map[string]*Client
+}
+
+// NewImpersonator 创建 Impersonator，base 为服务访问集群使用的配置
+func NewImpersonator(base *rest.Config, config configs.ImpersonationConfig) *Impersonator {
+	return &Impersonator{
+		base:    base,
+		config:  config,
+		clients: make(map[string]*Client),
+	}
+}
+
+// Identity 返回模拟的用户名和组，组按字典序去重
+func (i *Impersonator) Identity(username string, roles []string) (string, []string, error) {
+	if username == "" {
+		return "", nil, fmt.Errorf("用户名为空，无法模拟用户身份")
+	}
+	user := i.config.UserPrefix + username
+	set := make(map[string]struct{}, len(roles)+len(i.config.Groups))
+	for _, role := range roles {
+		if role != "" {
+			set[i.config.GroupPrefix+role] = struct{}{}
+		}
+	}
+	for _, group := range i.config.Groups {
+		if group != "" {
+			set[group] = struct{}{}
+		}
+	}
+	groups := make([]string, 0, len(set))
+	for group := range set {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	// 不允许通过用户名或角色名冒充集群内置身份，例如 system:masters
+	if isSystemIdentity(user) {
+		return "", nil, fmt.Errorf("不允许模拟系统用户 %q", user)
+	}
+	for _, group := range groups {
+		if isSystemIdentity(group) && !containsGroup(i.config.Groups, group) {
+			return "", nil, fmt.Errorf("不允许模拟系统组 %q", group)
+		}
+	}
+	return user, groups, nil
+}
+
+// ClientFor 返回以指定用户和角色身份访问集群的客户端
+func (i *Impersonator) ClientFor(username string, roles []string) (*Client, error) {
+	user, groups, err := i.Identity(username, roles)
+	if err != nil {
+		return nil, err
+	}
+	key := user + "\x00" + strings.Join(groups, "\x00")
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if client, ok := i.clients[key]; ok {
+		return client, nil
+	}
+
+	config := rest.CopyConfig(i.base)
+	config.Impersonate = rest.ImpersonationConfig{UserName: user, Groups: groups}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("创建模拟用户 %s 的客户端失败: %w", user, err)
+	}
+	if len(i.clients) >= maxImpersonatedClients {
+		i.clients = make(map[string]*Client)
+	}
+	client := &Client{Clientset: clientset, Config: config}
+	i.clients[key] = client
+	return client, nil
+}
+
+func isSystemIdentity(name string) bool {
+	return strings.HasPrefix(name, "system:")
+}
+
+func containsGroup(groups []string, group string) bool {
+	for _, g := range groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+// Package oidc 实现 OpenID Connect 授权码模式 (PKCE) 所需的发现、换取令牌和 ID Token 校验
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+// signingMethods 接受的 ID Token 签名算法，不允许 none 和 HS*
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// Config 连接 IdP 所需的客户端配置
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// UsernameClaim 和 GroupsClaim 指定从 ID Token 中读取用户名和分组的 claim
+	UsernameClaim string
+	GroupsClaim   string
+}
+
+// Claims 从 ID Token 中提取的用户信息
+type Claims struct {
+	Subject  string
+	Username string
+	Email    string
+	Groups   []string
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider 一个已完成发现的 OIDC 身份提供方
+type Provider struct {
+	config  Config
+	oauth2  oauth2.Config
+	issuer  string
+	jwksURI string
+	client  *http.Client
+
+	mu   sync.RWMutex
+	keys map[string]crypto.PublicKey // kid -> 公钥
+}
+
+// Discover 读取 issuer 的 /.well-known/openid-configuration 并创建 Provider
+func Discover(ctx context.Context, config Config) (*Provider, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	wellKnown := strings.TrimSuffix(config.IssuerURL, "/") + "/.well-known/openid-configuration"
+
+	var doc discoveryDocument
+	if err := getJSON(ctx, client, wellKnown, &doc); err != nil {
+		return nil, fmt.Errorf("OIDC 发现失败: %w", err)
+	}
+	// 规范要求发现文档中的 issuer 与配置的 issuer 完全一致
+	if strings.TrimSuffix(doc.Issuer, "/") != strings.TrimSuffix(config.IssuerURL, "/") {
+		return nil, fmt.Errorf("OIDC 发现文档中的 issuer %q 与配置的 %q 不一致", doc.Issuer, config.IssuerURL)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, errors.New("OIDC 发现文档缺少 authorization_endpoint、token_endpoint 或 jwks_uri")
+	}
+
+	return &Provider{
+		config: config,
+		oauth2: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Scopes:       config.Scopes,
+			Endpoint:     oauth2.Endpoint{AuthURL: doc.AuthorizationEndpoint, TokenURL: doc.TokenEndpoint},
+		},
+		issuer:  doc.Issuer,
+		jwksURI: doc.JWKSURI,
+		client:  client,
+	}, nil
+}
+
+// AuthCodeURL 返回跳转到 IdP 的授权地址，verifier 只保存在服务端，IdP 只收到其 S256 摘要
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	return p.oauth2.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier), oauth2.SetAuthURLParam("nonce", nonce))
+}
+
+// Exchange 用授权码和 PKCE verifier 换取令牌，并校验其中的 ID Token
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.client)
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("换取令牌失败: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("令牌响应中没有 id_token")
+	}
+	return p.VerifyIDToken(ctx, rawIDToken, nonce)
+}
+
+// VerifyIDToken 使用 JWKS 校验 ID Token 的签名、issuer、audience、过期时间和 nonce
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*Claims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, kid)
+	},
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(p.issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ID Token 校验失败: %w", err)
+	}
+	if tokenNonce, _ := claims["nonce"].(string); nonce != "" && tokenNonce != nonce {
+		return nil, errors.New("ID Token 校验失败: nonce 不匹配")
+	}
+
+	result := &Claims{}
+	result.Subject, _ = claims["sub"].(string)
+	if result.Subject == "" {
+		return nil, errors.New("ID Token 缺少 sub")
+	}
+	result.Email, _ = claims["email"].(string)
+	result.Username, _ = claims[p.config.UsernameClaim].(string)
+	result.Groups = stringSlice(claims[p.config.GroupsClaim])
+	return result, nil
+}
+
+// publicKey 按 kid 查找公钥，找不到时重新拉取一次 JWKS 以支持 IdP 轮换密钥
+func (p *Provider) publicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	var set jsonWebKeySet
+	if err := getJSON(ctx, p.client, p.jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("获取 JWKS 失败: %w", err)
+	}
+	keys, err := set.publicKeys()
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("JWKS 中没有 kid 为 %q 的密钥", kid)
+}
+
+// lookupKey 没有 kid 时只在 JWKS 仅包含一个密钥的情况下使用该密钥
+func (p *Provider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s 返回 %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// stringSlice 分组 claim 可能是字符串数组，也可能是单个字符串
+func stringSlice(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
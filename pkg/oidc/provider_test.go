@@ -0,0 +1,64 @@
+package oidc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ciliverse/cilikube/pkg/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyIDToken(t *testing.T) {
+	issuer := oidctest.NewServer("cilikube", "secret")
+	defer issuer.Close()
+
+	ctx := context.Background()
+	provider, err := Discover(ctx, Config{IssuerURL: issuer.URL, ClientID: "cilikube", UsernameClaim: "preferred_username", GroupsClaim: "groups"})
+	require.NoError(t, err)
+
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss": issuer.URL, "aud": "cilikube", "sub": "u-1", "nonce": "n-1",
+			"exp": now.Add(time.Hour).Unix(), "preferred_username": "alice", "groups": []string{"dev", "ops"},
+		}
+	}
+	sign := func(mutate func(jwt.MapClaims)) string {
+		claims := valid()
+		mutate(claims)
+		token, err := issuer.SignIDToken(claims)
+		require.NoError(t, err)
+		return token
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, sign(func(jwt.MapClaims) {}), "n-1")
+	require.NoError(t, err)
+	assert.Equal(t, &Claims{Subject: "u-1", Username: "alice", Groups: []string{"dev", "ops"}}, claims)
+
+	tests := map[string]func(jwt.MapClaims){
+		"错误的 audience": func(c jwt.MapClaims) { c["aud"] = "other" },
+		"错误的 issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+		"已过期":          func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Hour).Unix() },
+		"nonce 不匹配":    func(c jwt.MapClaims) { c["nonce"] = "n-2" },
+		"缺少 sub":       func(c jwt.MapClaims) { delete(c, "sub") },
+	}
+	for name, mutate := range tests {
+		_, err := provider.VerifyIDToken(ctx, sign(mutate), "n-1")
+		assert.Error(t, err, name)
+	}
+
+	// 使用其他签发方的密钥签名
+	other := oidctest.NewServer("cilikube", "secret")
+	defer other.Close()
+	forged, err := other.SignIDToken(valid())
+	require.NoError(t, err)
+	_, err = provider.VerifyIDToken(ctx, forged, "n-1")
+	assert.Error(t, err)
+
+	// 发现文档中的 issuer 必须与配置一致
+	_, err = Discover(ctx, Config{IssuerURL: issuer.URL + "/realm", ClientID: "cilikube"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238 附录 B 的 SHA1 测试向量，取后 6 位
+func TestCode_RFC6238(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range cases {
+		code, err := Code(secret, Counter(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, want, code, "t=%d", unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+
+	code, err := Code(secret, Counter(now.Add(-Period)))
+	require.NoError(t, err)
+	counter, ok := Validate(secret, code, now)
+	assert.True(t, ok, "允许一个时间步的偏差")
+	assert.Equal(t, Counter(now)-1, counter)
+
+	code, err = Code(secret, Counter(now.Add(-3*Period)))
+	require.NoError(t, err)
+	_, ok = Validate(secret, code, now)
+	assert.False(t, ok)
+	_, ok = Validate(secret, "abc", now)
+	assert.False(t, ok)
+
+	uri := ProvisioningURI("CiliKube", "alice", secret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/CiliKube:alice?"))
+	assert.Contains(t, uri, "secret="+secret)
+}